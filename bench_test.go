@@ -0,0 +1,161 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The benchmark history: 60 tasks a day for two years, more tasks than
+// SQLite will bind variables for in one statement, so queries that bind a
+// variable per task fail here rather than in production
+const (
+	benchDays   = 730
+	benchPerDay = 60
+)
+
+var bench struct {
+	once  sync.Once
+	dir   string
+	store *SQLiteStore
+	last  string // The final day of the history
+	err   error
+}
+
+// TestMain removes the benchmark database once the run is over
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if bench.store != nil {
+		bench.store.Close()
+	}
+	if bench.dir != "" {
+		os.RemoveAll(bench.dir)
+	}
+	os.Exit(code)
+}
+
+// benchStore returns the store holding the benchmark history, generating it
+// on first use
+func benchStore(b *testing.B) (*SQLiteStore, string) {
+	b.Helper()
+	bench.once.Do(func() {
+		if bench.dir, bench.err = os.MkdirTemp("", "todoapp-bench"); bench.err != nil {
+			return
+		}
+		if bench.store, bench.err = NewSQLiteStore(filepath.Join(bench.dir, "bench.db")); bench.err != nil {
+			return
+		}
+		bench.last, bench.err = generateBenchData(bench.store.db, benchDays, benchPerDay)
+	})
+	if bench.err != nil {
+		b.Fatal(bench.err)
+	}
+	b.ResetTimer()
+	return bench.store, bench.last
+}
+
+// generateBenchData fills the database with perDay tasks for each of the last
+// days days. Most tasks are completed on their day or a few days later; the
+// rest were rolled over to the final day. It returns the final day.
+func generateBenchData(db *sql.DB, days, perDay int) (string, error) {
+	first := time.Now().AddDate(0, 0, -days+1)
+	last := first.AddDate(0, 0, days-1).Format("2006-01-02")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`INSERT INTO tasks (title, description, created_date, assigned_date, completed_date, is_completed, category_id)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+	)
+	if err != nil {
+		return "", err
+	}
+	defer stmt.Close()
+
+	n := 0
+	for d := 0; d < days; d++ {
+		created := first.AddDate(0, 0, d).Format("2006-01-02")
+		for i := 0; i < perDay; i++ {
+			n++
+			categoryID := interface{}(int64(n%4 + 1))
+			if n%4 == 3 {
+				categoryID = nil
+			}
+
+			assigned, completed := created, interface{}(nil)
+			switch {
+			case n%10 == 0:
+				assigned = last // Still pending, dragged to today
+			case n%3 == 0:
+				done := first.AddDate(0, 0, d+n%5).Format("2006-01-02")
+				if done > last {
+					done = last
+				}
+				assigned, completed = done, done
+			default:
+				completed = created
+			}
+
+			_, err := stmt.Exec(fmt.Sprintf("Task %d", n), "Generated for benchmarking", created, assigned, completed, completed != nil, categoryID)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return last, tx.Commit()
+}
+
+func BenchmarkHistorySummaries(b *testing.B) {
+	s, _ := benchStore(b)
+	for i := 0; i < b.N; i++ {
+		if _, err := s.GetHistorySummaries(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTasksByDate(b *testing.B) {
+	s, last := benchStore(b)
+	for i := 0; i < b.N; i++ {
+		if _, err := s.GetTasksByDate(last); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTasksByCategory(b *testing.B) {
+	s, _ := benchStore(b)
+	for i := 0; i < b.N; i++ {
+		if _, err := s.GetTasksByCategory(1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkQueryTasksByDragDays(b *testing.B) {
+	s, _ := benchStore(b)
+	pending := false
+	for i := 0; i < b.N; i++ {
+		if _, err := s.QueryTasks(TaskQuery{Completed: &pending, Sort: "drag_days", Descending: true, Limit: 50}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEachTask(b *testing.B) {
+	s, _ := benchStore(b)
+	for i := 0; i < b.N; i++ {
+		if err := s.EachTask(TaskQuery{Sort: "id"}, func(Task) error { return nil }); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WorkCalendar decides which days count as working days: everything except
+// the configured weekend days and the loaded holidays
+type WorkCalendar struct {
+	mu       sync.RWMutex
+	weekend  map[time.Weekday]bool
+	holidays map[string]string // date -> holiday name
+	closures []string          // Sorted holiday dates that fall on working weekdays
+}
+
+// NewWorkCalendar creates a calendar with the given weekend days and no holidays
+func NewWorkCalendar(weekend []time.Weekday) *WorkCalendar {
+	c := &WorkCalendar{
+		weekend:  make(map[time.Weekday]bool),
+		holidays: make(map[string]string),
+	}
+	for _, day := range weekend {
+		c.weekend[day] = true
+	}
+	return c
+}
+
+// calendar is the working calendar used for drag days and rollover, set up in main
+var calendar = NewWorkCalendar([]time.Weekday{time.Saturday, time.Sunday})
+
+// SetHolidays replaces the calendar's holiday list
+func (c *WorkCalendar) SetHolidays(holidays []Holiday) {
+	byDate := make(map[string]string, len(holidays))
+	for _, h := range holidays {
+		byDate[h.Date] = h.Name
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.holidays = byDate
+	c.closures = nil
+	for date := range byDate {
+		if t, err := time.Parse("2006-01-02", date); err == nil && !c.weekend[t.Weekday()] {
+			c.closures = append(c.closures, date)
+		}
+	}
+	sort.Strings(c.closures)
+}
+
+// Weekend returns the configured weekend days in week order
+func (c *WorkCalendar) Weekend() []time.Weekday {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var days []time.Weekday
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if c.weekend[day] {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// IsWorkingDay reports whether t falls on a working day
+func (c *WorkCalendar) IsWorkingDay(t time.Time) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.weekend[t.Weekday()] {
+		return false
+	}
+	_, holiday := c.holidays[t.Format("2006-01-02")]
+	return !holiday
+}
+
+// WorkingDaysBetween counts the working days after start up to and including
+// end without walking the range day by day
+func (c *WorkCalendar) WorkingDaysBetween(start, end time.Time) int {
+	days := int(end.Sub(start).Hours()/24 + 0.5)
+	if days <= 0 {
+		return 0
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	perWeek := 7 - len(c.weekend)
+	count := days / 7 * perWeek
+	for i, day := 0, start.Weekday(); i < days%7; i++ {
+		day = (day + 1) % 7
+		if !c.weekend[day] {
+			count++
+		}
+	}
+
+	from, to := start.Format("2006-01-02"), end.Format("2006-01-02")
+	count -= sort.SearchStrings(c.closures, to+"\x00") - sort.SearchStrings(c.closures, from+"\x00")
+	return count
+}
+
+// PreviousWorkingDay returns the closest working day strictly before date
+func (c *WorkCalendar) PreviousWorkingDay(date string) string {
+	return c.stepWorkingDay(date, -1)
+}
+
+// NextWorkingDay returns the closest working day strictly after date
+func (c *WorkCalendar) NextWorkingDay(date string) string {
+	return c.stepWorkingDay(date, 1)
+}
+
+func (c *WorkCalendar) stepWorkingDay(date string, step int) string {
+	current, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+
+	// A year of consecutive non-working days means a misconfigured calendar
+	for i := 0; i < 366; i++ {
+		current = current.AddDate(0, 0, step)
+		if c.IsWorkingDay(current) {
+			break
+		}
+	}
+	return current.Format("2006-01-02")
+}
+
+// ParseWeekend parses a comma-separated list of weekday names such as "sat,sun"
+func ParseWeekend(value string) ([]time.Weekday, error) {
+	names := map[string]time.Weekday{
+		"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+		"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+	}
+
+	var days []time.Weekday
+	for _, part := range strings.Split(value, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+		if len(part) > 3 {
+			part = part[:3]
+		}
+		day, ok := names[part]
+		if !ok {
+			return nil, fmt.Errorf("unknown weekday %q", part)
+		}
+		days = append(days, day)
+	}
+
+	return days, nil
+}
+
+// reloadHolidays refreshes the working calendar from the store
+func reloadHolidays(s HolidayStore) error {
+	holidays, err := s.GetHolidays("", "")
+	if err != nil {
+		return err
+	}
+	calendar.SetHolidays(holidays)
+	return nil
+}
+
+// ImportHolidays adds holidays whose dates are not already in the store and
+// returns how many were created and how many were skipped as duplicates
+func ImportHolidays(s HolidayStore, holidays []Holiday) (created, skipped int, err error) {
+	existing, err := s.GetHolidays("", "")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	seen := make(map[string]bool, len(existing))
+	for _, h := range existing {
+		seen[h.Date] = true
+	}
+
+	for _, h := range holidays {
+		if seen[h.Date] {
+			skipped++
+			continue
+		}
+		if _, err := s.CreateHoliday(h.Date, h.Name); err != nil {
+			return created, skipped, err
+		}
+		seen[h.Date] = true
+		created++
+	}
+
+	return created, skipped, nil
+}
+
+// ParseICS extracts one holiday per day covered by each VEVENT in an
+// iCalendar file. Recurrence rules are not expanded.
+func ParseICS(r io.Reader) ([]Holiday, error) {
+	lines, err := unfoldICSLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var holidays []Holiday
+	var inEvent bool
+	var summary, start, end string
+
+	for _, line := range lines {
+		name, _, value := splitICSLine(line)
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			inEvent = true
+			summary, start, end = "", "", ""
+		case name == "END" && value == "VEVENT":
+			inEvent = false
+			if start == "" {
+				continue
+			}
+			days, err := icsEventDays(start, end)
+			if err != nil {
+				return nil, err
+			}
+			for _, day := range days {
+				holidays = append(holidays, Holiday{Date: day, Name: summary})
+			}
+		case inEvent && name == "SUMMARY":
+			summary = unescapeICSText(value)
+		case inEvent && name == "DTSTART":
+			start = icsDateValue(value)
+		case inEvent && name == "DTEND":
+			end = icsDateValue(value)
+		}
+	}
+
+	return holidays, nil
+}
+
+// unfoldICSLines joins RFC 5545 folded lines (continuations start with a space or tab)
+func unfoldICSLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, scanner.Err()
+}
+
+// splitICSLine splits "NAME;PARAM=X:VALUE" into its name, parameters and value
+func splitICSLine(line string) (name, params, value string) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return strings.ToUpper(line), "", ""
+	}
+
+	head := line[:colon]
+	value = line[colon+1:]
+	if semi := strings.Index(head, ";"); semi >= 0 {
+		return strings.ToUpper(head[:semi]), head[semi+1:], value
+	}
+	return strings.ToUpper(head), "", value
+}
+
+// icsDateValue returns the YYYYMMDD part of a DATE or DATE-TIME value
+func icsDateValue(value string) string {
+	if len(value) >= 8 {
+		return value[:8]
+	}
+	return value
+}
+
+// icsEventDays lists every date an event covers; DTEND is exclusive
+func icsEventDays(start, end string) ([]string, error) {
+	from, err := time.Parse("20060102", start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DTSTART %q", start)
+	}
+
+	to := from.AddDate(0, 0, 1)
+	if end != "" {
+		to, err = time.Parse("20060102", end)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DTEND %q", end)
+		}
+		if !to.After(from) {
+			to = from.AddDate(0, 0, 1)
+		}
+	}
+
+	var days []string
+	for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+		days = append(days, d.Format("2006-01-02"))
+	}
+	return days, nil
+}
+
+func unescapeICSText(value string) string {
+	replacer := strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return replacer.Replace(value)
+}
+
+// escapeICSText escapes a TEXT value: backslashes, semicolons, commas and
+// line breaks
+func escapeICSText(value string) string {
+	value = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(value)
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+	return replacer.Replace(value)
+}
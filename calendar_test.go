@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// testCalendar has a Saturday and Sunday weekend, a holiday on Friday
+// 2026-04-03 and one on Saturday 2026-04-04, which closes nothing extra
+func testCalendar() *WorkCalendar {
+	c := NewWorkCalendar([]time.Weekday{time.Saturday, time.Sunday})
+	c.SetHolidays([]Holiday{{Date: "2026-04-03", Name: "Good Friday"}, {Date: "2026-04-04", Name: "Holy Saturday"}})
+	return c
+}
+
+// useCalendar makes c the working calendar until the test ends
+func useCalendar(t *testing.T, c *WorkCalendar) {
+	t.Helper()
+	prev := calendar
+	calendar = c
+	t.Cleanup(func() { calendar = prev })
+}
+
+// mustParseDate parses a YYYY-MM-DD date or fails the test
+func mustParseDate(t *testing.T, date string) time.Time {
+	t.Helper()
+	d, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestWorkingDaysBetween(t *testing.T) {
+	c := testCalendar()
+	tests := []struct {
+		start, end string
+		want       int
+	}{
+		{"2026-03-30", "2026-03-30", 0}, // Same day
+		{"2026-03-31", "2026-03-30", 0}, // Backwards
+		{"2026-03-30", "2026-03-31", 1}, // Monday to Tuesday
+		{"2026-03-27", "2026-03-30", 1}, // Friday over the weekend to Monday
+		{"2026-03-28", "2026-03-29", 0}, // Within the weekend
+		{"2026-04-02", "2026-04-06", 1}, // Thursday over the holiday weekend to Monday
+		{"2026-04-03", "2026-04-03", 0}, // Start on the holiday
+		{"2026-03-30", "2026-04-03", 3}, // End on the holiday
+		{"2026-03-02", "2026-04-30", 42},
+	}
+	for _, tt := range tests {
+		got := c.WorkingDaysBetween(mustParseDate(t, tt.start), mustParseDate(t, tt.end))
+		if got != tt.want {
+			t.Errorf("%s to %s: got %d, want %d", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+// WorkingDaysBetween counts whole weeks at once; it must agree with walking
+// the range a day at a time for every start weekday, length and weekend
+func TestWorkingDaysBetweenMatchesDayByDay(t *testing.T) {
+	weekends := [][]time.Weekday{
+		{time.Saturday, time.Sunday},
+		{time.Friday, time.Saturday},
+		{time.Sunday},
+		nil,
+	}
+	holidays := []Holiday{{Date: "2026-01-01"}, {Date: "2026-01-16"}, {Date: "2026-01-17"}, {Date: "2026-02-02"}}
+
+	for _, weekend := range weekends {
+		c := NewWorkCalendar(weekend)
+		c.SetHolidays(holidays)
+		for first := 0; first < 14; first++ {
+			start := mustParseDate(t, "2025-12-25").AddDate(0, 0, first)
+			for days := 0; days < 60; days++ {
+				end := start.AddDate(0, 0, days)
+				want := 0
+				for d := start.AddDate(0, 0, 1); !d.After(end); d = d.AddDate(0, 0, 1) {
+					if c.IsWorkingDay(d) {
+						want++
+					}
+				}
+				if got := c.WorkingDaysBetween(start, end); got != want {
+					t.Fatalf("weekend %v, %s to %s: got %d, want %d", weekend, start.Format("2006-01-02"), end.Format("2006-01-02"), got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestWorkingDayStepsSkipHolidays(t *testing.T) {
+	c := testCalendar()
+	if got := c.NextWorkingDay("2026-04-02"); got != "2026-04-06" {
+		t.Errorf("next working day after 2026-04-02: got %s, want 2026-04-06", got)
+	}
+	if got := c.PreviousWorkingDay("2026-04-06"); got != "2026-04-02" {
+		t.Errorf("previous working day before 2026-04-06: got %s, want 2026-04-02", got)
+	}
+}
+
+func TestCalculateBusinessDaysUsesCalendar(t *testing.T) {
+	useCalendar(t, testCalendar())
+
+	if got := CalculateBusinessDays("2026-04-02", "2026-04-06"); got != 1 {
+		t.Errorf("got %d drag days over the holiday weekend, want 1", got)
+	}
+	if got := CalculateBusinessDays("not a date", "2026-04-06"); got != 0 {
+		t.Errorf("got %d for an invalid date, want 0", got)
+	}
+}
+
+func TestParseWeekend(t *testing.T) {
+	days, err := ParseWeekend("Fri, saturday")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 2 || days[0] != time.Friday || days[1] != time.Saturday {
+		t.Errorf("got %v, want [Friday Saturday]", days)
+	}
+	if _, err := ParseWeekend("sat,funday"); err == nil {
+		t.Error("got no error for an unknown weekday")
+	}
+}
+
+func TestStoreHolidays(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		christmas, err := s.CreateHoliday("2026-12-25", "Christmas")
+		if err != nil {
+			t.Fatal(err)
+		}
+		boxing, err := s.CreateHoliday("2026-12-26", "Boxing Day")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.CreateHoliday("2026-12-25", "Again"); !errors.Is(err, ErrDuplicate) {
+			t.Errorf("got %v creating a second holiday on a date, want ErrDuplicate", err)
+		}
+		if _, err := s.UpdateHoliday(boxing.ID, christmas.Date, "Clash"); !errors.Is(err, ErrDuplicate) {
+			t.Errorf("got %v moving a holiday onto another, want ErrDuplicate", err)
+		}
+		if _, err := s.UpdateHoliday(boxing.ID+100, "2026-12-31", "Missing"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v updating a missing holiday, want ErrNotFound", err)
+		}
+		if _, err := s.UpdateHoliday(boxing.ID, boxing.Date, boxing.Name); err != nil {
+			t.Errorf("got %v saving a holiday unchanged", err)
+		}
+
+		holidays, err := s.GetHolidays("2026-12-26", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(holidays) != 1 || holidays[0].Name != "Boxing Day" {
+			t.Errorf("got %+v from 2026-12-26 on, want Boxing Day", holidays)
+		}
+	})
+}
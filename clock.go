@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+	_ "time/tzdata" // embed zone data so -tz works on hosts without zoneinfo
+)
+
+// DefaultTimezone is the server-wide timezone used when none is configured
+const DefaultTimezone = "Asia/Kolkata"
+
+// Clock supplies the current time to every date computation
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock reports the real wall-clock time
+type SystemClock struct{}
+
+// Now returns the current time
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SimulatedClock runs at normal speed but offset onto a chosen date, so
+// rollover and drag-day behavior can be exercised across days
+type SimulatedClock struct {
+	mu     sync.RWMutex
+	offset time.Duration
+}
+
+// NewSimulatedClock returns a clock whose current date is date in loc
+func NewSimulatedClock(date string, loc *time.Location) (*SimulatedClock, error) {
+	c := &SimulatedClock{}
+	if err := c.SetDate(date, loc); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Now returns the simulated current time
+func (c *SimulatedClock) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return time.Now().Add(c.offset)
+}
+
+// SetDate moves the clock to date in loc, keeping the current time of day
+func (c *SimulatedClock) SetDate(date string, loc *time.Location) error {
+	target, err := time.ParseInLocation("2006-01-02", date, loc)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().In(loc)
+	y, m, d := now.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, loc)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offset = target.Sub(midnight)
+	return nil
+}
+
+// clock and location drive every "today" computation; both are set up in main
+var (
+	clock    Clock = SystemClock{}
+	location       = mustLoadLocation(DefaultTimezone)
+)
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// NowIn returns the current time in loc
+func NowIn(loc *time.Location) time.Time {
+	return clock.Now().In(loc)
+}
+
+// TodayIn returns today's date in loc
+func TodayIn(loc *time.Location) string {
+	return NowIn(loc).Format("2006-01-02")
+}
+
+// GetToday returns today's date in the server timezone
+func GetToday() string {
+	return TodayIn(location)
+}
+
+// AddDays returns the YYYY-MM-DD date n days after date
+func AddDays(date string, n int) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return t.AddDate(0, 0, n).Format("2006-01-02")
+}
+
+// isValidDate reports whether date is a valid YYYY-MM-DD string
+func isValidDate(date string) bool {
+	_, err := time.Parse("2006-01-02", date)
+	return err == nil
+}
+
+type locationKey struct{}
+
+// timezoneMiddleware resolves the caller's timezone from the X-Timezone
+// header or tz query parameter, falling back to the server timezone
+func timezoneMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("tz")
+		if name == "" {
+			name = r.Header.Get("X-Timezone")
+		}
+
+		loc := location
+		if name != "" {
+			var err error
+			loc, err = time.LoadLocation(name)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "Invalid timezone: "+name)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), locationKey{}, loc)))
+	})
+}
+
+// requestLocation returns the timezone resolved for the request
+func requestLocation(r *http.Request) *time.Location {
+	if loc, ok := r.Context().Value(locationKey{}).(*time.Location); ok {
+		return loc
+	}
+	return location
+}
+
+// requestToday returns today's date in the request's timezone
+func requestToday(r *http.Request) string {
+	return TodayIn(requestLocation(r))
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fixedClock is a Clock that stands still
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+// testNow is half past midnight UTC on testMonday, still the day before in
+// Honolulu
+var testNow = time.Date(2026, 3, 2, 0, 30, 0, 0, time.UTC)
+
+// setClock stops the clock at now until the test ends
+func setClock(t *testing.T, now time.Time) {
+	t.Helper()
+	prev := clock
+	clock = fixedClock(now)
+	t.Cleanup(func() { clock = prev })
+}
+
+func TestTodayIn(t *testing.T) {
+	setClock(t, testNow)
+	tests := []struct {
+		zone string
+		want string
+	}{
+		{"UTC", testMonday},
+		{"Asia/Kolkata", testMonday},
+		{"Pacific/Honolulu", "2026-03-01"},
+	}
+	for _, tt := range tests {
+		if got := TodayIn(mustLoadLocation(tt.zone)); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.zone, got, tt.want)
+		}
+	}
+}
+
+func TestSimulatedClock(t *testing.T) {
+	loc := mustLoadLocation("Pacific/Honolulu")
+	c, err := NewSimulatedClock("2030-01-31", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Now().In(loc).Format("2006-01-02"); got != "2030-01-31" {
+		t.Errorf("got %s, want 2030-01-31", got)
+	}
+
+	if err := c.SetDate("2030-02-01", loc); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Now().In(loc).Format("2006-01-02"); got != "2030-02-01" {
+		t.Errorf("got %s after moving on a day, want 2030-02-01", got)
+	}
+	if err := c.SetDate("01/02/2030", loc); err == nil {
+		t.Error("got no error for an invalid date")
+	}
+}
+
+func TestAddDays(t *testing.T) {
+	if got := AddDays("2026-02-28", 1); got != "2026-03-01" {
+		t.Errorf("got %s, want 2026-03-01", got)
+	}
+	if got := AddDays("2026-03-01", -1); got != "2026-02-28" {
+		t.Errorf("got %s, want 2026-02-28", got)
+	}
+	if got := AddDays("not a date", 1); got != "not a date" {
+		t.Errorf("got %s for an invalid date, want it unchanged", got)
+	}
+}
+
+func TestTimezoneMiddleware(t *testing.T) {
+	setClock(t, testNow)
+	today := func(w http.ResponseWriter, r *http.Request) {
+		respondJSON(w, http.StatusOK, map[string]string{"today": requestToday(r)})
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		header string
+		want   string
+	}{
+		{"server timezone", "/", "", testMonday},
+		{"query parameter", "/?tz=Pacific/Honolulu", "", "2026-03-01"},
+		{"header", "/", "Pacific/Honolulu", "2026-03-01"},
+		{"query parameter over header", "/?tz=UTC", "Pacific/Honolulu", testMonday},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.target, nil)
+			if tt.header != "" {
+				r.Header.Set("X-Timezone", tt.header)
+			}
+			w := httptest.NewRecorder()
+			timezoneMiddleware(http.HandlerFunc(today)).ServeHTTP(w, r)
+
+			var resp map[string]string
+			decodeResponse(t, w, http.StatusOK, &resp)
+			if resp["today"] != tt.want {
+				t.Errorf("got %s, want %s", resp["today"], tt.want)
+			}
+		})
+	}
+
+	var resp map[string]string
+	decodeResponse(t, serve(t, today, "GET", "/?tz=Mars/Olympus_Mons", nil), http.StatusBadRequest, &resp)
+}
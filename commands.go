@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// runCommand dispatches a command-line subcommand such as "migrate status"
+func runCommand(dbPath string, args []string) error {
+	switch args[0] {
+	case "migrate":
+		return runMigrateCommand(dbPath, args[1:])
+	case "holidays":
+		return runHolidaysCommand(dbPath, args[1:])
+	case "todotxt":
+		return runTodoTxtCommand(dbPath, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// runMigrateCommand handles "migrate status", "migrate up" and "migrate down [steps]"
+func runMigrateCommand(dbPath string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate status|up|down [steps]")
+	}
+
+	db, err := openDB(dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "status":
+		statuses, err := GetMigrationStatus(db)
+		if err != nil {
+			return err
+		}
+
+		version, err := GetSchemaVersion(db)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Schema version: %d (binary supports %d)\n", version, LatestSchemaVersion())
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("  %3d  %-40s %s\n", s.Version, s.Name, state)
+		}
+		return nil
+
+	case "up":
+		count, err := MigrateUp(db)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d migration(s)\n", count)
+		return nil
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid step count %q", args[1])
+			}
+			steps = n
+		}
+
+		count, err := MigrateDown(db, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Reverted %d migration(s)\n", count)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
+
+// runHolidaysCommand handles "holidays list" and "holidays import <file.ics>"
+func runHolidaysCommand(dbPath string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: holidays list|import <file.ics>")
+	}
+
+	s, err := NewSQLiteStore(dbPath)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	switch args[0] {
+	case "list":
+		holidays, err := s.GetHolidays("", "")
+		if err != nil {
+			return err
+		}
+		for _, h := range holidays {
+			fmt.Printf("%s  %s\n", h.Date, h.Name)
+		}
+		return nil
+
+	case "import":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: holidays import <file.ics>")
+		}
+
+		f, err := os.Open(args[1])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		holidays, err := ParseICS(f)
+		if err != nil {
+			return err
+		}
+
+		created, skipped, err := ImportHolidays(s, holidays)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported %d holiday(s), skipped %d already present\n", created, skipped)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown holidays command %q", args[0])
+	}
+}
+
+// runTodoTxtCommand handles "todotxt export [file]", which writes to stdout
+// without a file, and "todotxt import <file>"
+func runTodoTxtCommand(dbPath string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: todotxt export [file] | todotxt import <file>")
+	}
+
+	s, err := NewSQLiteStore(dbPath)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	switch args[0] {
+	case "export":
+		if len(args) < 2 {
+			return WriteTodoTxt(s, os.Stdout, nil)
+		}
+
+		f, err := os.Create(args[1])
+		if err != nil {
+			return err
+		}
+		if err := WriteTodoTxt(s, f, nil); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+
+	case "import":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: todotxt import <file>")
+		}
+
+		f, err := os.Open(args[1])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		lines, err := ParseTodoTxt(f)
+		if err != nil {
+			return err
+		}
+
+		result, err := s.ImportTodoTxt(lines, GetToday(), "cli")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created %d task(s), updated %d, unchanged %d, skipped %d already present\n",
+			result.Created, result.Updated, result.Unchanged, result.Skipped)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown todotxt command %q", args[0])
+	}
+}
@@ -2,122 +2,212 @@ package main
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
-var db *sql.DB
+// SQLiteStore is the Store implementation backed by a SQLite database file
+type SQLiteStore struct {
+	db  *sql.DB
+	fts bool // Whether the tasks_fts search index is available
+}
+
+// openDB opens the SQLite database at path without touching its schema
+func openDB(path string) (*sql.DB, error) {
+	conn, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
 
-// IST timezone (UTC+5:30)
-var IST = time.FixedZone("IST", 5*60*60+30*60)
+	if err := conn.Ping(); err != nil {
+		conn.Close()
+		return nil, err
+	}
 
-// GetTodayIST returns today's date in IST
-func GetTodayIST() string {
-	return time.Now().In(IST).Format("2006-01-02")
+	return conn, nil
 }
 
-// GetYesterdayIST returns yesterday's date in IST
-func GetYesterdayIST() string {
-	return time.Now().In(IST).AddDate(0, 0, -1).Format("2006-01-02")
+// NewSQLiteStore opens the database at path, migrates it to the latest schema
+// and seeds the default categories
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	conn, err := openDB(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bring the schema up to date; refuses databases newer than this binary
+	if _, err := MigrateUp(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	s := &SQLiteStore{db: conn}
+	if err := seedDefaultCategories(s); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err := s.setupSearchIndex(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return s, nil
 }
 
-func initDB() error {
-	var err error
-	db, err = sql.Open("sqlite3", "./todo.db")
+// setupSearchIndex keeps the tasks_fts full-text index in sync with tasks when
+// SQLite was built with FTS5 (go build -tags sqlite_fts5). The index is derived
+// data, so it lives outside the numbered migrations: a binary without FTS5
+// drops the sync triggers (which it could not run) and searches with LIKE,
+// and the next FTS5 binary to open the database rebuilds the index.
+func (s *SQLiteStore) setupSearchIndex() error {
+	var enabled bool
+	if err := s.db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&enabled); err != nil {
+		return err
+	}
+
+	if !enabled {
+		_, err := s.db.Exec(`
+		DROP TRIGGER IF EXISTS tasks_fts_insert;
+		DROP TRIGGER IF EXISTS tasks_fts_delete;
+		DROP TRIGGER IF EXISTS tasks_fts_update;
+		`)
+		return err
+	}
+
+	var triggers int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'tasks_fts_%'`).Scan(&triggers)
 	if err != nil {
 		return err
 	}
 
-	// Create tables
-	createTableSQL := `
-	CREATE TABLE IF NOT EXISTS categories (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL UNIQUE,
-		color TEXT NOT NULL DEFAULT '#58a6ff',
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
+	if triggers < 3 {
+		err := s.withTx(func(tx *sql.Tx) error {
+			return execSQL(tx, `
+			CREATE VIRTUAL TABLE IF NOT EXISTS tasks_fts USING fts5(
+				title, description,
+				content = 'tasks', content_rowid = 'id',
+				tokenize = 'unicode61 remove_diacritics 2'
+			);
+
+			DROP TRIGGER IF EXISTS tasks_fts_insert;
+			DROP TRIGGER IF EXISTS tasks_fts_delete;
+			DROP TRIGGER IF EXISTS tasks_fts_update;
+
+			CREATE TRIGGER tasks_fts_insert AFTER INSERT ON tasks BEGIN
+				INSERT INTO tasks_fts (rowid, title, description) VALUES (new.id, new.title, new.description);
+			END;
+
+			CREATE TRIGGER tasks_fts_delete AFTER DELETE ON tasks BEGIN
+				INSERT INTO tasks_fts (tasks_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+			END;
+
+			CREATE TRIGGER tasks_fts_update AFTER UPDATE OF title, description ON tasks BEGIN
+				INSERT INTO tasks_fts (tasks_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+				INSERT INTO tasks_fts (rowid, title, description) VALUES (new.id, new.title, new.description);
+			END;
+
+			INSERT INTO tasks_fts (tasks_fts) VALUES ('rebuild');
+			`)
+		})
+		if err != nil {
+			return err
+		}
+	}
 
-	CREATE TABLE IF NOT EXISTS tasks (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		title TEXT NOT NULL,
-		description TEXT DEFAULT '',
-		created_date TEXT NOT NULL,
-		assigned_date TEXT NOT NULL,
-		completed_date TEXT,
-		is_completed BOOLEAN DEFAULT FALSE,
-		category_id INTEGER,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE SET NULL
-	);
+	s.fts = true
+	return nil
+}
 
-	CREATE INDEX IF NOT EXISTS idx_assigned_date ON tasks(assigned_date);
-	CREATE INDEX IF NOT EXISTS idx_completed_date ON tasks(completed_date);
-	CREATE INDEX IF NOT EXISTS idx_category_id ON tasks(category_id);
-	`
+// Close closes the underlying database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
 
-	_, err = db.Exec(createTableSQL)
+// withTx runs fn in a transaction, committing only if it returns nil
+func (s *SQLiteStore) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	// Add category_id column if it doesn't exist (for existing databases)
-	db.Exec(`ALTER TABLE tasks ADD COLUMN category_id INTEGER REFERENCES categories(id) ON DELETE SET NULL`)
-
-	// Insert default categories if none exist
-	var count int
-	err = db.QueryRow(`SELECT COUNT(*) FROM categories`).Scan(&count)
-	if err != nil || count == 0 {
-		db.Exec(`INSERT OR IGNORE INTO categories (name, color) VALUES ('Work', '#58a6ff')`)
-		db.Exec(`INSERT OR IGNORE INTO categories (name, color) VALUES ('Personal', '#3fb950')`)
-		db.Exec(`INSERT OR IGNORE INTO categories (name, color) VALUES ('Misc', '#f0883e')`)
+	if err := fn(tx); err != nil {
+		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
 
 // Category CRUD operations
 
 // CreateCategory creates a new category
-func CreateCategory(name, color string) (*Category, error) {
-	result, err := db.Exec(
-		`INSERT INTO categories (name, color) VALUES (?, ?)`,
-		name, color,
+func (s *SQLiteStore) CreateCategory(req CategoryRequest) (*Category, error) {
+	result, err := s.db.Exec(
+		`INSERT INTO categories (name, color, rollover_policy, archive_after_days, stale_after_days, critical_after_days) VALUES (?, ?, ?, ?, ?, ?)`,
+		req.Name, req.Color, req.RolloverPolicy, req.ArchiveAfterDays, req.StaleAfterDays, req.CriticalAfterDays,
 	)
+	if isUniqueViolation(err) {
+		return nil, ErrDuplicate
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	id, _ := result.LastInsertId()
-	return GetCategoryByID(id)
+	return s.GetCategoryByID(id)
 }
 
-// GetCategoryByID retrieves a category by ID
-func GetCategoryByID(id int64) (*Category, error) {
+// categoryColumns selects a category in the order scanCategory reads it
+const categoryColumns = `c.id, c.name, c.color, c.rollover_policy, c.archive_after_days, c.stale_after_days, c.critical_after_days, c.created_at`
+
+// scanCategory reads a category selected with categoryColumns, followed by
+// any extra columns into extra
+func scanCategory(row rowScanner, extra ...interface{}) (*Category, error) {
 	cat := &Category{}
-	var createdAt string
+	var archiveAfterDays, staleAfterDays, criticalAfterDays sql.NullInt64
+
+	dest := append([]interface{}{&cat.ID, &cat.Name, &cat.Color, &cat.RolloverPolicy, &archiveAfterDays, &staleAfterDays, &criticalAfterDays, &cat.CreatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	cat.ArchiveAfterDays = nullIntPtr(archiveAfterDays)
+	cat.StaleAfterDays = nullIntPtr(staleAfterDays)
+	cat.CriticalAfterDays = nullIntPtr(criticalAfterDays)
+	return cat, nil
+}
 
-	err := db.QueryRow(
-		`SELECT id, name, color, created_at FROM categories WHERE id = ?`,
+// GetCategoryByID retrieves a category by ID
+func (s *SQLiteStore) GetCategoryByID(id int64) (*Category, error) {
+	cat, err := scanCategory(s.db.QueryRow(
+		`SELECT `+categoryColumns+` FROM categories c WHERE c.id = ? AND c.deleted_at IS NULL`,
 		id,
-	).Scan(&cat.ID, &cat.Name, &cat.Color, &createdAt)
+	))
 
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	cat.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
 	return cat, nil
 }
 
 // GetAllCategories retrieves all categories with task counts
-func GetAllCategories() ([]Category, error) {
-	rows, err := db.Query(
-		`SELECT c.id, c.name, c.color, c.created_at, 
-		 (SELECT COUNT(*) FROM tasks WHERE category_id = c.id AND is_completed = FALSE) as task_count
-		 FROM categories c ORDER BY c.name ASC`,
+func (s *SQLiteStore) GetAllCategories() ([]Category, error) {
+	rows, err := s.db.Query(
+		`SELECT ` + categoryColumns + `,
+		 (SELECT COUNT(*) FROM tasks WHERE category_id = c.id AND is_completed = FALSE AND deleted_at IS NULL) as task_count
+		 FROM categories c WHERE c.deleted_at IS NULL ORDER BY c.name ASC`,
 	)
 	if err != nil {
 		return nil, err
@@ -126,459 +216,2957 @@ func GetAllCategories() ([]Category, error) {
 
 	var categories []Category
 	for rows.Next() {
-		var cat Category
-		var createdAt string
+		var taskCount int
 
-		err := rows.Scan(&cat.ID, &cat.Name, &cat.Color, &createdAt, &cat.TaskCount)
+		cat, err := scanCategory(rows, &taskCount)
 		if err != nil {
 			return nil, err
 		}
+		cat.TaskCount = taskCount
 
-		cat.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
-		categories = append(categories, cat)
+		categories = append(categories, *cat)
 	}
 
 	return categories, nil
 }
 
 // UpdateCategory updates a category
-func UpdateCategory(id int64, name, color string) (*Category, error) {
-	_, err := db.Exec(
-		`UPDATE categories SET name = ?, color = ? WHERE id = ?`,
-		name, color, id,
+func (s *SQLiteStore) UpdateCategory(id int64, req CategoryRequest) (*Category, error) {
+	_, err := s.db.Exec(
+		`UPDATE categories SET name = ?, color = ?, rollover_policy = ?, archive_after_days = ?, stale_after_days = ?, critical_after_days = ?
+		 WHERE id = ? AND deleted_at IS NULL`,
+		req.Name, req.Color, req.RolloverPolicy, req.ArchiveAfterDays, req.StaleAfterDays, req.CriticalAfterDays, id,
 	)
+	if isUniqueViolation(err) {
+		return nil, ErrDuplicate
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	return GetCategoryByID(id)
+	return s.GetCategoryByID(id)
 }
 
-// DeleteCategory deletes a category
-func DeleteCategory(id int64) error {
-	_, err := db.Exec(`DELETE FROM categories WHERE id = ?`, id)
-	return err
+// DeleteCategory moves a category to the trash. Its tasks keep their
+// category_id but show as uncategorized until the category is restored.
+func (s *SQLiteStore) DeleteCategory(id int64, actor string) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		var name string
+		err := tx.QueryRow(`SELECT name FROM categories WHERE id = ? AND deleted_at IS NULL`, id).Scan(&name)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		before, err := readJournalStateTx(tx, nil, []int64{id})
+		if err != nil {
+			return err
+		}
+		if err := writeCategoryStateTx(tx, categoryState{ID: id, Deleted: true}, actor); err != nil {
+			return err
+		}
+		return journalTx(tx, OpDeleteCategory, operationSummary(OpDeleteCategory, name, 0), actor, before)
+	})
 }
 
-// CreateTask creates a new task
-func CreateTask(title, description, date string) (*Task, error) {
+// CreateTask creates a new task from req, assigned to req.Date (default today)
+func (s *SQLiteStore) CreateTask(req TaskRequest, actor string) (*Task, error) {
+	date := req.Date
 	if date == "" {
-		date = GetTodayIST()
+		date = GetToday()
 	}
 
-	result, err := db.Exec(
-		`INSERT INTO tasks (title, description, created_date, assigned_date, is_completed) VALUES (?, ?, ?, ?, ?)`,
-		title, description, date, date, false,
-	)
+	var id int64
+	err := s.withTx(func(tx *sql.Tx) error {
+		var err error
+		id, err = createTaskTx(tx, req, date, actor)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	return s.GetTaskByID(id)
+}
+
+// createTaskTx is CreateTask inside tx, with the date already defaulted
+func createTaskTx(tx *sql.Tx, req TaskRequest, date, actor string) (int64, error) {
+	result, err := tx.Exec(
+		`INSERT INTO tasks (title, description, created_date, assigned_date, is_completed, category_id, priority, due_date, recurring_id)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		req.Title, req.Description, date, date, false, req.CategoryID, emptyToNil(req.Priority), emptyToNil(req.DueDate), req.RecurringID,
+	)
+	if err != nil {
+		return 0, err
+	}
+
 	id, _ := result.LastInsertId()
-	return GetTaskByID(id)
+	if err := recordTaskEvent(tx, id, EventCreated, nil, &date, actor); err != nil {
+		return 0, err
+	}
+
+	// Tasks materialized from recurring templates are not the user's doing
+	if req.RecurringID != nil {
+		return id, nil
+	}
+	before, err := readJournalStateTx(tx, []int64{id}, nil)
+	if err != nil {
+		return 0, err
+	}
+	before.Tasks[0].Deleted = true
+	return id, journalTx(tx, OpCreateTask, operationSummary(OpCreateTask, req.Title, 1), actor, before)
 }
 
 // GetTaskByID retrieves a task by ID
-func GetTaskByID(id int64) (*Task, error) {
-	task := &Task{}
-	var completedDate sql.NullString
-	var categoryID sql.NullInt64
-	var createdAt, updatedAt string
+func (s *SQLiteStore) GetTaskByID(id int64) (*Task, error) {
+	tasks, err := s.queryTasks(taskColumns+` WHERE t.id = ? AND t.deleted_at IS NULL`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, ErrNotFound
+	}
 
-	err := db.QueryRow(
-		`SELECT id, title, description, created_date, assigned_date, completed_date, is_completed, category_id, created_at, updated_at FROM tasks WHERE id = ?`,
-		id,
-	).Scan(&task.ID, &task.Title, &task.Description, &task.CreatedDate, &task.AssignedDate, &completedDate, &task.IsCompleted, &categoryID, &createdAt, &updatedAt)
+	return &tasks[0], nil
+}
 
-	if err != nil {
+// taskColumns selects tasks joined with their category, in the order
+// scanTask reads them. Trashed tasks still match; callers filter on
+// t.deleted_at.
+const taskColumns = `SELECT ` + taskFields + ` FROM ` + taskTables
+
+const taskFields = `t.id, t.title, t.description, t.created_date, t.assigned_date, t.completed_date, t.is_completed,
+	t.category_id, t.priority, t.due_date, t.recurring_id, t.created_at, t.updated_at, t.deleted_at, c.name, c.color, c.rollover_policy, c.archive_after_days,
+	c.stale_after_days, c.critical_after_days, c.created_at`
+
+const taskTables = `tasks t LEFT JOIN categories c ON c.id = t.category_id AND c.deleted_at IS NULL`
+
+// scanTask reads a task row selected with taskColumns, followed by any extra
+// columns into extra
+func scanTask(row rowScanner, extra ...interface{}) (*Task, error) {
+	task := &Task{}
+	var completedDate, priority, dueDate, categoryName, categoryColor, categoryPolicy sql.NullString
+	var categoryID, recurringID, archiveAfterDays, staleAfterDays, criticalAfterDays sql.NullInt64
+	var deletedAt, categoryCreatedAt sql.NullTime
+
+	dest := append([]interface{}{&task.ID, &task.Title, &task.Description, &task.CreatedDate, &task.AssignedDate, &completedDate, &task.IsCompleted,
+		&categoryID, &priority, &dueDate, &recurringID, &task.CreatedAt, &task.UpdatedAt, &deletedAt,
+		&categoryName, &categoryColor, &categoryPolicy, &archiveAfterDays, &staleAfterDays, &criticalAfterDays, &categoryCreatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
 		return nil, err
 	}
 
-	if completedDate.Valid {
-		task.CompletedDate = &completedDate.String
+	if deletedAt.Valid {
+		task.DeletedAt = &deletedAt.Time
 	}
 
-	if categoryID.Valid {
+	task.CompletedDate = nullStringPtr(completedDate)
+	task.Priority = nullStringPtr(priority)
+	task.DueDate = nullStringPtr(dueDate)
+	task.RecurringID = nullInt64Ptr(recurringID)
+
+	// A category in the trash leaves its tasks uncategorized for now
+	if categoryID.Valid && categoryName.Valid {
 		task.CategoryID = &categoryID.Int64
-		task.Category, _ = GetCategoryByID(categoryID.Int64)
+		task.Category = &Category{
+			ID:                categoryID.Int64,
+			Name:              categoryName.String,
+			Color:             categoryColor.String,
+			RolloverPolicy:    categoryPolicy.String,
+			ArchiveAfterDays:  nullIntPtr(archiveAfterDays),
+			StaleAfterDays:    nullIntPtr(staleAfterDays),
+			CriticalAfterDays: nullIntPtr(criticalAfterDays),
+			CreatedAt:         categoryCreatedAt.Time,
+		}
 	}
 
-	task.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
-	task.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAt)
-
-	// Calculate drag days
 	task.DragDays = CalculateBusinessDays(task.CreatedDate, task.AssignedDate)
-
+	markStale(task)
 	return task, nil
 }
 
-// GetTasksByDate retrieves all tasks for a specific date
-func GetTasksByDate(date string) ([]Task, error) {
-	rows, err := db.Query(
-		`SELECT id, title, description, created_date, assigned_date, completed_date, is_completed, category_id, created_at, updated_at 
-		 FROM tasks WHERE assigned_date = ? OR (completed_date = ? AND is_completed = TRUE)
-		 ORDER BY is_completed ASC, created_at ASC`,
-		date, date,
-	)
+// taskChunkSize is how many tasks EachTask loads checklist progress and tags
+// for at a time
+const taskChunkSize = 500
+
+// EachTask calls fn with each task matching q in q's sort order, starting
+// after q.After and stopping after q.Limit tasks when set. The tasks are read
+// with one query and their related rows loaded a chunk at a time, so any
+// number of them can be streamed. fn must not write to the store.
+func (s *SQLiteStore) EachTask(q TaskQuery, fn func(Task) error) error {
+	return s.eachTask(q, func(task Task, _ TaskCursor) error {
+		return fn(task)
+	})
+}
+
+// eachTask is EachTask also passing the cursor that resumes after each task
+func (s *SQLiteStore) eachTask(q TaskQuery, fn func(Task, TaskCursor) error) error {
+	where, args, terms := taskQueryFilters(q)
+	column, numeric := taskSortColumn(q.Sort)
+	order, beyond, key := `ASC`, `>`, `?`
+	if q.Descending {
+		order, beyond = `DESC`, `<`
+	}
+	if numeric {
+		key = `CAST(? AS INTEGER)`
+	}
+
+	// Missing values sort last in either direction, and ties by ID
+	if q.After != nil && q.After.Null {
+		where += fmt.Sprintf(` AND %s IS NULL AND t.id %s ?`, column, beyond)
+		args = append(args, q.After.ID)
+	} else if q.After != nil {
+		where += fmt.Sprintf(` AND (%[1]s IS NULL OR %[1]s %[2]s %[3]s OR (%[1]s = %[3]s AND t.id %[2]s ?))`, column, beyond, key)
+		args = append(args, q.After.Key, q.After.Key, q.After.ID)
+	}
+
+	query := `SELECT ` + taskFields + `, ` + column + ` FROM ` + taskTables + ` WHERE ` + where +
+		` ORDER BY ` + column + ` IS NULL, ` + column + ` ` + order + `, t.id ` + order
+	// LIKE only narrows a text search down, so rows are read until enough match
+	if q.Limit > 0 && len(terms) == 0 {
+		query += ` LIMIT ?`
+		args = append(args, q.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var tasks []Task
-	for rows.Next() {
-		var task Task
-		var completedDate sql.NullString
-		var categoryID sql.NullInt64
-		var createdAt, updatedAt string
-
-		err := rows.Scan(&task.ID, &task.Title, &task.Description, &task.CreatedDate, &task.AssignedDate, &completedDate, &task.IsCompleted, &categoryID, &createdAt, &updatedAt)
+	var chunk []Task
+	var cursors []TaskCursor
+	flush := func() error {
+		tasks, err := s.attachRelated(chunk)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		for i, task := range tasks {
+			if err := fn(task, cursors[i]); err != nil {
+				return err
+			}
 		}
+		chunk, cursors = chunk[:0], cursors[:0]
+		return nil
+	}
 
-		if completedDate.Valid {
-			task.CompletedDate = &completedDate.String
+	for read := 0; (q.Limit == 0 || read < q.Limit) && rows.Next(); {
+		var sortKey sql.NullString
+		task, err := scanTask(rows, &sortKey)
+		if err != nil {
+			return err
+		}
+		if !matchesSearchTerms(task.Title, task.Description, terms) {
+			continue
 		}
 
-		if categoryID.Valid {
-			task.CategoryID = &categoryID.Int64
-			task.Category, _ = GetCategoryByID(categoryID.Int64)
+		read++
+		chunk = append(chunk, *task)
+		cursors = append(cursors, TaskCursor{Sort: q.sortSpec(), Key: sortKey.String, Null: !sortKey.Valid, ID: task.ID})
+		if len(chunk) == taskChunkSize {
+			if err := flush(); err != nil {
+				return err
+			}
 		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
 
-		task.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
-		task.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAt)
-		task.DragDays = CalculateBusinessDays(task.CreatedDate, task.AssignedDate)
+	return flush()
+}
 
-		tasks = append(tasks, task)
+// Import plans and applies an import inside one transaction, so the plan
+// sees the same categories and tasks the import writes to. Imported records
+// keep their dates and timestamps, and the whole import is journaled as one
+// operation.
+func (s *SQLiteStore) Import(doc ImportDocument, opts ImportOptions, actor string) (*ImportReport, error) {
+	var report *ImportReport
+	err := s.withTx(func(tx *sql.Tx) error {
+		var before journalState
+		var err error
+		report, before, err = importTx(tx, doc, opts, actor)
+		if err != nil || !report.Committed {
+			return err
+		}
+		return journalTx(tx, OpImport, operationSummary(OpImport, "", len(before.Tasks)), actor, before)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return tasks, nil
+	return report, nil
 }
 
-// GetDailyLog retrieves the daily log for a specific date
-func GetDailyLog(date string) (*DailyLog, error) {
-	tasks, err := GetTasksByDate(date)
+// importTx plans and applies an import inside tx without journaling it. It
+// returns the report and, when applied, the state undoing the import puts
+// back: everything it created in the trash.
+func importTx(tx *sql.Tx, doc ImportDocument, opts ImportOptions, actor string) (*ImportReport, journalState, error) {
+	live, trashed, err := importCategoriesTx(tx)
 	if err != nil {
-		return nil, err
+		return nil, journalState{}, err
+	}
+	existing, err := importTaskKeysTx(tx)
+	if err != nil {
+		return nil, journalState{}, err
 	}
 
-	log := &DailyLog{
-		Date:  date,
-		Tasks: tasks,
+	report := planImport(doc, opts, live, trashed, existing)
+	if !report.canApply() {
+		return report, journalState{}, nil
 	}
 
-	for _, task := range tasks {
-		if task.IsCompleted {
-			log.CompletedCount++
-		} else {
-			log.PendingCount++
+	var categoryIDs, taskIDs []int64
+	for i := range report.Categories {
+		c := &report.Categories[i]
+		if c.Action != ImportCreated {
+			continue
+		}
+		result, err := tx.Exec(
+			`INSERT INTO categories (name, color, rollover_policy, archive_after_days, stale_after_days, critical_after_days, created_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			c.request.Name, c.request.Color, c.request.RolloverPolicy, c.request.ArchiveAfterDays, c.request.StaleAfterDays,
+			c.request.CriticalAfterDays, importTimestamp(c.createdAt).Format("2006-01-02 15:04:05"),
+		)
+		if err != nil {
+			return nil, journalState{}, err
 		}
+		id, _ := result.LastInsertId()
+		c.ID = &id
+		categoryIDs = append(categoryIDs, id)
 	}
+	report.linkCategories()
 
-	return log, nil
-}
+	for i := range report.Tasks {
+		t := &report.Tasks[i]
+		if t.Action != ImportCreated {
+			continue
+		}
+		result, err := tx.Exec(
+			`INSERT INTO tasks (title, description, created_date, assigned_date, completed_date, is_completed, category_id, priority, due_date, created_at, updated_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			t.task.Title, t.task.Description, t.task.CreatedDate, t.task.AssignedDate, t.task.CompletedDate, t.task.IsCompleted,
+			report.categoryID(*t), t.task.Priority, t.task.DueDate,
+			importTimestamp(t.task.CreatedAt).Format("2006-01-02 15:04:05"), importTimestamp(t.task.UpdatedAt).Format("2006-01-02 15:04:05"),
+		)
+		if err != nil {
+			return nil, journalState{}, err
+		}
+		id, _ := result.LastInsertId()
+		t.ID = &id
+		taskIDs = append(taskIDs, id)
+
+		for _, e := range importedTaskEvents(t.task) {
+			if err := recordTaskEvent(tx, id, e.Type, e.OldValue, e.NewValue, actor); err != nil {
+				return nil, journalState{}, err
+			}
+		}
+		if err := importTagsAndItemsTx(tx, id, t.task); err != nil {
+			return nil, journalState{}, err
+		}
+	}
 
-// GetAllDates retrieves all unique dates that have tasks
-func GetAllDates() ([]string, error) {
-	rows, err := db.Query(
-		`SELECT DISTINCT date FROM (
-			SELECT assigned_date as date FROM tasks
-			UNION
-			SELECT completed_date as date FROM tasks WHERE completed_date IS NOT NULL
-		) ORDER BY date DESC`,
-	)
+	// Undoing the import sends everything it created to the trash
+	before, err := readJournalStateTx(tx, taskIDs, categoryIDs)
 	if err != nil {
-		return nil, err
+		return nil, journalState{}, err
 	}
-	defer rows.Close()
-
-	var dates []string
-	for rows.Next() {
-		var date string
-		if err := rows.Scan(&date); err != nil {
-			return nil, err
-		}
-		dates = append(dates, date)
+	for i := range before.Tasks {
+		before.Tasks[i].Deleted = true
 	}
-
-	return dates, nil
-}
-
-// HistorySummary represents what was accomplished on a specific date
-type HistorySummary struct {
-	Date           string `json:"date"`
-	CompletedCount int    `json:"completed_count"`
-	PendingCount   int    `json:"pending_count"` // Tasks assigned but not yet completed
+	for i := range before.Categories {
+		before.Categories[i].Deleted = true
+	}
+	report.Committed = true
+	return report, before, nil
 }
 
-// GetHistorySummaries retrieves completion stats for all dates
-func GetHistorySummaries() ([]HistorySummary, error) {
-	// Get all unique dates (both assigned and completed)
-	dates, err := GetAllDates()
-	if err != nil {
-		return nil, err
+// importTagsAndItemsTx gives an imported task its tags, creating the ones not
+// yet stored, and its checklist
+func importTagsAndItemsTx(tx *sql.Tx, taskID int64, t ExportTask) error {
+	for _, name := range t.Tags {
+		tagID, err := tagIDTx(tx, name)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO task_tags (task_id, tag_id) VALUES (?, ?)`, taskID, tagID); err != nil {
+			return err
+		}
 	}
 
-	var summaries []HistorySummary
-
-	for _, date := range dates {
-		summary := HistorySummary{Date: date}
-
-		// Count tasks COMPLETED on this date
-		err := db.QueryRow(
-			`SELECT COUNT(*) FROM tasks WHERE completed_date = ? AND is_completed = TRUE`,
-			date,
-		).Scan(&summary.CompletedCount)
+	for i, item := range t.Items {
+		_, err := tx.Exec(
+			`INSERT INTO task_items (task_id, title, is_done, position) VALUES (?, ?, ?, ?)`,
+			taskID, item.Title, item.IsDone, i,
+		)
 		if err != nil {
-			return nil, err
+			return err
 		}
+	}
+	return nil
+}
 
-		// Count tasks ASSIGNED to this date that are still pending
-		err = db.QueryRow(
-			`SELECT COUNT(*) FROM tasks WHERE assigned_date = ? AND is_completed = FALSE`,
-			date,
-		).Scan(&summary.PendingCount)
+// ImportTodoTxt applies a todo.txt file in one transaction, journaled as
+// one operation that undo reverses as a whole
+func (s *SQLiteStore) ImportTodoTxt(lines []TodoTxtTask, today, actor string) (*TodoTxtResult, error) {
+	var result *TodoTxtResult
+	err := s.withTx(func(tx *sql.Tx) error {
+		categories, _, err := importCategoriesTx(tx)
 		if err != nil {
-			return nil, err
+			return err
 		}
-
-		// Only include dates that have some activity
-		if summary.CompletedCount > 0 || summary.PendingCount > 0 {
-			summaries = append(summaries, summary)
+		tags, err := tagsTx(tx)
+		if err != nil {
+			return err
+		}
+		var ids []int64
+		for _, line := range lines {
+			if line.ID != nil {
+				ids = append(ids, *line.ID)
+			}
+		}
+		stored, err := todoTxtTasksTx(tx, ids)
+		if err != nil {
+			return err
 		}
-	}
-
-	return summaries, nil
-}
 
-// UpdateTaskCompletion marks a task as completed or not completed
-func UpdateTaskCompletion(id int64, isCompleted bool) (*Task, error) {
-	var completedDate interface{}
-	if isCompleted {
-		completedDate = GetTodayIST()
-	} else {
-		completedDate = nil
-	}
+		plan, err := planTodoTxt(lines, categories, tags, stored, today)
+		if err != nil {
+			return err
+		}
+		before, err := readJournalStateTx(tx, plan.taskIDs(), nil)
+		if err != nil {
+			return err
+		}
 
-	_, err := db.Exec(
-		`UPDATE tasks SET is_completed = ?, completed_date = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
-		isCompleted, completedDate, id,
-	)
+		apply := &sqliteTodoTxt{tx: tx, actor: actor}
+		if result, err = applyTodoTxt(apply, plan); err != nil {
+			return err
+		}
+		return journalTx(tx, OpTodoTxt, operationSummary(OpTodoTxt, "", result.Created+result.Updated), actor, before.merge(apply.created))
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return GetTaskByID(id)
+	return result, nil
 }
 
-// RolloverTasks moves incomplete tasks from one date to another
-func RolloverTasks(fromDate, toDate string) (int, error) {
-	result, err := db.Exec(
-		`UPDATE tasks SET assigned_date = ?, updated_at = CURRENT_TIMESTAMP WHERE assigned_date = ? AND is_completed = FALSE`,
-		toDate, fromDate,
-	)
-	if err != nil {
-		return 0, err
-	}
-
-	affected, _ := result.RowsAffected()
-	return int(affected), nil
+// sqliteTodoTxt applies a todo.txt import inside tx, keeping the state that
+// undoing what it creates puts back
+type sqliteTodoTxt struct {
+	tx      *sql.Tx
+	actor   string
+	created journalState
 }
 
-// RolloverAllPendingTasks moves ALL incomplete tasks from any past date to today
-func RolloverAllPendingTasks(toDate string) (int, error) {
-	result, err := db.Exec(
-		`UPDATE tasks SET assigned_date = ?, updated_at = CURRENT_TIMESTAMP WHERE assigned_date < ? AND is_completed = FALSE`,
-		toDate, toDate,
+func (a *sqliteTodoTxt) createCategory(req CategoryRequest) (*Category, error) {
+	result, err := a.tx.Exec(
+		`INSERT INTO categories (name, color, rollover_policy, archive_after_days, stale_after_days, critical_after_days) VALUES (?, ?, ?, ?, ?, ?)`,
+		req.Name, req.Color, req.RolloverPolicy, req.ArchiveAfterDays, req.StaleAfterDays, req.CriticalAfterDays,
 	)
+	if isUniqueViolation(err) {
+		return nil, ErrDuplicate
+	}
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	affected, _ := result.RowsAffected()
-	return int(affected), nil
+	id, _ := result.LastInsertId()
+	a.created.Categories = append(a.created.Categories, categoryState{ID: id, Deleted: true})
+	return scanCategory(a.tx.QueryRow(`SELECT `+categoryColumns+` FROM categories c WHERE c.id = ?`, id))
 }
 
-// DeleteTask deletes a task by ID
-func DeleteTask(id int64) error {
-	_, err := db.Exec(`DELETE FROM tasks WHERE id = ?`, id)
-	return err
+func (a *sqliteTodoTxt) applyBulkOperation(op BulkOperation, taskID int64) error {
+	return applyBulkOperationTx(a.tx, op, taskID, a.actor)
 }
 
-// UpdateTask updates a task's title and description
-func UpdateTask(id int64, title, description string) (*Task, error) {
-	_, err := db.Exec(
-		`UPDATE tasks SET title = ?, description = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
-		title, description, id,
-	)
+func (a *sqliteTodoTxt) addTaskTag(taskID int64, name string) error {
+	tagID, err := tagIDTx(a.tx, name)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	return addTaskTagTx(a.tx, taskID, tagID, name, a.actor)
+}
 
-	return GetTaskByID(id)
+func (a *sqliteTodoTxt) removeTaskTag(taskID int64, tag Tag) error {
+	return removeTaskTagTx(a.tx, taskID, tag.ID, tag.Name, a.actor)
 }
 
-// UpdateTaskCategory updates a task's category
-func UpdateTaskCategory(id int64, categoryID *int64) (*Task, error) {
-	_, err := db.Exec(
-		`UPDATE tasks SET category_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
-		categoryID, id,
-	)
+func (a *sqliteTodoTxt) importDocument(doc ImportDocument) (*ImportReport, error) {
+	report, created, err := importTx(a.tx, doc, ImportOptions{Categories: ImportMerge}, a.actor)
 	if err != nil {
 		return nil, err
 	}
-
-	return GetTaskByID(id)
+	a.created = a.created.merge(created)
+	return report, nil
 }
 
-// GetTasksByCategory retrieves all incomplete tasks for a specific category
-func GetTasksByCategory(categoryID int64) ([]Task, error) {
-	rows, err := db.Query(
-		`SELECT id, title, description, created_date, assigned_date, completed_date, is_completed, category_id, created_at, updated_at 
-		 FROM tasks WHERE category_id = ? AND is_completed = FALSE
-		 ORDER BY assigned_date ASC, created_at ASC`,
-		categoryID,
-	)
+// tagsTx loads every tag inside tx
+func tagsTx(tx *sql.Tx) ([]Tag, error) {
+	rows, err := tx.Query(`SELECT id, name, color, created_at FROM tags ORDER BY name COLLATE NOCASE ASC`)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var tasks []Task
+	var tags []Tag
 	for rows.Next() {
-		var task Task
-		var completedDate sql.NullString
-		var catID sql.NullInt64
-		var createdAt, updatedAt string
-
-		err := rows.Scan(&task.ID, &task.Title, &task.Description, &task.CreatedDate, &task.AssignedDate, &completedDate, &task.IsCompleted, &catID, &createdAt, &updatedAt)
-		if err != nil {
+		var tag Tag
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.Color, &tag.CreatedAt); err != nil {
 			return nil, err
 		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
 
-		if completedDate.Valid {
-			task.CompletedDate = &completedDate.String
+// todoTxtTasksTx loads the live tasks with the given IDs and their tags
+// inside tx, keyed by ID
+func todoTxtTasksTx(tx *sql.Tx, ids []int64) (map[int64]*Task, error) {
+	tasks := make(map[int64]*Task, len(ids))
+	err := eachIDChunk(sortedIDs(ids), func(chunk []int64) error {
+		placeholders, args := inClause(chunk)
+		rows, err := tx.Query(taskColumns+` WHERE t.id IN (`+placeholders+`) AND t.deleted_at IS NULL`, args...)
+		if err != nil {
+			return err
 		}
-
-		if catID.Valid {
-			task.CategoryID = &catID.Int64
-			task.Category, _ = GetCategoryByID(catID.Int64)
+		defer rows.Close()
+
+		for rows.Next() {
+			task, err := scanTask(rows)
+			if err != nil {
+				return err
+			}
+			tasks[task.ID] = task
 		}
-
-		task.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
-		task.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAt)
-		task.DragDays = CalculateBusinessDays(task.CreatedDate, task.AssignedDate)
-
-		tasks = append(tasks, task)
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	tags, err := taskTags(tx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for id, task := range tasks {
+		task.Tags = tags[id]
+	}
 	return tasks, nil
 }
 
-// CalculateBusinessDays calculates the number of business days between two dates
-func CalculateBusinessDays(startDate, endDate string) int {
-	start, err := time.Parse("2006-01-02", startDate)
-	if err != nil {
-		return 0
+// tagIDTx looks a tag up by name inside tx, ignoring case, and creates it
+// when there is none
+func tagIDTx(tx *sql.Tx, name string) (int64, error) {
+	var id int64
+	err := tx.QueryRow(`SELECT id FROM tags WHERE name = ?`, name).Scan(&id)
+	if err != sql.ErrNoRows {
+		return id, err
 	}
 
-	end, err := time.Parse("2006-01-02", endDate)
+	result, err := tx.Exec(`INSERT INTO tags (name) VALUES (?)`, name)
 	if err != nil {
-		return 0
+		return 0, err
 	}
+	return result.LastInsertId()
+}
 
-	if start.Equal(end) || start.After(end) {
-		return 0
+// importCategoriesTx loads the live and trashed categories an import is
+// matched against
+func importCategoriesTx(tx *sql.Tx) (live, trashed []Category, err error) {
+	rows, err := tx.Query(`SELECT ` + categoryColumns + `, c.deleted_at IS NOT NULL FROM categories c ORDER BY c.id ASC`)
+	if err != nil {
+		return nil, nil, err
 	}
+	defer rows.Close()
 
-	businessDays := 0
-	current := start
-
-	for current.Before(end) {
-		current = current.AddDate(0, 0, 1)
-		// Skip weekends (Saturday = 6, Sunday = 0)
-		if current.Weekday() != time.Saturday && current.Weekday() != time.Sunday {
-			businessDays++
+	for rows.Next() {
+		var deleted bool
+		cat, err := scanCategory(rows, &deleted)
+		if err != nil {
+			return nil, nil, err
+		}
+		if deleted {
+			trashed = append(trashed, *cat)
+		} else {
+			live = append(live, *cat)
 		}
 	}
-
-	return businessDays
+	return live, trashed, rows.Err()
 }
 
-// GetCompletedTasksForDate retrieves tasks that were completed on a specific date
-func GetCompletedTasksForDate(date string) ([]Task, error) {
-	rows, err := db.Query(
-		`SELECT id, title, description, created_date, assigned_date, completed_date, is_completed, category_id, created_at, updated_at 
-		 FROM tasks WHERE completed_date = ? AND is_completed = TRUE
-		 ORDER BY created_at ASC`,
-		date,
-	)
+// importTaskKeysTx loads the keys of the stored tasks an import skips
+func importTaskKeysTx(tx *sql.Tx) (map[importTaskKey]bool, error) {
+	rows, err := tx.Query(`SELECT title, created_date, category_id FROM tasks WHERE deleted_at IS NULL`)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var tasks []Task
+	keys := make(map[importTaskKey]bool)
 	for rows.Next() {
-		var task Task
-		var completedDate sql.NullString
+		var key importTaskKey
 		var categoryID sql.NullInt64
-		var createdAt, updatedAt string
-
-		err := rows.Scan(&task.ID, &task.Title, &task.Description, &task.CreatedDate, &task.AssignedDate, &completedDate, &task.IsCompleted, &categoryID, &createdAt, &updatedAt)
-		if err != nil {
+		if err := rows.Scan(&key.Title, &key.CreatedDate, &categoryID); err != nil {
 			return nil, err
 		}
-
-		if completedDate.Valid {
-			task.CompletedDate = &completedDate.String
-		}
-
-		if categoryID.Valid {
-			task.CategoryID = &categoryID.Int64
-			task.Category, _ = GetCategoryByID(categoryID.Int64)
-		}
-
-		task.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
-		task.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAt)
-		task.DragDays = CalculateBusinessDays(task.CreatedDate, task.AssignedDate)
-
-		tasks = append(tasks, task)
+		key.CategoryID = categoryID.Int64
+		keys[key] = true
 	}
-
-	return tasks, nil
+	return keys, rows.Err()
 }
 
-// GetHistoricalLog retrieves the log of what was accomplished on a specific date
-func GetHistoricalLog(date string) (*DailyLog, error) {
-	// Get tasks that were completed on this date
-	completedTasks, err := GetCompletedTasksForDate(date)
+// queryTasks runs a taskColumns query and attaches the tasks' checklist
+// progress and tags
+func (s *SQLiteStore) queryTasks(query string, args ...interface{}) ([]Task, error) {
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
-
-	// Get tasks that were assigned to this date but not completed (they would have been rolled over)
-	rows, err := db.Query(
-		`SELECT id, title, description, created_date, assigned_date, completed_date, is_completed, created_at, updated_at 
-		 FROM tasks WHERE created_date <= ? AND (
-			 (completed_date = ?) OR 
-			 (assigned_date > ? AND created_date <= ?)
-		 )
-		 ORDER BY is_completed DESC, created_at ASC`,
-		date, date, date, date,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("error querying historical tasks: %v", err)
-	}
 	defer rows.Close()
 
-	log := &DailyLog{
-		Date:  date,
-		Tasks: completedTasks,
+	var tasks []Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, *task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.attachRelated(tasks)
+}
+
+// GetTasksByDate retrieves all tasks for a specific date
+func (s *SQLiteStore) GetTasksByDate(date string) ([]Task, error) {
+	return s.queryTasks(
+		taskColumns+` WHERE t.deleted_at IS NULL AND (t.assigned_date = ? OR (t.completed_date = ? AND t.is_completed = TRUE))
+		 ORDER BY t.is_completed ASC, t.priority IS NULL, t.priority ASC, t.created_at ASC`,
+		date, date,
+	)
+}
+
+// GetAllDates retrieves all unique dates that have tasks
+func (s *SQLiteStore) GetAllDates() ([]string, error) {
+	rows, err := s.db.Query(
+		`SELECT DISTINCT date FROM (
+			SELECT assigned_date as date FROM tasks WHERE deleted_at IS NULL
+			UNION
+			SELECT completed_date as date FROM tasks WHERE completed_date IS NOT NULL AND deleted_at IS NULL
+		) ORDER BY date DESC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dates []string
+	for rows.Next() {
+		var date string
+		if err := rows.Scan(&date); err != nil {
+			return nil, err
+		}
+		dates = append(dates, date)
+	}
+
+	return dates, nil
+}
+
+// GetHistorySummaries retrieves completion stats for every date with activity
+// in one grouped query: tasks completed on the date and tasks still pending
+// on it
+func (s *SQLiteStore) GetHistorySummaries() ([]HistorySummary, error) {
+	rows, err := s.db.Query(
+		`SELECT date, SUM(completed), SUM(pending) FROM (
+			SELECT completed_date AS date, 1 AS completed, 0 AS pending FROM tasks
+			WHERE is_completed = TRUE AND completed_date IS NOT NULL AND deleted_at IS NULL
+			UNION ALL
+			SELECT assigned_date, 0, 1 FROM tasks WHERE is_completed = FALSE AND deleted_at IS NULL
+		) GROUP BY date ORDER BY date DESC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []HistorySummary
+	for rows.Next() {
+		var summary HistorySummary
+		if err := rows.Scan(&summary.Date, &summary.CompletedCount, &summary.PendingCount); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, rows.Err()
+}
+
+// UpdateTaskCompletion marks a task as completed on completedDate or not completed
+func (s *SQLiteStore) UpdateTaskCompletion(id int64, isCompleted bool, completedDate, actor string) (*Task, error) {
+	if isCompleted && completedDate == "" {
+		completedDate = GetToday()
+	}
+
+	kind := OpCompleteTask
+	if !isCompleted {
+		kind = OpUncompleteTask
+	}
+	err := s.journaledTaskTx(id, kind, actor, func(tx *sql.Tx) error {
+		return updateTaskCompletionTx(tx, id, isCompleted, completedDate, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetTaskByID(id)
+}
+
+// updateTaskCompletionTx is UpdateTaskCompletion inside tx, with completedDate
+// already defaulted
+func updateTaskCompletionTx(tx *sql.Tx, id int64, isCompleted bool, completedDate, actor string) error {
+	var completed interface{}
+	if isCompleted {
+		completed = completedDate
+	}
+
+	var wasCompleted bool
+	var oldDate sql.NullString
+	err := tx.QueryRow(`SELECT is_completed, completed_date FROM tasks WHERE id = ? AND deleted_at IS NULL`, id).Scan(&wasCompleted, &oldDate)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`UPDATE tasks SET is_completed = ?, completed_date = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		isCompleted, completed, id,
+	)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case isCompleted && (!wasCompleted || oldDate.String != completedDate):
+		return recordTaskEvent(tx, id, EventCompleted, nullStringPtr(oldDate), &completedDate, actor)
+	case !isCompleted && wasCompleted:
+		return recordTaskEvent(tx, id, EventUncompleted, nullStringPtr(oldDate), nil, actor)
+	}
+	return nil
+}
+
+// RolloverTasks moves incomplete tasks from one date to another
+func (s *SQLiteStore) RolloverTasks(fromDate, toDate string, opts RolloverOptions, actor string) ([]RolloverMove, error) {
+	return s.rollover(`tasks.assigned_date = ?`, fromDate, toDate, opts, actor)
+}
+
+// RolloverAllPendingTasks moves ALL incomplete tasks from any past date to today
+func (s *SQLiteStore) RolloverAllPendingTasks(toDate string, opts RolloverOptions, actor string) ([]RolloverMove, error) {
+	return s.rollover(`tasks.assigned_date < ?`, toDate, toDate, opts, actor)
+}
+
+// rollableCondition leaves out occurrences of recurring tasks whose missed
+// policy is to skip rather than roll
+const rollableCondition = `NOT EXISTS (
+	SELECT 1 FROM recurring_tasks r WHERE r.id = tasks.recurring_id AND r.missed_policy = 'skip'
+)`
+
+// rollover reassigns the incomplete tasks matching where (with one date
+// argument) that opts selects, rolling each over according to its category's
+// policy and recording a rolled_over event for every task moved. Tasks whose
+// policy archives them go to the trash. A dry run only lists the moves.
+func (s *SQLiteStore) rollover(where, whereDate, toDate string, opts RolloverOptions, actor string) ([]RolloverMove, error) {
+	var moves []RolloverMove
+	err := s.withTx(func(tx *sql.Tx) error {
+		var err error
+		moves, err = rolloverMovesTx(tx, where, whereDate, toDate, opts)
+		if err != nil || opts.DryRun || len(moves) == 0 {
+			return err
+		}
+
+		ids := make([]int64, len(moves))
+		for i, m := range moves {
+			ids[i] = m.TaskID
+		}
+		before, err := readJournalStateTx(tx, ids, nil)
+		if err != nil {
+			return err
+		}
+
+		// Policies can send tasks to different dates, so move them a date at a time
+		var dates []string
+		byDate := map[string][]int64{}
+		for _, m := range moves {
+			if m.Action == RolloverArchived {
+				if err := deleteTaskTx(tx, m.TaskID, actor); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, ok := byDate[m.ToDate]; !ok {
+				dates = append(dates, m.ToDate)
+			}
+			byDate[m.ToDate] = append(byDate[m.ToDate], m.TaskID)
+		}
+
+		for _, date := range dates {
+			err := eachIDChunk(byDate[date], func(chunk []int64) error {
+				placeholders, args := inClause(chunk)
+				_, err := tx.Exec(
+					`INSERT INTO task_events (task_id, event_type, old_value, new_value, actor, created_at)
+					 SELECT id, ?, assigned_date, ?, ?, ? FROM tasks WHERE id IN (`+placeholders+`)`,
+					append([]interface{}{EventRolledOver, date, actor, eventTimestamp()}, args...)...,
+				)
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.Exec(
+					`UPDATE tasks SET assigned_date = ?, updated_at = CURRENT_TIMESTAMP WHERE id IN (`+placeholders+`)`,
+					append([]interface{}{date}, args...)...,
+				)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return journalTx(tx, OpRollover, operationSummary(OpRollover, toDate, len(moves)), actor, before)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return moves, nil
+}
+
+// rolloverMovesTx lists the moves a rollover would make, oldest date first.
+// Tasks without a category, or whose category is in the trash, roll to the
+// next day.
+func rolloverMovesTx(tx *sql.Tx, where, whereDate, toDate string, opts RolloverOptions) ([]RolloverMove, error) {
+	rows, err := tx.Query(
+		`SELECT tasks.id, tasks.title, tasks.created_date, tasks.assigned_date, c.id, c.rollover_policy, c.archive_after_days FROM tasks
+		 LEFT JOIN categories c ON c.id = tasks.category_id AND c.deleted_at IS NULL
+		 WHERE `+where+` AND tasks.is_completed = FALSE AND tasks.deleted_at IS NULL AND `+rollableCondition+`
+		 ORDER BY tasks.assigned_date ASC, tasks.id ASC`,
+		whereDate,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var moves []RolloverMove
+	for rows.Next() {
+		var m RolloverMove
+		var createdDate string
+		var policy sql.NullString
+		var categoryID, archiveAfterDays sql.NullInt64
+		if err := rows.Scan(&m.TaskID, &m.Title, &createdDate, &m.FromDate, &categoryID, &policy, &archiveAfterDays); err != nil {
+			return nil, err
+		}
+		m.CategoryID = nullInt64Ptr(categoryID)
+		if !opts.selects(m.TaskID, m.CategoryID) {
+			continue
+		}
+		m.ToDate = toDate
+		if planRolloverMove(&m, createdDate, policy.String, nullIntPtr(archiveAfterDays)) {
+			moves = append(moves, m)
+		}
+	}
+
+	return moves, rows.Err()
+}
+
+// DeleteTask moves a task to the trash
+func (s *SQLiteStore) DeleteTask(id int64, actor string) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		var title string
+		err := tx.QueryRow(`SELECT title FROM tasks WHERE id = ? AND deleted_at IS NULL`, id).Scan(&title)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		before, err := readJournalStateTx(tx, []int64{id}, nil)
+		if err != nil {
+			return err
+		}
+		if err := deleteTaskTx(tx, id, actor); err != nil {
+			return err
+		}
+		return journalTx(tx, OpDeleteTask, operationSummary(OpDeleteTask, title, 1), actor, before)
+	})
+}
+
+// deleteTaskTx moves a task to the trash inside tx. Its checklist and tags
+// stay with it so a restore brings them back.
+func deleteTaskTx(tx *sql.Tx, id int64, actor string) error {
+	var assignedDate, title string
+	err := tx.QueryRow(`SELECT assigned_date, title FROM tasks WHERE id = ? AND deleted_at IS NULL`, id).Scan(&assignedDate, &title)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE tasks SET deleted_at = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, eventTimestamp(), id)
+	if err != nil {
+		return err
+	}
+
+	return recordTaskEvent(tx, id, EventDeleted, &assignedDate, &title, actor)
+}
+
+// UpdateTask updates a task's title and description, and its priority and
+// due date when they are set in req (an empty string clears them)
+func (s *SQLiteStore) UpdateTask(id int64, req TaskRequest, actor string) (*Task, error) {
+	err := s.journaledTaskTx(id, OpUpdateTask, actor, func(tx *sql.Tx) error {
+		return updateTaskTx(tx, id, req, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetTaskByID(id)
+}
+
+// updateTaskTx is UpdateTask inside tx
+func updateTaskTx(tx *sql.Tx, id int64, req TaskRequest, actor string) error {
+	var oldTitle, oldDescription string
+	var oldPriority, oldDueDate sql.NullString
+	err := tx.QueryRow(`SELECT title, description, priority, due_date FROM tasks WHERE id = ? AND deleted_at IS NULL`, id).
+		Scan(&oldTitle, &oldDescription, &oldPriority, &oldDueDate)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	priority := nullStringPtr(oldPriority)
+	if req.Priority != nil {
+		priority = emptyToNil(req.Priority)
+	}
+	dueDate := nullStringPtr(oldDueDate)
+	if req.DueDate != nil {
+		dueDate = emptyToNil(req.DueDate)
+	}
+
+	_, err = tx.Exec(
+		`UPDATE tasks SET title = ?, description = ?, priority = ?, due_date = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		req.Title, req.Description, priority, dueDate, id,
+	)
+	if err != nil {
+		return err
+	}
+
+	if req.Title != oldTitle {
+		if err := recordTaskEvent(tx, id, EventTitleChanged, &oldTitle, &req.Title, actor); err != nil {
+			return err
+		}
+	}
+	if req.Description != oldDescription {
+		if err := recordTaskEvent(tx, id, EventDescriptionChanged, &oldDescription, &req.Description, actor); err != nil {
+			return err
+		}
+	}
+	if !sameStringPtr(nullStringPtr(oldPriority), priority) {
+		if err := recordTaskEvent(tx, id, EventPriorityChanged, nullStringPtr(oldPriority), priority, actor); err != nil {
+			return err
+		}
+	}
+	if !sameStringPtr(nullStringPtr(oldDueDate), dueDate) {
+		return recordTaskEvent(tx, id, EventDueDateChanged, nullStringPtr(oldDueDate), dueDate, actor)
+	}
+	return nil
+}
+
+// UpdateTaskCategory updates a task's category
+func (s *SQLiteStore) UpdateTaskCategory(id int64, categoryID *int64, actor string) (*Task, error) {
+	err := s.journaledTaskTx(id, OpSetCategory, actor, func(tx *sql.Tx) error {
+		return updateTaskCategoryTx(tx, id, categoryID, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetTaskByID(id)
+}
+
+// updateTaskCategoryTx is UpdateTaskCategory inside tx
+func updateTaskCategoryTx(tx *sql.Tx, id int64, categoryID *int64, actor string) error {
+	var oldCategoryID sql.NullInt64
+	err := tx.QueryRow(`SELECT category_id FROM tasks WHERE id = ? AND deleted_at IS NULL`, id).Scan(&oldCategoryID)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`UPDATE tasks SET category_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		categoryID, id,
+	)
+	if err != nil {
+		return err
+	}
+
+	oldValue := nullInt64Ptr(oldCategoryID)
+	if !sameInt64Ptr(oldValue, categoryID) {
+		return recordTaskEvent(tx, id, EventCategoryChanged, formatIDPtr(oldValue), formatIDPtr(categoryID), actor)
+	}
+	return nil
+}
+
+// BulkUpdateTasks applies ops to their tasks in order inside one
+// transaction. Each item runs in a savepoint, so a failing item is undone on
+// its own; with allOrNothing any failure rolls back the whole batch and fails
+// every item.
+func (s *SQLiteStore) BulkUpdateTasks(ops []BulkOperation, allOrNothing bool, actor string) ([]BulkResult, error) {
+	var results []BulkResult
+	failed := false
+
+	err := s.withTx(func(tx *sql.Tx) error {
+		var ids []int64
+		for _, op := range ops {
+			ids = append(ids, op.TaskIDs...)
+		}
+		before, err := readJournalStateTx(tx, ids, nil)
+		if err != nil {
+			return err
+		}
+
+		for i, op := range ops {
+			for _, id := range op.TaskIDs {
+				result := BulkResult{Operation: i, Op: op.Op, TaskID: id}
+
+				if _, err := tx.Exec(`SAVEPOINT bulk_item`); err != nil {
+					return err
+				}
+				if err := applyBulkOperationTx(tx, op, id, actor); err != nil {
+					if _, err := tx.Exec(`ROLLBACK TO bulk_item`); err != nil {
+						return err
+					}
+					result.Error = bulkErrorMessage(err)
+					failed = true
+				} else {
+					result.OK = true
+				}
+				if _, err := tx.Exec(`RELEASE bulk_item`); err != nil {
+					return err
+				}
+
+				results = append(results, result)
+			}
+		}
+
+		if allOrNothing && failed {
+			return errBulkRolledBack
+		}
+
+		succeeded := 0
+		for _, r := range results {
+			if r.OK {
+				succeeded++
+			}
+		}
+		return journalTx(tx, OpBulk, operationSummary(OpBulk, "", succeeded), actor, before)
+	})
+	if err == errBulkRolledBack {
+		return rollBackBulkResults(results), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int64
+	for _, r := range results {
+		ids = append(ids, r.TaskID)
+	}
+	tasks, err := s.GetTasksByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return attachBulkTasks(results, tasks), nil
+}
+
+// applyBulkOperationTx applies one bulk operation to task id inside tx
+func applyBulkOperationTx(tx *sql.Tx, op BulkOperation, id int64, actor string) error {
+	switch op.Op {
+	case BulkComplete:
+		return updateTaskCompletionTx(tx, id, true, op.Date, actor)
+	case BulkUncomplete:
+		return updateTaskCompletionTx(tx, id, false, "", actor)
+	case BulkDelete:
+		return deleteTaskTx(tx, id, actor)
+	case BulkSetCategory:
+		return updateTaskCategoryTx(tx, id, op.CategoryID, actor)
+	case BulkMoveToDate:
+		return moveTaskTx(tx, id, op.Date, actor)
+	case BulkUpdate:
+		var title, description string
+		err := tx.QueryRow(`SELECT title, description FROM tasks WHERE id = ? AND deleted_at IS NULL`, id).Scan(&title, &description)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return updateTaskTx(tx, id, op.Fields.taskRequest(title, description), actor)
+	}
+	return fmt.Errorf("unknown operation %q", op.Op)
+}
+
+// moveTaskTx reassigns a task to date inside tx, recording a moved event
+func moveTaskTx(tx *sql.Tx, id int64, date, actor string) error {
+	var assignedDate string
+	err := tx.QueryRow(`SELECT assigned_date FROM tasks WHERE id = ? AND deleted_at IS NULL`, id).Scan(&assignedDate)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil || assignedDate == date {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE tasks SET assigned_date = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, date, id)
+	if err != nil {
+		return err
+	}
+
+	return recordTaskEvent(tx, id, EventMoved, &assignedDate, &date, actor)
+}
+
+// GetOverdueTasks retrieves incomplete tasks whose due date is before today,
+// most overdue first
+func (s *SQLiteStore) GetOverdueTasks(today string) ([]Task, error) {
+	return s.queryTasks(
+		taskColumns+` WHERE t.due_date < ? AND t.is_completed = FALSE AND t.deleted_at IS NULL
+		 ORDER BY t.due_date ASC, t.priority IS NULL, t.priority ASC, t.created_at ASC`,
+		today,
+	)
+}
+
+// GetTasksByCategory retrieves all incomplete tasks for a specific category
+func (s *SQLiteStore) GetTasksByCategory(categoryID int64) ([]Task, error) {
+	return s.queryTasks(
+		taskColumns+` WHERE c.id = ? AND t.is_completed = FALSE AND t.deleted_at IS NULL
+		 ORDER BY t.assigned_date ASC, t.created_at ASC`,
+		categoryID,
+	)
+}
+
+// Checklist item operations
+
+// taskProgress counts done and total checklist items for each task
+func (s *SQLiteStore) taskProgress(ids []int64) (map[int64]Progress, error) {
+	progress := make(map[int64]Progress, len(ids))
+	err := eachIDChunk(ids, func(chunk []int64) error {
+		placeholders, args := inClause(chunk)
+		rows, err := s.db.Query(
+			`SELECT task_id, COALESCE(SUM(CASE WHEN is_done THEN 1 ELSE 0 END), 0), COUNT(*)
+			 FROM task_items WHERE task_id IN (`+placeholders+`) GROUP BY task_id`,
+			args...,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var taskID int64
+			var p Progress
+			if err := rows.Scan(&taskID, &p.Done, &p.Total); err != nil {
+				return err
+			}
+			progress[taskID] = p
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return progress, nil
+}
+
+// attachRelated fills in the checklist progress and tags of each task
+func (s *SQLiteStore) attachRelated(tasks []Task) ([]Task, error) {
+	ids := make([]int64, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+
+	progress, err := s.taskProgress(ids)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := taskTags(s.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range tasks {
+		tasks[i].Progress = progress[tasks[i].ID]
+		tasks[i].Tags = tags[tasks[i].ID]
+		if tasks[i].Tags == nil {
+			tasks[i].Tags = []Tag{}
+		}
+	}
+
+	return tasks, nil
+}
+
+// CreateTaskItem appends a checklist item to a task
+func (s *SQLiteStore) CreateTaskItem(taskID int64, title, actor string) (*TaskItem, error) {
+	var id int64
+	err := s.withTx(func(tx *sql.Tx) error {
+		var count int
+		err := tx.QueryRow(`SELECT COUNT(*) FROM tasks WHERE id = ? AND deleted_at IS NULL`, taskID).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrNotFound
+		}
+
+		result, err := tx.Exec(
+			`INSERT INTO task_items (task_id, title, position)
+			 VALUES (?, ?, (SELECT COUNT(*) FROM task_items WHERE task_id = ?))`,
+			taskID, title, taskID,
+		)
+		if err != nil {
+			return err
+		}
+
+		id, _ = result.LastInsertId()
+		return recordTaskEvent(tx, taskID, EventItemAdded, nil, &title, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getTaskItem(taskID, id)
+}
+
+// GetTaskItems retrieves a task's checklist in order
+func (s *SQLiteStore) GetTaskItems(taskID int64) ([]TaskItem, error) {
+	rows, err := s.db.Query(
+		`SELECT id, task_id, title, is_done, position, created_at, updated_at
+		 FROM task_items WHERE task_id = ? ORDER BY position ASC, id ASC`,
+		taskID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []TaskItem
+	for rows.Next() {
+		var item TaskItem
+		err := rows.Scan(&item.ID, &item.TaskID, &item.Title, &item.IsDone, &item.Position, &item.CreatedAt, &item.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// GetTaskChecklists retrieves the checklists of several tasks keyed by task ID
+func (s *SQLiteStore) GetTaskChecklists(taskIDs []int64) (map[int64][]TaskItem, error) {
+	checklists := make(map[int64][]TaskItem)
+	err := eachIDChunk(taskIDs, func(chunk []int64) error {
+		placeholders, args := inClause(chunk)
+		rows, err := s.db.Query(
+			`SELECT id, task_id, title, is_done, position, created_at, updated_at
+			 FROM task_items WHERE task_id IN (`+placeholders+`) ORDER BY task_id ASC, position ASC, id ASC`,
+			args...,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var item TaskItem
+			err := rows.Scan(&item.ID, &item.TaskID, &item.Title, &item.IsDone, &item.Position, &item.CreatedAt, &item.UpdatedAt)
+			if err != nil {
+				return err
+			}
+			checklists[item.TaskID] = append(checklists[item.TaskID], item)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return checklists, nil
+}
+
+func (s *SQLiteStore) getTaskItem(taskID, itemID int64) (*TaskItem, error) {
+	item := &TaskItem{}
+	err := s.db.QueryRow(
+		`SELECT id, task_id, title, is_done, position, created_at, updated_at
+		 FROM task_items WHERE id = ? AND task_id = ?`,
+		itemID, taskID,
+	).Scan(&item.ID, &item.TaskID, &item.Title, &item.IsDone, &item.Position, &item.CreatedAt, &item.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// UpdateTaskItem renames, checks off or moves a checklist item. Moving an item
+// shifts its siblings so positions stay contiguous.
+func (s *SQLiteStore) UpdateTaskItem(taskID, itemID int64, req TaskItemRequest, actor string) (*TaskItem, error) {
+	err := s.withTx(func(tx *sql.Tx) error {
+		var title string
+		var isDone bool
+		var position, count int
+		err := tx.QueryRow(`SELECT title, is_done, position FROM task_items
+			WHERE id = ? AND task_id = ? AND task_id IN (SELECT id FROM tasks WHERE deleted_at IS NULL)`, itemID, taskID).
+			Scan(&title, &isDone, &position)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM task_items WHERE task_id = ?`, taskID).Scan(&count); err != nil {
+			return err
+		}
+
+		if req.Title != nil && *req.Title != title {
+			if err := recordTaskEvent(tx, taskID, EventItemRenamed, &title, req.Title, actor); err != nil {
+				return err
+			}
+			title = *req.Title
+		}
+
+		if req.IsDone != nil && *req.IsDone != isDone {
+			eventType := EventItemChecked
+			if !*req.IsDone {
+				eventType = EventItemUnchecked
+			}
+			if err := recordTaskEvent(tx, taskID, eventType, nil, &title, actor); err != nil {
+				return err
+			}
+			isDone = *req.IsDone
+		}
+
+		if req.Position != nil {
+			target := clampPosition(*req.Position, count)
+			switch {
+			case target < position:
+				_, err = tx.Exec(
+					`UPDATE task_items SET position = position + 1 WHERE task_id = ? AND position >= ? AND position < ?`,
+					taskID, target, position,
+				)
+			case target > position:
+				_, err = tx.Exec(
+					`UPDATE task_items SET position = position - 1 WHERE task_id = ? AND position > ? AND position <= ?`,
+					taskID, position, target,
+				)
+			}
+			if err != nil {
+				return err
+			}
+			position = target
+		}
+
+		_, err = tx.Exec(
+			`UPDATE task_items SET title = ?, is_done = ?, position = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+			title, isDone, position, itemID,
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getTaskItem(taskID, itemID)
+}
+
+// DeleteTaskItem removes a checklist item and closes the gap it leaves
+func (s *SQLiteStore) DeleteTaskItem(taskID, itemID int64, actor string) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		var title string
+		var position int
+		err := tx.QueryRow(`SELECT title, position FROM task_items
+			WHERE id = ? AND task_id = ? AND task_id IN (SELECT id FROM tasks WHERE deleted_at IS NULL)`, itemID, taskID).
+			Scan(&title, &position)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`DELETE FROM task_items WHERE id = ?`, itemID); err != nil {
+			return err
+		}
+		_, err = tx.Exec(
+			`UPDATE task_items SET position = position - 1 WHERE task_id = ? AND position > ?`,
+			taskID, position,
+		)
+		if err != nil {
+			return err
+		}
+
+		return recordTaskEvent(tx, taskID, EventItemRemoved, &title, nil, actor)
+	})
+}
+
+// Recurring task operations
+
+const recurringColumns = `id, title, description, category_id, priority, rule, start_date, end_date, missed_policy, generated_through, created_at, updated_at`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// queryer runs queries against the database or inside a transaction
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// scanRecurringTask reads a recurring_tasks row selected with recurringColumns
+func scanRecurringTask(row rowScanner) (*RecurringTask, error) {
+	rt := &RecurringTask{}
+	var categoryID sql.NullInt64
+	var priority, endDate, generatedThrough sql.NullString
+
+	err := row.Scan(&rt.ID, &rt.Title, &rt.Description, &categoryID, &priority, &rt.Rule, &rt.StartDate,
+		&endDate, &rt.MissedPolicy, &generatedThrough, &rt.CreatedAt, &rt.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	rt.CategoryID = nullInt64Ptr(categoryID)
+	rt.Priority = nullStringPtr(priority)
+	rt.EndDate = nullStringPtr(endDate)
+	rt.GeneratedThrough = nullStringPtr(generatedThrough)
+	return rt, nil
+}
+
+// CreateRecurringTask stores a new recurring task template
+func (s *SQLiteStore) CreateRecurringTask(rt RecurringTask) (*RecurringTask, error) {
+	result, err := s.db.Exec(
+		`INSERT INTO recurring_tasks (title, description, category_id, priority, rule, start_date, end_date, missed_policy, generated_through)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rt.Title, rt.Description, rt.CategoryID, rt.Priority, rt.Rule, rt.StartDate, rt.EndDate, rt.MissedPolicy, rt.GeneratedThrough,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	id, _ := result.LastInsertId()
+	return s.GetRecurringTaskByID(id)
+}
+
+// GetRecurringTaskByID retrieves a recurring task template by ID
+func (s *SQLiteStore) GetRecurringTaskByID(id int64) (*RecurringTask, error) {
+	rt, err := scanRecurringTask(s.db.QueryRow(`SELECT `+recurringColumns+` FROM recurring_tasks WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return rt, err
+}
+
+// GetRecurringTasks retrieves every recurring task template
+func (s *SQLiteStore) GetRecurringTasks() ([]RecurringTask, error) {
+	rows, err := s.db.Query(`SELECT ` + recurringColumns + ` FROM recurring_tasks ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []RecurringTask
+	for rows.Next() {
+		rt, err := scanRecurringTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *rt)
+	}
+
+	return templates, rows.Err()
+}
+
+// UpdateRecurringTask replaces a template's definition. Tasks it already
+// generated are left as they are.
+func (s *SQLiteStore) UpdateRecurringTask(rt RecurringTask) (*RecurringTask, error) {
+	result, err := s.db.Exec(
+		`UPDATE recurring_tasks SET title = ?, description = ?, category_id = ?, priority = ?, rule = ?, start_date = ?,
+		 end_date = ?, missed_policy = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		rt.Title, rt.Description, rt.CategoryID, rt.Priority, rt.Rule, rt.StartDate, rt.EndDate, rt.MissedPolicy, rt.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrNotFound
+	}
+
+	return s.GetRecurringTaskByID(rt.ID)
+}
+
+// DeleteRecurringTask deletes a template. Tasks it generated are kept but no
+// longer linked to it.
+func (s *SQLiteStore) DeleteRecurringTask(id int64) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`UPDATE tasks SET recurring_id = NULL WHERE recurring_id = ?`, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM recurring_occurrences WHERE recurring_id = ?`, id); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM recurring_tasks WHERE id = ?`, id)
+		return err
+	})
+}
+
+// MaterializeOccurrence creates the task for a template's occurrence on date
+// and marks the occurrence handled in one transaction. It returns nil when
+// the occurrence was handled before, or when skip only records it as skipped.
+func (s *SQLiteStore) MaterializeOccurrence(rt RecurringTask, date string, skip bool) (*Task, error) {
+	var id int64
+	err := s.withTx(func(tx *sql.Tx) error {
+		var count int
+		err := tx.QueryRow(
+			`SELECT COUNT(*) FROM recurring_occurrences WHERE recurring_id = ? AND occurrence_date = ?`,
+			rt.ID, date,
+		).Scan(&count)
+		if err != nil || count > 0 {
+			return err
+		}
+
+		var taskID *int64
+		if !skip {
+			if id, err = createTaskTx(tx, occurrenceRequest(rt, date), date, "recurring"); err != nil {
+				return err
+			}
+			taskID = &id
+		}
+		_, err = tx.Exec(
+			`INSERT INTO recurring_occurrences (recurring_id, occurrence_date, task_id) VALUES (?, ?, ?)`,
+			rt.ID, date, taskID,
+		)
+		return err
+	})
+	if err != nil || id == 0 {
+		return nil, err
+	}
+
+	return s.GetTaskByID(id)
+}
+
+// SetRecurringGeneratedThrough records that every occurrence up to date has been handled
+func (s *SQLiteStore) SetRecurringGeneratedThrough(id int64, date string) error {
+	_, err := s.db.Exec(`UPDATE recurring_tasks SET generated_through = ? WHERE id = ?`, date, id)
+	return err
+}
+
+// Tag operations
+
+// taskTags loads the tags of each task, ordered by name, through db or a
+// transaction
+func taskTags(db queryer, ids []int64) (map[int64][]Tag, error) {
+	tags := make(map[int64][]Tag, len(ids))
+	err := eachIDChunk(ids, func(chunk []int64) error {
+		placeholders, args := inClause(chunk)
+		rows, err := db.Query(
+			`SELECT tt.task_id, t.id, t.name, t.color, t.created_at
+			 FROM task_tags tt JOIN tags t ON t.id = tt.tag_id
+			 WHERE tt.task_id IN (`+placeholders+`)
+			 ORDER BY t.name COLLATE NOCASE ASC`,
+			args...,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var taskID int64
+			var tag Tag
+			if err := rows.Scan(&taskID, &tag.ID, &tag.Name, &tag.Color, &tag.CreatedAt); err != nil {
+				return err
+			}
+			tags[taskID] = append(tags[taskID], tag)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// CreateTag creates a new tag; names are unique regardless of case
+func (s *SQLiteStore) CreateTag(name, color string) (*Tag, error) {
+	result, err := s.db.Exec(`INSERT INTO tags (name, color) VALUES (?, ?)`, name, color)
+	if isUniqueViolation(err) {
+		return nil, ErrDuplicate
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	id, _ := result.LastInsertId()
+	return s.GetTagByID(id)
+}
+
+// GetTagByID retrieves a tag by ID
+func (s *SQLiteStore) GetTagByID(id int64) (*Tag, error) {
+	tag := &Tag{}
+	err := s.db.QueryRow(`SELECT id, name, color, created_at FROM tags WHERE id = ?`, id).
+		Scan(&tag.ID, &tag.Name, &tag.Color, &tag.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// GetTagByName retrieves a tag by name, ignoring case
+func (s *SQLiteStore) GetTagByName(name string) (*Tag, error) {
+	tag := &Tag{}
+	err := s.db.QueryRow(`SELECT id, name, color, created_at FROM tags WHERE name = ?`, name).
+		Scan(&tag.ID, &tag.Name, &tag.Color, &tag.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// GetAllTags retrieves all tags with their incomplete task counts
+func (s *SQLiteStore) GetAllTags() ([]Tag, error) {
+	rows, err := s.db.Query(
+		`SELECT t.id, t.name, t.color, t.created_at,
+		 (SELECT COUNT(*) FROM task_tags tt JOIN tasks ON tasks.id = tt.task_id
+		  WHERE tt.tag_id = t.id AND tasks.is_completed = FALSE AND tasks.deleted_at IS NULL) AS task_count
+		 FROM tags t ORDER BY t.name COLLATE NOCASE ASC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []Tag
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.Color, &tag.CreatedAt, &tag.TaskCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// UpdateTag renames or recolors a tag
+func (s *SQLiteStore) UpdateTag(id int64, name, color string) (*Tag, error) {
+	result, err := s.db.Exec(`UPDATE tags SET name = ?, color = ? WHERE id = ?`, name, color, id)
+	if isUniqueViolation(err) {
+		return nil, ErrDuplicate
+	}
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrNotFound
+	}
+
+	return s.GetTagByID(id)
+}
+
+// DeleteTag deletes a tag, removing it from its tasks first so each one gets
+// a tag_removed event
+func (s *SQLiteStore) DeleteTag(id int64, actor string) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(
+			`INSERT INTO task_events (task_id, event_type, old_value, new_value, actor, created_at)
+			 SELECT tt.task_id, ?, t.name, NULL, ?, ? FROM task_tags tt JOIN tags t ON t.id = tt.tag_id WHERE tt.tag_id = ?`,
+			EventTagRemoved, actor, eventTimestamp(), id,
+		)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`DELETE FROM task_tags WHERE tag_id = ?`, id); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`DELETE FROM tags WHERE id = ?`, id)
+		return err
+	})
+}
+
+// AddTaskTag puts a tag on a task; adding a tag the task already has is a no-op
+func (s *SQLiteStore) AddTaskTag(taskID, tagID int64, actor string) (*Task, error) {
+	err := s.withTx(func(tx *sql.Tx) error {
+		var name string
+		err := tx.QueryRow(`SELECT name FROM tags WHERE id = ?`, tagID).Scan(&name)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var count int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM tasks WHERE id = ? AND deleted_at IS NULL`, taskID).Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrNotFound
+		}
+
+		return addTaskTagTx(tx, taskID, tagID, name, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetTaskByID(taskID)
+}
+
+// addTaskTagTx puts the tag named name on a task inside tx, recording a
+// tag_added event unless the task already has it
+func addTaskTagTx(tx *sql.Tx, taskID, tagID int64, name, actor string) error {
+	result, err := tx.Exec(`INSERT OR IGNORE INTO task_tags (task_id, tag_id) VALUES (?, ?)`, taskID, tagID)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := result.RowsAffected(); n > 0 {
+		return recordTaskEvent(tx, taskID, EventTagAdded, nil, &name, actor)
+	}
+	return nil
+}
+
+// RemoveTaskTag takes a tag off a task; removing a tag it doesn't have is a no-op
+func (s *SQLiteStore) RemoveTaskTag(taskID, tagID int64, actor string) (*Task, error) {
+	err := s.withTx(func(tx *sql.Tx) error {
+		var name string
+		err := tx.QueryRow(`SELECT name FROM tags WHERE id = ?`, tagID).Scan(&name)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		return removeTaskTagTx(tx, taskID, tagID, name, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetTaskByID(taskID)
+}
+
+// removeTaskTagTx takes the tag named name off a task inside tx, recording a
+// tag_removed event if the task had it
+func removeTaskTagTx(tx *sql.Tx, taskID, tagID int64, name, actor string) error {
+	result, err := tx.Exec(`DELETE FROM task_tags WHERE task_id = ? AND tag_id = ?`, taskID, tagID)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := result.RowsAffected(); n > 0 {
+		return recordTaskEvent(tx, taskID, EventTagRemoved, &name, nil, actor)
+	}
+	return nil
+}
+
+// Search operations
+
+// SearchTasks finds tasks whose title or description match q.Text, most
+// relevant first. It uses the FTS5 index when available and otherwise
+// narrows candidates with LIKE and ranks them in Go.
+func (s *SQLiteStore) SearchTasks(q SearchQuery) ([]SearchResult, error) {
+	terms := parseSearchQuery(q.Text)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	filters := []string{`t.deleted_at IS NULL`}
+	var args []interface{}
+	if q.Completed != nil {
+		filters = append(filters, `t.is_completed = ?`)
+		args = append(args, *q.Completed)
+	}
+	if q.CategoryID != nil {
+		filters = append(filters, `t.category_id IN (SELECT id FROM categories WHERE id = ? AND deleted_at IS NULL)`)
+		args = append(args, *q.CategoryID)
+	}
+	if q.FromDate != "" {
+		filters = append(filters, `t.assigned_date >= ?`)
+		args = append(args, q.FromDate)
+	}
+	if q.ToDate != "" {
+		filters = append(filters, `t.assigned_date <= ?`)
+		args = append(args, q.ToDate)
+	}
+
+	if !s.fts {
+		return s.searchWithLike(q, terms, filters, args)
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = -1 // no limit
+	}
+
+	where := `tasks_fts MATCH ?`
+	for _, f := range filters {
+		where += ` AND ` + f
+	}
+
+	rows, err := s.db.Query(
+		`SELECT t.id, -bm25(tasks_fts, 10.0, 1.0),
+		 highlight(tasks_fts, 0, ?, ?), snippet(tasks_fts, 1, ?, ?, '…', ?)
+		 FROM tasks_fts JOIN tasks t ON t.id = tasks_fts.rowid
+		 WHERE `+where+`
+		 ORDER BY bm25(tasks_fts, 10.0, 1.0) ASC, t.id DESC LIMIT ?`,
+		append(append([]interface{}{ftsHighlightStart, ftsHighlightEnd, ftsHighlightStart, ftsHighlightEnd, snippetWords, ftsMatchExpression(terms)}, args...), limit)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	var ids []int64
+	for rows.Next() {
+		var r SearchResult
+		if err := rows.Scan(&r.Task.ID, &r.Score, &r.TitleHighlight, &r.Snippet); err != nil {
+			return nil, err
+		}
+		r.TitleHighlight = escapeFTSHighlight(r.TitleHighlight)
+		r.Snippet = escapeFTSHighlight(r.Snippet)
+		results = append(results, r)
+		ids = append(ids, r.Task.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tasks, err := s.GetTasksByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]Task, len(tasks))
+	for _, t := range tasks {
+		byID[t.ID] = t
+	}
+	for i := range results {
+		results[i].Task = byID[results[i].Task.ID]
+	}
+
+	return results, nil
+}
+
+// searchWithLike is the search fallback when SQLite lacks FTS5: every term
+// must appear somewhere in the title or description
+func (s *SQLiteStore) searchWithLike(q SearchQuery, terms []searchTerm, filters []string, args []interface{}) ([]SearchResult, error) {
+	for _, term := range terms {
+		pattern := "%" + strings.Join(term.words, "%") + "%"
+		filters = append(filters, `(t.title LIKE ? OR t.description LIKE ?)`)
+		args = append(args, pattern, pattern)
+	}
+
+	rows, err := s.db.Query(`SELECT t.id FROM tasks t WHERE `+strings.Join(filters, ` AND `), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tasks, err := s.GetTasksByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return rankSearchResults(tasks, q), nil
+}
+
+// QueryTasks returns the page of tasks matching q. Filtering, sorting and
+// the cursor all run in SQL and a page reads one task more than its limit,
+// to tell whether there is a next page, however many tasks match.
+func (s *SQLiteStore) QueryTasks(q TaskQuery) (*TaskPage, error) {
+	total, err := s.countTasks(q)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &TaskPage{Tasks: []Task{}, Total: total}
+	read := q
+	if q.Limit > 0 {
+		read.Limit = q.Limit + 1
+	}
+	var last TaskCursor
+	err = s.eachTask(read, func(task Task, cursor TaskCursor) error {
+		if q.Limit > 0 && len(page.Tasks) == q.Limit {
+			next := EncodeTaskCursor(last)
+			page.NextCursor = &next
+			return nil
+		}
+		page.Tasks = append(page.Tasks, task)
+		last = cursor
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// countTasks counts the tasks matching q's filters
+func (s *SQLiteStore) countTasks(q TaskQuery) (int, error) {
+	where, args, terms := taskQueryFilters(q)
+	if len(terms) == 0 {
+		var total int
+		err := s.db.QueryRow(`SELECT COUNT(*) FROM `+taskTables+` WHERE `+where, args...).Scan(&total)
+		return total, err
+	}
+
+	rows, err := s.db.Query(`SELECT t.title, t.description FROM `+taskTables+` WHERE `+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	total := 0
+	for rows.Next() {
+		var title, description string
+		if err := rows.Scan(&title, &description); err != nil {
+			return 0, err
+		}
+		if matchesSearchTerms(title, description, terms) {
+			total++
+		}
+	}
+
+	return total, rows.Err()
+}
+
+// taskQueryFilters builds the WHERE clause for the filters of q. LIKE only
+// narrows a text search down, so the returned search terms still have to be
+// matched against the words of each task.
+func taskQueryFilters(q TaskQuery) (string, []interface{}, []searchTerm) {
+	filters := []string{`t.deleted_at IS NULL`}
+	var args []interface{}
+	addRange := func(column, from, to string) {
+		if from != "" {
+			filters = append(filters, column+` >= ?`)
+			args = append(args, from)
+		}
+		if to != "" {
+			filters = append(filters, column+` <= ?`)
+			args = append(args, to)
+		}
+	}
+
+	addRange(`t.created_date`, q.CreatedFrom, q.CreatedTo)
+	addRange(`t.assigned_date`, q.AssignedFrom, q.AssignedTo)
+	addRange(`t.completed_date`, q.CompletedFrom, q.CompletedTo)
+	if q.Completed != nil {
+		filters = append(filters, `t.is_completed = ?`)
+		args = append(args, *q.Completed)
+	}
+	if len(q.CategoryIDs) > 0 {
+		placeholders, ids := inClause(q.CategoryIDs)
+		filters = append(filters, `c.id IN (`+placeholders+`)`)
+		args = append(args, ids...)
+	}
+	if q.MinDragDays != nil {
+		filters = append(filters, dragDaysColumn()+` >= ?`)
+		args = append(args, *q.MinDragDays)
+	}
+	if q.MaxDragDays != nil {
+		filters = append(filters, dragDaysColumn()+` <= ?`)
+		args = append(args, *q.MaxDragDays)
+	}
+
+	terms := parseSearchQuery(q.Text)
+	for _, term := range terms {
+		pattern := "%" + strings.Join(term.words, "%") + "%"
+		filters = append(filters, `(t.title LIKE ? OR t.description LIKE ?)`)
+		args = append(args, pattern, pattern)
+	}
+
+	return strings.Join(filters, ` AND `), args, terms
+}
+
+// taskSortColumn is the SQL expression tasks are sorted by for a sort field,
+// and whether its values are numbers
+func taskSortColumn(field string) (string, bool) {
+	switch field {
+	case "title":
+		return `LOWER(t.title)`, false
+	case "priority", "created_date", "assigned_date", "completed_date", "due_date":
+		return `t.` + field, false
+	case "created_at", "updated_at":
+		return `datetime(t.` + field + `)`, false
+	case "drag_days":
+		return dragDaysColumn(), true
+	}
+	return `t.id`, true
+}
+
+// dragDaysColumn computes drag days in SQL the way
+// WorkCalendar.WorkingDaysBetween does, so queries can filter and sort on
+// them: the working days in the whole weeks from the created to the assigned
+// date, those in the partial week left over, looked up by its first weekday
+// and length, less the holidays on working days in between
+func dragDaysColumn() string {
+	weekend := calendar.Weekend()
+	isWeekend := map[time.Weekday]bool{}
+	days := make([]string, len(weekend))
+	for i, day := range weekend {
+		isWeekend[day] = true
+		days[i] = strconv.Itoa(int(day))
+	}
+
+	var partial strings.Builder
+	for start := time.Sunday; start <= time.Saturday; start++ {
+		count := 0
+		for i := 0; i < 7; i++ {
+			if i > 0 && !isWeekend[(start+time.Weekday(i))%7] {
+				count++
+			}
+			partial.WriteString(strconv.Itoa(count))
+		}
+	}
+
+	holidays := `SELECT COUNT(*) FROM holidays h WHERE h.date > t.created_date AND h.date <= t.assigned_date`
+	if len(days) > 0 {
+		holidays += ` AND CAST(strftime('%w', h.date) AS INTEGER) NOT IN (` + strings.Join(days, ", ") + `)`
+	}
+
+	span := `MAX(CAST(julianday(t.assigned_date) - julianday(t.created_date) AS INTEGER), 0)`
+	return fmt.Sprintf(`COALESCE(%[1]s / 7 * %[2]d + CAST(substr('%[3]s', CAST(strftime('%%w', t.created_date) AS INTEGER) * 7 + %[1]s %% 7 + 1, 1) AS INTEGER) - (%[4]s), 0)`,
+		span, 7-len(weekend), partial.String(), holidays)
+}
+
+// Holiday operations
+
+// isUniqueViolation reports whether err is a SQLite UNIQUE constraint failure
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
+
+// CreateHoliday adds a holiday to the working calendar
+func (s *SQLiteStore) CreateHoliday(date, name string) (*Holiday, error) {
+	result, err := s.db.Exec(`INSERT INTO holidays (date, name) VALUES (?, ?)`, date, name)
+	if isUniqueViolation(err) {
+		return nil, ErrDuplicate
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	id, _ := result.LastInsertId()
+	return s.GetHolidayByID(id)
+}
+
+// GetHolidayByID retrieves a holiday by ID
+func (s *SQLiteStore) GetHolidayByID(id int64) (*Holiday, error) {
+	h := &Holiday{}
+	err := s.db.QueryRow(
+		`SELECT id, date, name, created_at FROM holidays WHERE id = ?`,
+		id,
+	).Scan(&h.ID, &h.Date, &h.Name, &h.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// GetHolidays retrieves holidays between two dates inclusive; empty bounds are open
+func (s *SQLiteStore) GetHolidays(fromDate, toDate string) ([]Holiday, error) {
+	rows, err := s.db.Query(
+		`SELECT id, date, name, created_at FROM holidays
+		 WHERE (? = '' OR date >= ?) AND (? = '' OR date <= ?)
+		 ORDER BY date ASC`,
+		fromDate, fromDate, toDate, toDate,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holidays []Holiday
+	for rows.Next() {
+		var h Holiday
+		if err := rows.Scan(&h.ID, &h.Date, &h.Name, &h.CreatedAt); err != nil {
+			return nil, err
+		}
+		holidays = append(holidays, h)
+	}
+
+	return holidays, rows.Err()
+}
+
+// UpdateHoliday changes a holiday's date and name
+func (s *SQLiteStore) UpdateHoliday(id int64, date, name string) (*Holiday, error) {
+	result, err := s.db.Exec(`UPDATE holidays SET date = ?, name = ? WHERE id = ?`, date, name, id)
+	if isUniqueViolation(err) {
+		return nil, ErrDuplicate
+	}
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrNotFound
+	}
+
+	return s.GetHolidayByID(id)
+}
+
+// DeleteHoliday removes a holiday
+func (s *SQLiteStore) DeleteHoliday(id int64) error {
+	_, err := s.db.Exec(`DELETE FROM holidays WHERE id = ?`, id)
+	return err
+}
+
+// Rollover run operations
+
+// RecordRolloverRun stores the outcome of a scheduled rollover
+func (s *SQLiteStore) RecordRolloverRun(run *RolloverRun) error {
+	result, err := s.db.Exec(
+		`INSERT INTO rollover_runs (run_date, trigger, tasks_moved, error, started_at, finished_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		run.RunDate, run.Trigger, run.TasksMoved, run.Error, run.StartedAt, run.FinishedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	run.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// GetLastRolloverRun retrieves the most recent successful rollover run
+func (s *SQLiteStore) GetLastRolloverRun() (*RolloverRun, error) {
+	run := &RolloverRun{}
+	err := s.db.QueryRow(
+		`SELECT id, run_date, trigger, tasks_moved, error, started_at, finished_at
+		 FROM rollover_runs WHERE error = '' ORDER BY run_date DESC, id DESC LIMIT 1`,
+	).Scan(&run.ID, &run.RunDate, &run.Trigger, &run.TasksMoved, &run.Error, &run.StartedAt, &run.FinishedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return run, nil
+}
+
+// GetRolloverRuns retrieves the most recent rollover runs, newest first
+func (s *SQLiteStore) GetRolloverRuns(limit int) ([]RolloverRun, error) {
+	rows, err := s.db.Query(
+		`SELECT id, run_date, trigger, tasks_moved, error, started_at, finished_at
+		 FROM rollover_runs ORDER BY id DESC LIMIT ?`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []RolloverRun
+	for rows.Next() {
+		var run RolloverRun
+		if err := rows.Scan(&run.ID, &run.RunDate, &run.Trigger, &run.TasksMoved, &run.Error, &run.StartedAt, &run.FinishedAt); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+// Task event operations
+
+// queryIDsTx runs a query selecting one ID column inside tx
+func queryIDsTx(tx *sql.Tx, query string, args ...interface{}) ([]int64, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// readJournalStateTx snapshots the given tasks and categories, live or in
+// the trash, ordered by ID
+func readJournalStateTx(tx *sql.Tx, taskIDs, categoryIDs []int64) (journalState, error) {
+	var state journalState
+
+	err := eachIDChunk(sortedIDs(taskIDs), func(chunk []int64) error {
+		placeholders, args := inClause(chunk)
+		rows, err := tx.Query(
+			`SELECT id, title, description, assigned_date, completed_date, is_completed, category_id, priority, due_date, deleted_at IS NOT NULL
+			 FROM tasks WHERE id IN (`+placeholders+`) ORDER BY id ASC`,
+			args...,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var t taskState
+			var completedDate, priority, dueDate sql.NullString
+			var categoryID sql.NullInt64
+			err := rows.Scan(&t.ID, &t.Title, &t.Description, &t.AssignedDate, &completedDate, &t.IsCompleted,
+				&categoryID, &priority, &dueDate, &t.Deleted)
+			if err != nil {
+				return err
+			}
+			t.CompletedDate = nullStringPtr(completedDate)
+			t.CategoryID = nullInt64Ptr(categoryID)
+			t.Priority = nullStringPtr(priority)
+			t.DueDate = nullStringPtr(dueDate)
+			state.Tasks = append(state.Tasks, t)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return state, err
+	}
+
+	err = eachIDChunk(sortedIDs(categoryIDs), func(chunk []int64) error {
+		placeholders, args := inClause(chunk)
+		rows, err := tx.Query(
+			`SELECT id, deleted_at IS NOT NULL FROM categories WHERE id IN (`+placeholders+`) ORDER BY id ASC`,
+			args...,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var c categoryState
+			if err := rows.Scan(&c.ID, &c.Deleted); err != nil {
+				return err
+			}
+			state.Categories = append(state.Categories, c)
+		}
+		return rows.Err()
+	})
+
+	return state, err
+}
+
+// writeTaskStateTx puts a task back into a journaled state, recording the
+// events of each change. Tasks purged from the trash since are skipped.
+func writeTaskStateTx(tx *sql.Tx, to taskState, actor string) error {
+	current, err := readJournalStateTx(tx, []int64{to.ID}, nil)
+	if err != nil || len(current.Tasks) == 0 {
+		return err
+	}
+	events := taskTransition(current.Tasks[0], to)
+	if len(events) == 0 {
+		return nil
+	}
+
+	var completed interface{}
+	if to.IsCompleted {
+		completed = to.CompletedDate
+	}
+	_, err = tx.Exec(
+		`UPDATE tasks SET title = ?, description = ?, assigned_date = ?, completed_date = ?, is_completed = ?,
+		 category_id = ?, priority = ?, due_date = ?,
+		 deleted_at = CASE WHEN ? THEN COALESCE(deleted_at, ?) ELSE NULL END,
+		 updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		to.Title, to.Description, to.AssignedDate, completed, to.IsCompleted,
+		to.CategoryID, to.Priority, to.DueDate, to.Deleted, eventTimestamp(), to.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range events {
+		if err := recordTaskEvent(tx, to.ID, e.Type, e.OldValue, e.NewValue, actor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCategoryStateTx moves a category into or out of the trash. Its tasks
+// get a category_changed event, as they gain or lose the category.
+func writeCategoryStateTx(tx *sql.Tx, to categoryState, actor string) error {
+	current, err := readJournalStateTx(tx, nil, []int64{to.ID})
+	if err != nil || len(current.Categories) == 0 || current.Categories[0] == to {
+		return err
 	}
 
-	log.CompletedCount = len(completedTasks)
+	categoryID := strconv.FormatInt(to.ID, 10)
+	var oldValue, newValue interface{} = categoryID, nil
+	if !to.Deleted {
+		oldValue, newValue = nil, categoryID
+	}
+	_, err = tx.Exec(
+		`INSERT INTO task_events (task_id, event_type, old_value, new_value, actor, created_at)
+		 SELECT id, ?, ?, ?, ?, ? FROM tasks WHERE category_id = ? AND deleted_at IS NULL`,
+		EventCategoryChanged, oldValue, newValue, actor, eventTimestamp(), to.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`UPDATE categories SET deleted_at = CASE WHEN ? THEN ? ELSE NULL END WHERE id = ?`,
+		to.Deleted, eventTimestamp(), to.ID,
+	)
+	return err
+}
+
+// writeJournalStateTx puts everything in state back
+func writeJournalStateTx(tx *sql.Tx, state journalState, actor string) error {
+	for _, c := range state.Categories {
+		if err := writeCategoryStateTx(tx, c, actor); err != nil {
+			return err
+		}
+	}
+	for _, t := range state.Tasks {
+		if err := writeTaskStateTx(tx, t, actor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// journalTx records an operation that took the things in before to their
+// current state. Operations that changed nothing are left out, and a new
+// operation discards whatever could have been redone.
+func journalTx(tx *sql.Tx, kind, summary, actor string, before journalState) error {
+	after, err := readJournalStateTx(tx, before.taskIDs(), before.categoryIDs())
+	if err != nil || sameJournalState(before, after) {
+		return err
+	}
+
+	beforeJSON, err := json.Marshal(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := json.Marshal(after)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM operations WHERE undone_at IS NOT NULL`); err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		`INSERT INTO operations (kind, summary, actor, task_count, before_state, after_state, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		kind, summary, actor, len(after.Tasks), string(beforeJSON), string(afterJSON), eventTimestamp(),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`DELETE FROM operations WHERE id NOT IN (SELECT id FROM operations ORDER BY id DESC LIMIT ?)`,
+		journalSize,
+	)
+	return err
+}
+
+// journaledTaskTx runs fn against a live task inside a transaction and
+// journals the change as an operation of kind
+func (s *SQLiteStore) journaledTaskTx(id int64, kind, actor string, fn func(tx *sql.Tx) error) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		before, err := readJournalStateTx(tx, []int64{id}, nil)
+		if err != nil {
+			return err
+		}
+		if len(before.Tasks) == 0 || before.Tasks[0].Deleted {
+			return ErrNotFound
+		}
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return journalTx(tx, kind, operationSummary(kind, before.Tasks[0].Title, 1), actor, before)
+	})
+}
+
+// RestoreTask takes a task out of the trash
+func (s *SQLiteStore) RestoreTask(id int64, actor string) (*Task, error) {
+	err := s.withTx(func(tx *sql.Tx) error {
+		before, err := readJournalStateTx(tx, []int64{id}, nil)
+		if err != nil {
+			return err
+		}
+		if len(before.Tasks) == 0 || !before.Tasks[0].Deleted {
+			return ErrNotFound
+		}
+
+		restored := before.Tasks[0]
+		restored.Deleted = false
+		if err := writeTaskStateTx(tx, restored, actor); err != nil {
+			return err
+		}
+		return journalTx(tx, OpRestoreTask, operationSummary(OpRestoreTask, restored.Title, 1), actor, before)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetTaskByID(id)
+}
+
+// RestoreCategory takes a category out of the trash, giving its tasks their
+// category back
+func (s *SQLiteStore) RestoreCategory(id int64, actor string) (*Category, error) {
+	err := s.withTx(func(tx *sql.Tx) error {
+		var name string
+		err := tx.QueryRow(`SELECT name FROM categories WHERE id = ? AND deleted_at IS NOT NULL`, id).Scan(&name)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		before, err := readJournalStateTx(tx, nil, []int64{id})
+		if err != nil {
+			return err
+		}
+		if err := writeCategoryStateTx(tx, categoryState{ID: id}, actor); err != nil {
+			return err
+		}
+		return journalTx(tx, OpRestoreCategory, operationSummary(OpRestoreCategory, name, 0), actor, before)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetCategoryByID(id)
+}
+
+// GetTrash lists deleted tasks and categories, most recently deleted first
+func (s *SQLiteStore) GetTrash() (*Trash, error) {
+	tasks, err := s.queryTasks(taskColumns + ` WHERE t.deleted_at IS NOT NULL ORDER BY t.deleted_at DESC, t.id DESC`)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(
+		`SELECT ` + categoryColumns + `, c.deleted_at FROM categories c
+		 WHERE c.deleted_at IS NOT NULL ORDER BY c.deleted_at DESC, c.id DESC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trash := &Trash{Tasks: tasks, Categories: []Category{}}
+	if trash.Tasks == nil {
+		trash.Tasks = []Task{}
+	}
+	for rows.Next() {
+		var deletedAt time.Time
+		c, err := scanCategory(rows, &deletedAt)
+		if err != nil {
+			return nil, err
+		}
+		c.DeletedAt = &deletedAt
+		trash.Categories = append(trash.Categories, *c)
+	}
+
+	return trash, rows.Err()
+}
+
+// EmptyTrash permanently deletes everything in the trash and returns how
+// many tasks and categories went. Task history is kept.
+func (s *SQLiteStore) EmptyTrash() (int, error) {
+	var purged int64
+	err := s.withTx(func(tx *sql.Tx) error {
+		for _, stmt := range []string{
+			`DELETE FROM task_items WHERE task_id IN (SELECT id FROM tasks WHERE deleted_at IS NOT NULL)`,
+			`DELETE FROM task_tags WHERE task_id IN (SELECT id FROM tasks WHERE deleted_at IS NOT NULL)`,
+			`UPDATE tasks SET category_id = NULL WHERE category_id IN (SELECT id FROM categories WHERE deleted_at IS NOT NULL)`,
+			`UPDATE recurring_tasks SET category_id = NULL WHERE category_id IN (SELECT id FROM categories WHERE deleted_at IS NOT NULL)`,
+		} {
+			if _, err := tx.Exec(stmt); err != nil {
+				return err
+			}
+		}
+
+		for _, table := range []string{"tasks", "categories"} {
+			result, err := tx.Exec(`DELETE FROM ` + table + ` WHERE deleted_at IS NOT NULL`)
+			if err != nil {
+				return err
+			}
+			n, _ := result.RowsAffected()
+			purged += n
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(purged), nil
+}
+
+// GetOperations lists the most recent journaled operations, newest first
+func (s *SQLiteStore) GetOperations(limit int) ([]Operation, error) {
+	rows, err := s.db.Query(
+		`SELECT id, kind, summary, actor, task_count, undone_at IS NOT NULL, created_at
+		 FROM operations ORDER BY id DESC LIMIT ?`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var operations []Operation
+	for rows.Next() {
+		var op Operation
+		if err := rows.Scan(&op.ID, &op.Kind, &op.Summary, &op.Actor, &op.TaskCount, &op.Undone, &op.CreatedAt); err != nil {
+			return nil, err
+		}
+		operations = append(operations, op)
+	}
+
+	return operations, rows.Err()
+}
+
+// Undo reverts the most recent operation that has not been undone
+func (s *SQLiteStore) Undo(actor string) (*Operation, error) {
+	return s.replayOperation(
+		`SELECT id, before_state FROM operations WHERE undone_at IS NULL ORDER BY id DESC LIMIT 1`,
+		eventTimestamp(), actor,
+	)
+}
+
+// Redo reapplies the earliest undone operation
+func (s *SQLiteStore) Redo(actor string) (*Operation, error) {
+	return s.replayOperation(
+		`SELECT id, after_state FROM operations WHERE undone_at IS NOT NULL ORDER BY id ASC LIMIT 1`,
+		nil, actor,
+	)
+}
+
+// replayOperation writes back the state selected by pick and sets the
+// operation's undone_at. It returns ErrNotFound when there is nothing to
+// replay.
+func (s *SQLiteStore) replayOperation(pick string, undoneAt interface{}, actor string) (*Operation, error) {
+	var id int64
+	err := s.withTx(func(tx *sql.Tx) error {
+		var stateJSON string
+		err := tx.QueryRow(pick).Scan(&id, &stateJSON)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var state journalState
+		if err := json.Unmarshal([]byte(stateJSON), &state); err != nil {
+			return err
+		}
+		if err := writeJournalStateTx(tx, state, actor); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`UPDATE operations SET undone_at = ? WHERE id = ?`, undoneAt, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var op Operation
+	err = s.db.QueryRow(
+		`SELECT id, kind, summary, actor, task_count, undone_at IS NOT NULL, created_at FROM operations WHERE id = ?`, id,
+	).Scan(&op.ID, &op.Kind, &op.Summary, &op.Actor, &op.TaskCount, &op.Undone, &op.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &op, nil
+}
+
+// recordTaskEvent appends an entry to a task's history
+func recordTaskEvent(tx *sql.Tx, taskID int64, eventType string, oldValue, newValue *string, actor string) error {
+	_, err := tx.Exec(
+		`INSERT INTO task_events (task_id, event_type, old_value, new_value, actor, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		taskID, eventType, oldValue, newValue, actor, eventTimestamp(),
+	)
+	return err
+}
+
+// eventTimestamp is the injectable clock's current time in CURRENT_TIMESTAMP
+// format, so simulated days produce correctly dated history
+func eventTimestamp() string {
+	return clock.Now().UTC().Format("2006-01-02 15:04:05")
+}
+
+// GetTaskHistory retrieves every event recorded for a task, oldest first
+func (s *SQLiteStore) GetTaskHistory(taskID int64) ([]TaskEvent, error) {
+	rows, err := s.db.Query(
+		`SELECT id, task_id, event_type, old_value, new_value, actor, created_at
+		 FROM task_events WHERE task_id = ? ORDER BY id ASC`,
+		taskID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []TaskEvent
+	for rows.Next() {
+		var e TaskEvent
+		var oldValue, newValue sql.NullString
+		if err := rows.Scan(&e.ID, &e.TaskID, &e.Type, &oldValue, &newValue, &e.Actor, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.OldValue = nullStringPtr(oldValue)
+		e.NewValue = nullStringPtr(newValue)
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+// GetTaskHistories retrieves the events of several tasks keyed by task ID
+func (s *SQLiteStore) GetTaskHistories(taskIDs []int64) (map[int64][]TaskEvent, error) {
+	histories := make(map[int64][]TaskEvent)
+	if len(taskIDs) == 0 {
+		return histories, nil
+	}
+
+	err := eachIDChunk(taskIDs, func(chunk []int64) error {
+		placeholders, args := inClause(chunk)
+		rows, err := s.db.Query(
+			`SELECT id, task_id, event_type, old_value, new_value, actor, created_at
+			 FROM task_events WHERE task_id IN (`+placeholders+`) ORDER BY id ASC`,
+			args...,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var e TaskEvent
+			var oldValue, newValue sql.NullString
+			if err := rows.Scan(&e.ID, &e.TaskID, &e.Type, &oldValue, &newValue, &e.Actor, &e.CreatedAt); err != nil {
+				return err
+			}
+			e.OldValue = nullStringPtr(oldValue)
+			e.NewValue = nullStringPtr(newValue)
+			histories[e.TaskID] = append(histories[e.TaskID], e)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return histories, nil
+}
+
+// GetTaskIDsTouchingDate finds every task, deleted ones included, that was
+// created, assigned or completed on date according to its row or its events
+func (s *SQLiteStore) GetTaskIDsTouchingDate(date string) ([]int64, error) {
+	rows, err := s.db.Query(
+		`SELECT id FROM tasks WHERE created_date = ? OR assigned_date = ? OR completed_date = ?
+		 UNION
+		 SELECT task_id FROM task_events
+		 WHERE event_type IN (?, ?, ?, ?, ?, ?) AND (old_value = ? OR new_value = ?)
+		 ORDER BY 1`,
+		date, date, date,
+		EventCreated, EventRolledOver, EventMoved, EventCompleted, EventDeleted, EventRestored, date, date,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// RecordEscalations records an escalated event for every pending task whose
+// stale level is above the last one recorded for it
+func (s *SQLiteStore) RecordEscalations(actor string) ([]Escalation, error) {
+	var escalations []Escalation
+	err := s.withTx(func(tx *sql.Tx) error {
+		rows, err := tx.Query(
+			`SELECT t.id, t.title, t.created_date, t.assigned_date, c.stale_after_days, c.critical_after_days,
+			 (SELECT e.new_value FROM task_events e WHERE e.task_id = t.id AND e.event_type = ? ORDER BY e.id DESC LIMIT 1)
+			 FROM tasks t LEFT JOIN categories c ON c.id = t.category_id AND c.deleted_at IS NULL
+			 WHERE t.is_completed = FALSE AND t.deleted_at IS NULL
+			 ORDER BY t.id ASC`,
+			EventEscalated,
+		)
+		if err != nil {
+			return err
+		}
+
+		var recorded []*string
+		for rows.Next() {
+			var e Escalation
+			var createdDate, assignedDate string
+			var staleAfter, criticalAfter sql.NullInt64
+			var last sql.NullString
+			if err := rows.Scan(&e.TaskID, &e.Title, &createdDate, &assignedDate, &staleAfter, &criticalAfter, &last); err != nil {
+				rows.Close()
+				return err
+			}
+
+			e.DragDays = CalculateBusinessDays(createdDate, assignedDate)
+			e.Level = categoryThresholds(nullIntPtr(staleAfter), nullIntPtr(criticalAfter)).level(e.DragDays)
+			if levelRank(e.Level) > levelRank(last.String) {
+				e.Outcome = EscalationDragging
+				escalations = append(escalations, e)
+				recorded = append(recorded, nullStringPtr(last))
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for i := range escalations {
+			e := &escalations[i]
+			if err := recordTaskEvent(tx, e.TaskID, EventEscalated, recorded[i], &e.Level, actor); err != nil {
+				return err
+			}
+			e.EscalatedAt = clock.Now().UTC().Truncate(time.Second)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return escalations, nil
+}
+
+// GetEscalations lists the escalations recorded in [from, to) with what has
+// become of each task since
+func (s *SQLiteStore) GetEscalations(from, to time.Time) ([]Escalation, error) {
+	rows, err := s.db.Query(
+		`SELECT e.task_id, e.new_value, e.created_at, t.id IS NOT NULL, COALESCE(t.title, ''),
+		 COALESCE(t.is_completed, FALSE), t.deleted_at IS NOT NULL, COALESCE(t.created_date, ''), COALESCE(t.assigned_date, '')
+		 FROM task_events e LEFT JOIN tasks t ON t.id = e.task_id
+		 WHERE e.event_type = ? AND e.created_at >= ? AND e.created_at < ?
+		 ORDER BY e.id ASC`,
+		EventEscalated, from.UTC().Format("2006-01-02 15:04:05"), to.UTC().Format("2006-01-02 15:04:05"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var escalations []Escalation
+	for rows.Next() {
+		var e Escalation
+		var exists, completed, deleted bool
+		var createdDate, assignedDate string
+		err := rows.Scan(&e.TaskID, &e.Level, &e.EscalatedAt, &exists, &e.Title, &completed, &deleted, &createdDate, &assignedDate)
+		if err != nil {
+			return nil, err
+		}
+		e.Outcome = escalationOutcome(exists, deleted, completed)
+		e.DragDays = CalculateBusinessDays(createdDate, assignedDate)
+		escalations = append(escalations, e)
+	}
+
+	return escalations, rows.Err()
+}
+
+// maxInClauseIDs caps the IDs bound in one IN list, well under SQLite's limit
+// on bind variables
+const maxInClauseIDs = 500
+
+// eachIDChunk calls fn with ids split into chunks of at most maxInClauseIDs,
+// in order
+func eachIDChunk(ids []int64, fn func(chunk []int64) error) error {
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > maxInClauseIDs {
+			n = maxInClauseIDs
+		}
+		if err := fn(ids[:n]); err != nil {
+			return err
+		}
+		ids = ids[n:]
+	}
+	return nil
+}
+
+// sortedIDs returns a sorted copy of ids, so chunks of it come back in ID
+// order
+func sortedIDs(ids []int64) []int64 {
+	sorted := append([]int64(nil), ids...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+// inClause builds "?, ?, ?" placeholders and arguments for an IN list
+func inClause(ids []int64) (string, []interface{}) {
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	return strings.Join(placeholders, ", "), args
+}
+
+func nullStringPtr(v sql.NullString) *string {
+	if !v.Valid {
+		return nil
+	}
+	return &v.String
+}
+
+func nullInt64Ptr(v sql.NullInt64) *int64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Int64
+}
+
+func nullIntPtr(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	n := int(v.Int64)
+	return &n
+}
+
+// CalculateBusinessDays calculates the number of working days between two dates
+func CalculateBusinessDays(startDate, endDate string) int {
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return 0
+	}
+
+	end, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		return 0
+	}
+
+	// Skip weekends and holidays from the working calendar
+	return calendar.WorkingDaysBetween(start, end)
+}
+
+// GetTasksByIDs retrieves the tasks with the given IDs in ID order, skipping
+// missing ones
+func (s *SQLiteStore) GetTasksByIDs(ids []int64) ([]Task, error) {
+	var tasks []Task
+	err := eachIDChunk(sortedIDs(ids), func(chunk []int64) error {
+		placeholders, args := inClause(chunk)
+		found, err := s.queryTasks(taskColumns+` WHERE t.id IN (`+placeholders+`) AND t.deleted_at IS NULL ORDER BY t.id ASC`, args...)
+		tasks = append(tasks, found...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// GetTasksByIDsIncludingTrash is GetTasksByIDs with the tasks in the trash
+func (s *SQLiteStore) GetTasksByIDsIncludingTrash(ids []int64) ([]Task, error) {
+	var tasks []Task
+	err := eachIDChunk(sortedIDs(ids), func(chunk []int64) error {
+		placeholders, args := inClause(chunk)
+		found, err := s.queryTasks(taskColumns+` WHERE t.id IN (`+placeholders+`) ORDER BY t.id ASC`, args...)
+		tasks = append(tasks, found...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
 
-	return log, nil
+// GetCompletedTasksForDate retrieves tasks that were completed on a specific date
+func (s *SQLiteStore) GetCompletedTasksForDate(date string) ([]Task, error) {
+	return s.queryTasks(
+		taskColumns+` WHERE t.completed_date = ? AND t.is_completed = TRUE AND t.deleted_at IS NULL
+		 ORDER BY t.created_at ASC`,
+		date,
+	)
 }
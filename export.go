@@ -0,0 +1,349 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export formats
+const (
+	ExportCSV    = "csv"
+	ExportJSON   = "json"
+	ExportNDJSON = "ndjson"
+)
+
+// ExportTask is a task as exported, flattened with its category. Its fields
+// are in the same order as the CSV columns; CSV leaves out the checklist.
+type ExportTask struct {
+	ID            int64        `json:"id"`
+	Title         string       `json:"title"`
+	Description   string       `json:"description"`
+	CreatedDate   string       `json:"created_date"`
+	AssignedDate  string       `json:"assigned_date"`
+	CompletedDate *string      `json:"completed_date"`
+	IsCompleted   bool         `json:"is_completed"`
+	DragDays      int          `json:"drag_days"`
+	Priority      *string      `json:"priority"`
+	DueDate       *string      `json:"due_date"`
+	CategoryID    *int64       `json:"category_id"`
+	CategoryName  *string      `json:"category_name"`
+	CategoryColor *string      `json:"category_color"`
+	RecurringID   *int64       `json:"recurring_id"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+	Tags          []string     `json:"tags"`  // Tag names
+	Items         []ExportItem `json:"items"` // The checklist in order
+}
+
+// ExportItem is a checklist item as exported
+type ExportItem struct {
+	Title  string `json:"title"`
+	IsDone bool   `json:"is_done"`
+}
+
+// exportTaskColumns is the CSV header for tasks
+var exportTaskColumns = []string{
+	"id", "title", "description", "created_date", "assigned_date", "completed_date", "is_completed", "drag_days",
+	"priority", "due_date", "category_id", "category_name", "category_color", "recurring_id", "created_at", "updated_at", "tags",
+}
+
+// ExportCategory is a category as exported, in CSV column order
+type ExportCategory struct {
+	ID                int64     `json:"id"`
+	Name              string    `json:"name"`
+	Color             string    `json:"color"`
+	RolloverPolicy    string    `json:"rollover_policy"`
+	ArchiveAfterDays  *int      `json:"archive_after_days"`
+	StaleAfterDays    *int      `json:"stale_after_days"`
+	CriticalAfterDays *int      `json:"critical_after_days"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// exportCategoryColumns is the CSV header for categories
+var exportCategoryColumns = []string{
+	"id", "name", "color", "rollover_policy", "archive_after_days", "stale_after_days", "critical_after_days", "created_at",
+}
+
+// newExportTask flattens a task with its checklist items
+func newExportTask(t Task, items []TaskItem) ExportTask {
+	e := ExportTask{
+		ID:            t.ID,
+		Title:         t.Title,
+		Description:   t.Description,
+		CreatedDate:   t.CreatedDate,
+		AssignedDate:  t.AssignedDate,
+		CompletedDate: t.CompletedDate,
+		IsCompleted:   t.IsCompleted,
+		DragDays:      t.DragDays,
+		Priority:      t.Priority,
+		DueDate:       t.DueDate,
+		CategoryID:    t.CategoryID,
+		RecurringID:   t.RecurringID,
+		CreatedAt:     t.CreatedAt.UTC(),
+		UpdatedAt:     t.UpdatedAt.UTC(),
+		Tags:          make([]string, len(t.Tags)),
+		Items:         make([]ExportItem, len(items)),
+	}
+	if t.Category != nil {
+		e.CategoryName = &t.Category.Name
+		e.CategoryColor = &t.Category.Color
+	}
+	for i, tag := range t.Tags {
+		e.Tags[i] = tag.Name
+	}
+	for i, item := range items {
+		e.Items[i] = ExportItem{Title: item.Title, IsDone: item.IsDone}
+	}
+	return e
+}
+
+func newExportCategory(c Category) ExportCategory {
+	return ExportCategory{
+		ID:                c.ID,
+		Name:              c.Name,
+		Color:             c.Color,
+		RolloverPolicy:    c.RolloverPolicy,
+		ArchiveAfterDays:  c.ArchiveAfterDays,
+		StaleAfterDays:    c.StaleAfterDays,
+		CriticalAfterDays: c.CriticalAfterDays,
+		CreatedAt:         c.CreatedAt.UTC(),
+	}
+}
+
+func (e ExportTask) csvRecord() []string {
+	return []string{
+		strconv.FormatInt(e.ID, 10), e.Title, e.Description, e.CreatedDate, e.AssignedDate, csvString(e.CompletedDate),
+		strconv.FormatBool(e.IsCompleted), strconv.Itoa(e.DragDays), csvString(e.Priority), csvString(e.DueDate),
+		csvInt64(e.CategoryID), csvString(e.CategoryName), csvString(e.CategoryColor), csvInt64(e.RecurringID),
+		e.CreatedAt.Format(time.RFC3339), e.UpdatedAt.Format(time.RFC3339), strings.Join(e.Tags, ","),
+	}
+}
+
+func (e ExportCategory) csvRecord() []string {
+	return []string{
+		strconv.FormatInt(e.ID, 10), e.Name, e.Color, e.RolloverPolicy,
+		csvInt(e.ArchiveAfterDays), csvInt(e.StaleAfterDays), csvInt(e.CriticalAfterDays),
+		e.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// Optional values are empty CSV fields when unset
+func csvString(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
+
+func csvInt64(v *int64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatInt(*v, 10)
+}
+
+func csvInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+// ExportRequest says what to export and how
+type ExportRequest struct {
+	Format     string
+	From       string // Inclusive bounds on the tasks' created date; empty bounds are open
+	To         string
+	Tasks      bool
+	Categories bool
+}
+
+// taskQuery selects the tasks to export in ID order
+func (req ExportRequest) taskQuery() TaskQuery {
+	return TaskQuery{CreatedFrom: req.From, CreatedTo: req.To, Sort: "id"}
+}
+
+// eachExportTask calls fn with each task to export and its checklist. Tasks
+// are read a chunk at a time so their checklists load in one query per chunk.
+func eachExportTask(s Store, req ExportRequest, fn func(ExportTask) error) error {
+	var chunk []Task
+	flush := func() error {
+		ids := make([]int64, len(chunk))
+		for i, t := range chunk {
+			ids[i] = t.ID
+		}
+		checklists, err := s.GetTaskChecklists(ids)
+		if err != nil {
+			return err
+		}
+		for _, t := range chunk {
+			if err := fn(newExportTask(t, checklists[t.ID])); err != nil {
+				return err
+			}
+		}
+		chunk = chunk[:0]
+		return nil
+	}
+
+	err := s.EachTask(req.taskQuery(), func(t Task) error {
+		chunk = append(chunk, t)
+		if len(chunk) < taskChunkSize {
+			return nil
+		}
+		return flush()
+	})
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+// Export writes the requested tasks and categories to w, reading and writing
+// tasks one at a time. CSV holds either tasks or categories, as CSV has one
+// header; JSON is a single object and NDJSON a line per record, tagged with
+// its type.
+func Export(s Store, w io.Writer, req ExportRequest) error {
+	var categories []Category
+	if req.Categories {
+		var err error
+		if categories, err = s.GetAllCategories(); err != nil {
+			return err
+		}
+	}
+
+	buf := bufio.NewWriter(w)
+	var err error
+	switch req.Format {
+	case ExportCSV:
+		err = exportCSV(s, buf, req, categories)
+	case ExportJSON:
+		err = exportJSON(s, buf, req, categories)
+	case ExportNDJSON:
+		err = exportNDJSON(s, buf, req, categories)
+	default:
+		err = fmt.Errorf("unknown export format %q", req.Format)
+	}
+	if err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+func exportCSV(s Store, w io.Writer, req ExportRequest, categories []Category) error {
+	cw := csv.NewWriter(w)
+
+	if req.Categories {
+		if err := cw.Write(exportCategoryColumns); err != nil {
+			return err
+		}
+		for _, c := range categories {
+			if err := cw.Write(newExportCategory(c).csvRecord()); err != nil {
+				return err
+			}
+		}
+	} else {
+		if err := cw.Write(exportTaskColumns); err != nil {
+			return err
+		}
+		err := s.EachTask(req.taskQuery(), func(t Task) error {
+			return cw.Write(newExportTask(t, nil).csvRecord())
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func exportJSON(s Store, w io.Writer, req ExportRequest, categories []Category) error {
+	header := struct {
+		ExportedAt time.Time `json:"exported_at"`
+		From       *string   `json:"from"`
+		To         *string   `json:"to"`
+	}{clock.Now().UTC().Truncate(time.Second), emptyToNil(&req.From), emptyToNil(&req.To)}
+	data, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	// Leave the header object open to add the lists to it
+	if _, err := w.Write(data[:len(data)-1]); err != nil {
+		return err
+	}
+
+	if req.Categories {
+		list := make([]ExportCategory, len(categories))
+		for i, c := range categories {
+			list[i] = newExportCategory(c)
+		}
+		data, err := json.Marshal(list)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, `,"categories":%s`, data); err != nil {
+			return err
+		}
+	}
+
+	if req.Tasks {
+		if _, err := io.WriteString(w, `,"tasks":[`); err != nil {
+			return err
+		}
+		first := true
+		err := eachExportTask(s, req, func(t ExportTask) error {
+			data, err := json.Marshal(t)
+			if err != nil {
+				return err
+			}
+			if !first {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			first = false
+			_, err = w.Write(data)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "]"); err != nil {
+			return err
+		}
+	}
+
+	_, err = io.WriteString(w, "}\n")
+	return err
+}
+
+func exportNDJSON(s Store, w io.Writer, req ExportRequest, categories []Category) error {
+	enc := json.NewEncoder(w)
+
+	for _, c := range categories {
+		line := struct {
+			Type string `json:"type"`
+			ExportCategory
+		}{"category", newExportCategory(c)}
+		if err := enc.Encode(line); err != nil {
+			return err
+		}
+	}
+
+	if !req.Tasks {
+		return nil
+	}
+	return eachExportTask(s, req, func(t ExportTask) error {
+		line := struct {
+			Type string `json:"type"`
+			ExportTask
+		}{"task", t}
+		return enc.Encode(line)
+	})
+}
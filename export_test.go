@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// createExportTasks stores a categorized, tagged task with a checklist and
+// awkward text on testMonday, a completed one on testTuesday and one the week
+// after, returning their IDs in that order
+func createExportTasks(t *testing.T, s Store) []int64 {
+	t.Helper()
+	office, err := s.CreateCategory(CategoryRequest{Name: "Office", Color: "#123456", RolloverPolicy: RolloverNextDay})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p1 := "P1"
+	plan, err := s.CreateTask(TaskRequest{Title: `Plan "Q2", again`, Description: "Line one\nLine two", Date: testMonday, Priority: &p1, CategoryID: &office.ID}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"alpha", "beta"} {
+		tag, err := s.CreateTag(name, "#abcdef")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.AddTaskTag(plan.ID, tag.ID, "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, title := range []string{"Draft", "Review"} {
+		if _, err := s.CreateTaskItem(plan.ID, title, "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	done := mustCreateTask(t, s, "Done", testTuesday)
+	if _, err := s.UpdateTaskCompletion(done.ID, true, testTuesday, "test"); err != nil {
+		t.Fatal(err)
+	}
+	later := mustCreateTask(t, s, "Later", "2026-03-10")
+	return []int64{plan.ID, done.ID, later.ID}
+}
+
+// export runs req against s and returns what it wrote
+func export(t *testing.T, s Store, req ExportRequest) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Export(s, &buf, req); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestExportCSV(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		ids := createExportTasks(t, s)
+
+		records, err := csv.NewReader(strings.NewReader(export(t, s, ExportRequest{Format: ExportCSV, From: testMonday, To: testTuesday, Tasks: true}))).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 3 || !reflect.DeepEqual(records[0], exportTaskColumns) {
+			t.Fatalf("got %d records with header %q, want a header and 2 tasks", len(records), records[0])
+		}
+		plan := map[string]string{}
+		for i, column := range exportTaskColumns {
+			plan[column] = records[1][i]
+		}
+		want := map[string]string{
+			"title":         `Plan "Q2", again`,
+			"description":   "Line one\nLine two",
+			"priority":      "P1",
+			"category_name": "Office",
+			"tags":          "alpha,beta",
+			"due_date":      "",
+		}
+		for column, value := range want {
+			if plan[column] != value {
+				t.Errorf("%s: got %q, want %q", column, plan[column], value)
+			}
+		}
+		if records[2][0] != strconv.FormatInt(ids[1], 10) || records[2][6] != "true" || records[2][5] != testTuesday {
+			t.Errorf("got %q for the completed task", records[2])
+		}
+
+		records, err = csv.NewReader(strings.NewReader(export(t, s, ExportRequest{Format: ExportCSV, Categories: true}))).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 5 || !reflect.DeepEqual(records[0], exportCategoryColumns) || records[2][1] != "Office" {
+			t.Errorf("got categories %q, want a header and the 3 seeded with Office by name", records)
+		}
+	})
+}
+
+func TestExportJSON(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		setClock(t, testNow)
+		ids := createExportTasks(t, s)
+
+		var doc struct {
+			Categories []ExportCategory `json:"categories"`
+			Tasks      []ExportTask     `json:"tasks"`
+		}
+		out := export(t, s, ExportRequest{Format: ExportJSON, From: testTuesday, Tasks: true, Categories: true})
+		if err := json.Unmarshal([]byte(out), &doc); err != nil {
+			t.Fatalf("%v in %s", err, out)
+		}
+		var header struct {
+			ExportedAt string  `json:"exported_at"`
+			From       *string `json:"from"`
+			To         *string `json:"to"`
+		}
+		if err := json.Unmarshal([]byte(out), &header); err != nil {
+			t.Fatal(err)
+		}
+		if header.ExportedAt != "2026-03-02T00:30:00Z" || header.From == nil || *header.From != testTuesday || header.To != nil {
+			t.Errorf("got header %+v", header)
+		}
+		if len(doc.Categories) != 4 {
+			t.Errorf("got %d categories, want 4", len(doc.Categories))
+		}
+		if len(doc.Tasks) != 2 || doc.Tasks[0].ID != ids[1] || doc.Tasks[1].ID != ids[2] {
+			t.Errorf("got tasks %+v, want the two from Tuesday on", doc.Tasks)
+		}
+
+		// Checklists and tags come along in JSON
+		if err := json.Unmarshal([]byte(export(t, s, ExportRequest{Format: ExportJSON, To: testMonday, Tasks: true})), &doc); err != nil {
+			t.Fatal(err)
+		}
+		items := []ExportItem{{Title: "Draft"}, {Title: "Review"}}
+		if len(doc.Tasks) != 1 || !reflect.DeepEqual(doc.Tasks[0].Items, items) || !reflect.DeepEqual(doc.Tasks[0].Tags, []string{"alpha", "beta"}) {
+			t.Errorf("got %+v, want the Monday task with its checklist and tags", doc.Tasks)
+		}
+	})
+}
+
+func TestExportNDJSON(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		ids := createExportTasks(t, s)
+
+		var types []string
+		var taskIDs []int64
+		scanner := bufio.NewScanner(strings.NewReader(export(t, s, ExportRequest{Format: ExportNDJSON, Tasks: true, Categories: true})))
+		for scanner.Scan() {
+			var line struct {
+				Type string `json:"type"`
+				ID   int64  `json:"id"`
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				t.Fatalf("%v in %s", err, scanner.Text())
+			}
+			types = append(types, line.Type)
+			if line.Type == "task" {
+				taskIDs = append(taskIDs, line.ID)
+			}
+		}
+		want := []string{"category", "category", "category", "category", "task", "task", "task"}
+		if !reflect.DeepEqual(types, want) {
+			t.Errorf("got lines %q, want %q", types, want)
+		}
+		if !reflect.DeepEqual(taskIDs, ids) {
+			t.Errorf("got tasks %v, want %v", taskIDs, ids)
+		}
+	})
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	if err := Export(NewMemoryStore(), &bytes.Buffer{}, ExportRequest{Format: "xml", Tasks: true}); err == nil {
+		t.Error("got no error for an unknown format")
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// icsLineLimit is the longest content line RFC 5545 allows, in octets
+// without the line break
+const icsLineLimit = 75
+
+// icsProductID identifies the app as the producer of its calendars
+const icsProductID = "-//TodoLoggerApp//Tasks//EN"
+
+// icsWriter writes iCalendar content lines, folding long ones and ending
+// each with CRLF. The first error is kept and later writes are skipped.
+type icsWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// line writes "name:value", with value already escaped as needed
+func (iw *icsWriter) line(name, value string) {
+	if iw.err != nil {
+		return
+	}
+	_, iw.err = iw.w.WriteString(foldICSLine(name+":"+value) + "\r\n")
+}
+
+// foldICSLine breaks a content line into lines of at most 75 octets, each
+// continuation starting with a space. Multi-byte characters are never split.
+func foldICSLine(line string) string {
+	if len(line) <= icsLineLimit {
+		return line
+	}
+
+	folded := make([]byte, 0, len(line)+len(line)/icsLineLimit*3)
+	width := 0
+	for len(line) > 0 {
+		_, size := utf8.DecodeRuneInString(line)
+		if width+size > icsLineLimit {
+			folded = append(folded, "\r\n "...)
+			width = 1 // The leading space counts towards the next line
+		}
+		folded = append(folded, line[:size]...)
+		width += size
+		line = line[size:]
+	}
+	return string(folded)
+}
+
+// icsDate formats a YYYY-MM-DD date as a DATE value
+func icsDate(date string) string {
+	d, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return ""
+	}
+	return d.Format("20060102")
+}
+
+// icsTime formats an instant as a UTC DATE-TIME value
+func icsTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// icsPriority maps P0 to P3 onto iCalendar's 1 (highest) to 9 (lowest)
+func icsPriority(priority *string) string {
+	if priority == nil || len(*priority) != 2 {
+		return ""
+	}
+	level, err := strconv.Atoi((*priority)[1:])
+	if err != nil || level < 0 || level > 3 {
+		return ""
+	}
+	return strconv.Itoa(1 + 2*level)
+}
+
+// WriteTaskCalendar writes the tasks matching q as an iCalendar file with
+// one VTODO per task, streaming them from the store. UIDs come from the task
+// IDs so clients recognize a task across refreshes.
+func WriteTaskCalendar(s TaskStore, w io.Writer, q TaskQuery) error {
+	iw := &icsWriter{w: bufio.NewWriter(w)}
+
+	iw.line("BEGIN", "VCALENDAR")
+	iw.line("VERSION", "2.0")
+	iw.line("PRODID", icsProductID)
+	iw.line("CALSCALE", "GREGORIAN")
+	iw.line("X-WR-CALNAME", "Tasks")
+
+	err := s.EachTask(q, func(task Task) error {
+		iw.todo(task)
+		return iw.err
+	})
+	if err != nil {
+		return err
+	}
+
+	iw.line("END", "VCALENDAR")
+	if iw.err != nil {
+		return iw.err
+	}
+	return iw.w.Flush()
+}
+
+// todo writes a task as a VTODO. A due date is only written when it falls
+// after the assigned date, as DUE must come after DTSTART.
+func (iw *icsWriter) todo(task Task) {
+	iw.line("BEGIN", "VTODO")
+	iw.line("UID", fmt.Sprintf("task-%d@todologgerapp", task.ID))
+	iw.line("DTSTAMP", icsTime(task.UpdatedAt))
+	iw.line("CREATED", icsTime(task.CreatedAt))
+	iw.line("LAST-MODIFIED", icsTime(task.UpdatedAt))
+	iw.line("SUMMARY", escapeICSText(task.Title))
+	if task.Description != "" {
+		iw.line("DESCRIPTION", escapeICSText(task.Description))
+	}
+	iw.line("DTSTART;VALUE=DATE", icsDate(task.AssignedDate))
+	if task.DueDate != nil && *task.DueDate > task.AssignedDate {
+		iw.line("DUE;VALUE=DATE", icsDate(*task.DueDate))
+	}
+	if task.Category != nil {
+		iw.line("CATEGORIES", escapeICSText(task.Category.Name))
+	}
+	if p := icsPriority(task.Priority); p != "" {
+		iw.line("PRIORITY", p)
+	}
+
+	if task.IsCompleted {
+		iw.line("STATUS", "COMPLETED")
+		if task.CompletedDate != nil {
+			// COMPLETED is an instant; the day starts in the app's timezone
+			if day, err := time.ParseInLocation("2006-01-02", *task.CompletedDate, location); err == nil {
+				iw.line("COMPLETED", icsTime(day))
+			}
+		}
+		iw.line("PERCENT-COMPLETE", "100")
+	} else {
+		iw.line("STATUS", "NEEDS-ACTION")
+	}
+	iw.line("END", "VTODO")
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// checkFolded checks that folded is line split into lines of at most 75
+// octets of whole characters, each continuation starting with a space
+func checkFolded(t *testing.T, line, folded string) {
+	t.Helper()
+	for i, part := range strings.Split(folded, "\r\n") {
+		if len(part) > icsLineLimit {
+			t.Errorf("line %d is %d octets", i, len(part))
+		}
+		if !utf8.ValidString(part) {
+			t.Errorf("line %d splits a character: %q", i, part)
+		}
+		if i > 0 && !strings.HasPrefix(part, " ") {
+			t.Errorf("continuation %d does not start with a space: %q", i, part)
+		}
+	}
+	if unfolded := strings.ReplaceAll(folded, "\r\n ", ""); unfolded != line {
+		t.Errorf("unfolds to %q, want %q", unfolded, line)
+	}
+}
+
+func TestFoldICSLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		lines int
+	}{
+		{"short", "SUMMARY:Buy milk", 1},
+		{"exactly the limit", "SUMMARY:" + strings.Repeat("a", icsLineLimit-8), 1},
+		{"one octet over", "SUMMARY:" + strings.Repeat("a", icsLineLimit-7), 2},
+		// Continuations hold 74 octets after their leading space
+		{"long", "DESCRIPTION:" + strings.Repeat("b", 75+74+10), 3},
+		{"two-byte characters", "SUMMARY:" + strings.Repeat("é", 100), 3},
+		{"four-byte characters", "SUMMARY:x" + strings.Repeat("😀", 40), 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folded := foldICSLine(tt.line)
+			checkFolded(t, tt.line, folded)
+			if n := strings.Count(folded, "\r\n") + 1; n != tt.lines {
+				t.Errorf("got %d lines, want %d", n, tt.lines)
+			}
+		})
+	}
+}
+
+// Folded lines read back in through the holiday importer's unfolding
+func TestFoldICSLineUnfolds(t *testing.T) {
+	line := "SUMMARY:" + strings.Repeat("Überstunden abbauen, ", 12)
+	lines, err := unfoldICSLines(strings.NewReader(foldICSLine(line) + "\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 || lines[0] != line {
+		t.Errorf("got %q, want %q", lines, line)
+	}
+}
+
+func TestWriteTaskCalendar(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		title := "Plan the offsite; book rooms, caterers and travel for everyone on the team (again)"
+		p0, due := "P0", "2026-03-06"
+		task, err := s.CreateTask(TaskRequest{Title: title, Description: "Line one\nLine two", Date: testMonday, Priority: &p0, DueDate: &due}, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.UpdateTaskCompletion(mustCreateTask(t, s, "Done", testMonday).ID, true, testTuesday, "test"); err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		if err := WriteTaskCalendar(s, &buf, TaskQuery{Sort: "id"}); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		if !strings.HasSuffix(out, "END:VCALENDAR\r\n") || strings.Contains(strings.ReplaceAll(out, "\r\n", ""), "\n") {
+			t.Fatal("content lines must all end in CRLF")
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+			if len(line) > icsLineLimit {
+				t.Errorf("%q is %d octets", line, len(line))
+			}
+		}
+
+		lines, err := unfoldICSLines(strings.NewReader(out))
+		if err != nil {
+			t.Fatal(err)
+		}
+		props := map[string][]string{}
+		for _, line := range lines {
+			name, _, value := splitICSLine(line)
+			props[name] = append(props[name], value)
+		}
+
+		if got := props["SUMMARY"]; len(got) != 2 || unescapeICSText(got[0]) != title || got[0] == title {
+			t.Errorf("got summaries %q, want the title escaped", got)
+		}
+		if got := props["DESCRIPTION"]; len(got) != 1 || got[0] != `Line one\nLine two` {
+			t.Errorf("got descriptions %q", got)
+		}
+		if got := props["UID"]; len(got) != 2 || got[0] != fmt.Sprintf("task-%d@todologgerapp", task.ID) {
+			t.Errorf("got UIDs %q", got)
+		}
+		if got := props["DUE"]; len(got) != 1 || got[0] != "20260306" {
+			t.Errorf("got due dates %q, want 20260306", got)
+		}
+		if got := props["PRIORITY"]; len(got) != 1 || got[0] != "1" {
+			t.Errorf("got priorities %q, want 1", got)
+		}
+		if got := props["STATUS"]; len(got) != 2 || got[0] != "NEEDS-ACTION" || got[1] != "COMPLETED" {
+			t.Errorf("got statuses %q", got)
+		}
+	})
+}
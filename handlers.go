@@ -2,9 +2,13 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Response helpers
@@ -18,12 +22,20 @@ func respondError(w http.ResponseWriter, status int, message string) {
 	respondJSON(w, status, map[string]string{"error": message})
 }
 
+// requestActor identifies who made a change, from the X-Actor header
+func requestActor(r *http.Request) string {
+	if actor := strings.TrimSpace(r.Header.Get("X-Actor")); actor != "" {
+		return actor
+	}
+	return "api"
+}
+
 // CORS middleware
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Timezone, X-Actor")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -34,6 +46,19 @@ func corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// markOverdue flags the tasks still pending past their due date as of today.
+// Stores leave IsOverdue unset, as only the request knows what day it is.
+func markOverdue(tasks []Task, today string) {
+	for i := range tasks {
+		markTaskOverdue(&tasks[i], today)
+	}
+}
+
+// markTaskOverdue flags a task still pending past its due date as of today
+func markTaskOverdue(task *Task, today string) {
+	task.IsOverdue = isOverdue(*task, today)
+}
+
 // HandleCreateTask creates a new task
 func HandleCreateTask(w http.ResponseWriter, r *http.Request) {
 	var req TaskRequest
@@ -47,47 +72,122 @@ func HandleCreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if msg := normalizeTaskRequest(&req); msg != "" {
+		respondError(w, http.StatusBadRequest, msg)
+		return
+	}
+
 	if req.Date == "" {
-		req.Date = GetTodayIST()
+		req.Date = requestToday(r)
 	}
 
-	task, err := CreateTask(req.Title, req.Description, req.Date)
+	task, err := store.CreateTask(req, requestActor(r))
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	markTaskOverdue(task, requestToday(r))
 	respondJSON(w, http.StatusCreated, task)
 }
 
-// HandleGetTasks gets all tasks for a specific date
+// normalizePriority upper-cases an optional priority and reports whether it is valid
+func normalizePriority(priority *string) (*string, bool) {
+	if priority == nil || *priority == "" {
+		return priority, true
+	}
+	p := strings.ToUpper(strings.TrimSpace(*priority))
+	switch p {
+	case "P0", "P1", "P2", "P3":
+		return &p, true
+	}
+	return priority, false
+}
+
+// normalizeTaskRequest validates the optional priority and due date of a task
+// request, upper-casing the priority. It returns an error message or "".
+func normalizeTaskRequest(req *TaskRequest) string {
+	var ok bool
+	if req.Priority, ok = normalizePriority(req.Priority); !ok {
+		return "Priority must be one of P0, P1, P2, P3"
+	}
+
+	if req.DueDate != nil && *req.DueDate != "" && !isValidDate(*req.DueDate) {
+		return "Due date must be YYYY-MM-DD"
+	}
+
+	return ""
+}
+
+// HandleGetTasks gets all tasks for a specific date, or every overdue task
+// with ?overdue=true, optionally narrowed down by tag
 func HandleGetTasks(w http.ResponseWriter, r *http.Request) {
 	date := r.URL.Query().Get("date")
 	if date == "" {
-		date = GetTodayIST()
+		date = requestToday(r)
 	}
 
-	tasks, err := GetTasksByDate(date)
+	var tasks []Task
+	var err error
+	if r.URL.Query().Get("overdue") == "true" {
+		tasks, err = store.GetOverdueTasks(requestToday(r))
+	} else if _, err = MaterializeRecurring(store, date, requestToday(r)); err == nil {
+		tasks, err = store.GetTasksByDate(date)
+	}
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	names, matchAll, msg := parseTagFilter(r)
+	if msg != "" {
+		respondError(w, http.StatusBadRequest, msg)
+		return
+	}
+	tasks = FilterTasksByTags(tasks, names, matchAll)
+
 	if tasks == nil {
 		tasks = []Task{}
 	}
 
+	markOverdue(tasks, requestToday(r))
 	respondJSON(w, http.StatusOK, tasks)
 }
 
+// parseTagFilter reads tag names from repeated or comma-separated tag
+// parameters and tag_mode (and, the default, or or). It returns an error
+// message or "".
+func parseTagFilter(r *http.Request) (names []string, matchAll bool, msg string) {
+	for _, value := range r.URL.Query()["tag"] {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	switch strings.ToLower(r.URL.Query().Get("tag_mode")) {
+	case "", "and":
+		return names, true, ""
+	case "or":
+		return names, false, ""
+	}
+	return nil, false, "tag_mode must be and or or"
+}
+
 // HandleGetDailyLog gets the daily log for a specific date
 func HandleGetDailyLog(w http.ResponseWriter, r *http.Request) {
 	date := r.URL.Query().Get("date")
 	if date == "" {
-		date = GetTodayIST()
+		date = requestToday(r)
+	}
+
+	if _, err := MaterializeRecurring(store, date, requestToday(r)); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
 	}
 
-	log, err := GetDailyLog(date)
+	log, err := GetDailyLog(store, date)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -97,12 +197,13 @@ func HandleGetDailyLog(w http.ResponseWriter, r *http.Request) {
 		log.Tasks = []Task{}
 	}
 
+	markOverdue(log.Tasks, requestToday(r))
 	respondJSON(w, http.StatusOK, log)
 }
 
 // HandleGetAllDates gets all dates that have tasks
 func HandleGetAllDates(w http.ResponseWriter, r *http.Request) {
-	dates, err := GetAllDates()
+	dates, err := store.GetAllDates()
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -117,7 +218,7 @@ func HandleGetAllDates(w http.ResponseWriter, r *http.Request) {
 
 // HandleGetHistorySummaries gets completion stats for all dates
 func HandleGetHistorySummaries(w http.ResponseWriter, r *http.Request) {
-	summaries, err := GetHistorySummaries()
+	summaries, err := store.GetHistorySummaries()
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -134,7 +235,7 @@ func HandleGetHistorySummaries(w http.ResponseWriter, r *http.Request) {
 
 // HandleGetCategories gets all categories
 func HandleGetCategories(w http.ResponseWriter, r *http.Request) {
-	categories, err := GetAllCategories()
+	categories, err := store.GetAllCategories()
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -147,12 +248,40 @@ func HandleGetCategories(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, categories)
 }
 
+// categoryConflictMessage explains a name clash, which can also be with a
+// category in the trash
+const categoryConflictMessage = "A category with that name already exists (it may be in the trash)"
+
+// normalizeCategoryRequest validates a category's rollover policy, defaulting
+// to rolling over to the next day, and its stale thresholds. Only the archive
+// policy keeps a drag day limit. It returns an error message or "".
+func normalizeCategoryRequest(req *CategoryRequest) string {
+	switch req.RolloverPolicy {
+	case "":
+		req.RolloverPolicy = RolloverNextDay
+	case RolloverNextDay, RolloverNextBusinessDay, RolloverNextWeek, RolloverArchive, RolloverNever:
+	default:
+		return "Rollover policy must be one of next_day, next_business_day, next_week, archive, never"
+	}
+
+	if req.RolloverPolicy != RolloverArchive {
+		req.ArchiveAfterDays = nil
+	} else if req.ArchiveAfterDays == nil || *req.ArchiveAfterDays < 1 {
+		return "archive_after_days must be at least 1 for the archive policy"
+	}
+
+	if (req.StaleAfterDays != nil && *req.StaleAfterDays < 1) || (req.CriticalAfterDays != nil && *req.CriticalAfterDays < 1) {
+		return "stale_after_days and critical_after_days must be at least 1"
+	}
+	if req.StaleAfterDays != nil && req.CriticalAfterDays != nil && *req.CriticalAfterDays < *req.StaleAfterDays {
+		return "critical_after_days cannot be below stale_after_days"
+	}
+	return ""
+}
+
 // HandleCreateCategory creates a new category
 func HandleCreateCategory(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Name  string `json:"name"`
-		Color string `json:"color"`
-	}
+	var req CategoryRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
@@ -167,7 +296,16 @@ func HandleCreateCategory(w http.ResponseWriter, r *http.Request) {
 		req.Color = "#58a6ff" // default blue
 	}
 
-	category, err := CreateCategory(req.Name, req.Color)
+	if msg := normalizeCategoryRequest(&req); msg != "" {
+		respondError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	category, err := store.CreateCategory(req)
+	if err == ErrDuplicate {
+		respondError(w, http.StatusConflict, categoryConflictMessage)
+		return
+	}
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -185,17 +323,44 @@ func HandleUpdateCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req struct {
-		Name  string `json:"name"`
-		Color string `json:"color"`
+	existing, err := store.GetCategoryByID(id)
+	if err == ErrNotFound {
+		respondError(w, http.StatusNotFound, "Category not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Fields left out of the body keep their current values
+	req := CategoryRequest{
+		Name:              existing.Name,
+		Color:             existing.Color,
+		RolloverPolicy:    existing.RolloverPolicy,
+		ArchiveAfterDays:  existing.ArchiveAfterDays,
+		StaleAfterDays:    existing.StaleAfterDays,
+		CriticalAfterDays: existing.CriticalAfterDays,
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
-	category, err := UpdateCategory(id, req.Name, req.Color)
-	if err != nil {
+	if msg := normalizeCategoryRequest(&req); msg != "" {
+		respondError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	category, err := store.UpdateCategory(id, req)
+	switch {
+	case err == ErrNotFound:
+		respondError(w, http.StatusNotFound, "Category not found")
+		return
+	case err == ErrDuplicate:
+		respondError(w, http.StatusConflict, categoryConflictMessage)
+		return
+	case err != nil:
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -203,7 +368,7 @@ func HandleUpdateCategory(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, category)
 }
 
-// HandleDeleteCategory deletes a category
+// HandleDeleteCategory moves a category to the trash
 func HandleDeleteCategory(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/api/categories/")
 	id, err := strconv.ParseInt(path, 10, 64)
@@ -212,7 +377,12 @@ func HandleDeleteCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := DeleteCategory(id); err != nil {
+	err = store.DeleteCategory(id, requestActor(r))
+	if err == ErrNotFound {
+		respondError(w, http.StatusNotFound, "Category not found")
+		return
+	}
+	if err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -239,12 +409,24 @@ func HandleUpdateTaskCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task, err := UpdateTaskCategory(id, req.CategoryID)
+	if req.CategoryID != nil {
+		if _, err := store.GetCategoryByID(*req.CategoryID); err != nil {
+			respondError(w, http.StatusNotFound, "Category not found")
+			return
+		}
+	}
+
+	task, err := store.UpdateTaskCategory(id, req.CategoryID, requestActor(r))
+	if err == ErrNotFound {
+		respondError(w, http.StatusNotFound, "Task not found")
+		return
+	}
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	markTaskOverdue(task, requestToday(r))
 	respondJSON(w, http.StatusOK, task)
 }
 
@@ -258,7 +440,7 @@ func HandleGetTasksByCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tasks, err := GetTasksByCategory(id)
+	tasks, err := store.GetTasksByCategory(id)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -268,6 +450,7 @@ func HandleGetTasksByCategory(w http.ResponseWriter, r *http.Request) {
 		tasks = []Task{}
 	}
 
+	markOverdue(tasks, requestToday(r))
 	respondJSON(w, http.StatusOK, tasks)
 }
 
@@ -288,12 +471,17 @@ func HandleUpdateTaskCompletion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task, err := UpdateTaskCompletion(id, req.IsCompleted)
+	task, err := store.UpdateTaskCompletion(id, req.IsCompleted, requestToday(r), requestActor(r))
+	if err == ErrNotFound {
+		respondError(w, http.StatusNotFound, "Task not found")
+		return
+	}
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	markTaskOverdue(task, requestToday(r))
 	respondJSON(w, http.StatusOK, task)
 }
 
@@ -310,21 +498,42 @@ func HandleRollover(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	count, err := RolloverTasks(req.FromDate, req.ToDate)
+	moves, err := store.RolloverTasks(req.FromDate, req.ToDate, req.RolloverOptions, requestActor(r))
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"message":     "Tasks rolled over successfully",
-		"tasks_moved": count,
-		"from_date":   req.FromDate,
-		"to_date":     req.ToDate,
-	})
+	if !req.DryRun {
+		recordEscalations(store, requestActor(r))
+	}
+
+	resp := rolloverResponse("Tasks rolled over successfully", moves, req.DryRun)
+	resp["from_date"] = req.FromDate
+	resp["to_date"] = req.ToDate
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// rolloverResponse reports the tasks a rollover moved, or would move in a
+// dry run
+func rolloverResponse(message string, moves []RolloverMove, dryRun bool) map[string]interface{} {
+	if dryRun {
+		message = "Rollover preview; nothing was moved"
+	}
+	if moves == nil {
+		moves = []RolloverMove{}
+	}
+	rolled, archived := countRolloverMoves(moves)
+	return map[string]interface{}{
+		"message":        message,
+		"dry_run":        dryRun,
+		"tasks_moved":    rolled,
+		"tasks_archived": archived,
+		"tasks":          moves,
+	}
 }
 
-// HandleDeleteTask deletes a task
+// HandleDeleteTask moves a task to the trash
 func HandleDeleteTask(w http.ResponseWriter, r *http.Request) {
 	// Extract ID from URL path: /api/tasks/{id}
 	path := strings.TrimPrefix(r.URL.Path, "/api/tasks/")
@@ -334,7 +543,12 @@ func HandleDeleteTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := DeleteTask(id); err != nil {
+	err = store.DeleteTask(id, requestActor(r))
+	if err == ErrNotFound {
+		respondError(w, http.StatusNotFound, "Task not found")
+		return
+	}
+	if err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -342,7 +556,7 @@ func HandleDeleteTask(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, map[string]string{"message": "Task deleted successfully"})
 }
 
-// HandleUpdateTask updates a task's title and description
+// HandleUpdateTask updates a task's title, description, priority and due date
 func HandleUpdateTask(w http.ResponseWriter, r *http.Request) {
 	// Extract ID from URL path: /api/tasks/{id}
 	path := strings.TrimPrefix(r.URL.Path, "/api/tasks/")
@@ -358,12 +572,22 @@ func HandleUpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task, err := UpdateTask(id, req.Title, req.Description)
+	if msg := normalizeTaskRequest(&req); msg != "" {
+		respondError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	task, err := store.UpdateTask(id, req, requestActor(r))
+	if err == ErrNotFound {
+		respondError(w, http.StatusNotFound, "Task not found")
+		return
+	}
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	markTaskOverdue(task, requestToday(r))
 	respondJSON(w, http.StatusOK, task)
 }
 
@@ -375,16 +599,20 @@ func HandleGetHistoricalLog(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log, err := GetHistoricalLog(date)
+	if !isValidDate(date) {
+		respondError(w, http.StatusBadRequest, "Date must be YYYY-MM-DD")
+		return
+	}
+
+	log, err := GetHistoricalLog(store, date, requestLocation(r))
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	if log.Tasks == nil {
-		log.Tasks = []Task{}
+	for i := range log.Tasks {
+		markTaskOverdue(&log.Tasks[i].Task, requestToday(r))
 	}
-
 	respondJSON(w, http.StatusOK, log)
 }
 
@@ -398,47 +626,1643 @@ func HandleGetTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task, err := GetTaskByID(id)
+	task, err := store.GetTaskByID(id)
 	if err != nil {
 		respondError(w, http.StatusNotFound, "Task not found")
 		return
 	}
 
+	markTaskOverdue(task, requestToday(r))
 	respondJSON(w, http.StatusOK, task)
 }
 
-// HandleAutoRollover automatically rolls over incomplete tasks from yesterday to today
-func HandleAutoRollover(w http.ResponseWriter, r *http.Request) {
-	today := GetTodayIST()
-	yesterday := GetYesterdayIST()
+// HandleGetTaskHistory gets the full event timeline of a task
+func HandleGetTaskHistory(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from URL path: /api/tasks/{id}/history
+	path := strings.TrimPrefix(r.URL.Path, "/api/tasks/")
+	path = strings.TrimSuffix(path, "/history")
+	id, err := strconv.ParseInt(path, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid task ID")
+		return
+	}
+
+	events, err := store.GetTaskHistory(id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if len(events) == 0 {
+		if _, err := store.GetTaskByID(id); err != nil {
+			respondError(w, http.StatusNotFound, "Task not found")
+			return
+		}
+		events = []TaskEvent{}
+	}
+
+	respondJSON(w, http.StatusOK, events)
+}
+
+// Checklist item handlers
+
+// parseTaskItemPath extracts the task ID and optional item ID from
+// /api/tasks/{id}/items[/{itemID}]
+func parseTaskItemPath(urlPath string) (taskID, itemID int64, err error) {
+	path := strings.TrimPrefix(urlPath, "/api/tasks/")
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+
+	taskID, err = strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(parts) > 2 {
+		itemID, err = strconv.ParseInt(parts[2], 10, 64)
+	}
+	return taskID, itemID, err
+}
+
+// HandleGetTaskItems gets a task's checklist in order
+func HandleGetTaskItems(w http.ResponseWriter, r *http.Request) {
+	taskID, _, err := parseTaskItemPath(r.URL.Path)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid task ID")
+		return
+	}
+
+	if _, err := store.GetTaskByID(taskID); err != nil {
+		respondError(w, http.StatusNotFound, "Task not found")
+		return
+	}
+
+	items, err := store.GetTaskItems(taskID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if items == nil {
+		items = []TaskItem{}
+	}
+
+	respondJSON(w, http.StatusOK, items)
+}
+
+// HandleCreateTaskItem appends an item to a task's checklist
+func HandleCreateTaskItem(w http.ResponseWriter, r *http.Request) {
+	taskID, _, err := parseTaskItemPath(r.URL.Path)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid task ID")
+		return
+	}
+
+	var req TaskItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Title == nil || strings.TrimSpace(*req.Title) == "" {
+		respondError(w, http.StatusBadRequest, "Title is required")
+		return
+	}
+
+	item, err := store.CreateTaskItem(taskID, *req.Title, requestActor(r))
+	if err == ErrNotFound {
+		respondError(w, http.StatusNotFound, "Task not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Placing an item at a given position is a move after appending it
+	if req.Position != nil {
+		item, err = store.UpdateTaskItem(taskID, item.ID, TaskItemRequest{Position: req.Position}, requestActor(r))
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	respondJSON(w, http.StatusCreated, item)
+}
+
+// HandleUpdateTaskItem renames, checks off or moves a checklist item. Checking
+// off the last open item completes the parent task.
+func HandleUpdateTaskItem(w http.ResponseWriter, r *http.Request) {
+	taskID, itemID, err := parseTaskItemPath(r.URL.Path)
+	if err != nil || itemID == 0 {
+		respondError(w, http.StatusBadRequest, "Invalid item ID")
+		return
+	}
+
+	var req TaskItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Title != nil && strings.TrimSpace(*req.Title) == "" {
+		respondError(w, http.StatusBadRequest, "Title cannot be empty")
+		return
+	}
+
+	item, err := store.UpdateTaskItem(taskID, itemID, req, requestActor(r))
+	if err == ErrNotFound {
+		respondError(w, http.StatusNotFound, "Item not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if req.IsDone != nil && *req.IsDone {
+		if _, err := CompleteIfChecklistDone(store, taskID, requestToday(r), requestActor(r)); err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	respondJSON(w, http.StatusOK, item)
+}
+
+// HandleDeleteTaskItem removes a checklist item. Removing the last open item
+// completes the parent task.
+func HandleDeleteTaskItem(w http.ResponseWriter, r *http.Request) {
+	taskID, itemID, err := parseTaskItemPath(r.URL.Path)
+	if err != nil || itemID == 0 {
+		respondError(w, http.StatusBadRequest, "Invalid item ID")
+		return
+	}
 
-	count, err := RolloverTasks(yesterday, today)
+	err = store.DeleteTaskItem(taskID, itemID, requestActor(r))
+	if err == ErrNotFound {
+		respondError(w, http.StatusNotFound, "Item not found")
+		return
+	}
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	if _, err := CompleteIfChecklistDone(store, taskID, requestToday(r), requestActor(r)); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Item deleted successfully"})
+}
+
+// HandleAutoRollover automatically rolls over incomplete tasks from the previous
+// working day (and any non-working days since) to today
+func HandleAutoRollover(w http.ResponseWriter, r *http.Request) {
+	today := requestToday(r)
+	fromDate := calendar.PreviousWorkingDay(today)
+
+	moved, archived := 0, 0
+	for date := fromDate; date < today; date = AddDays(date, 1) {
+		moves, err := store.RolloverTasks(date, today, RolloverOptions{}, requestActor(r))
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		rolled, gone := countRolloverMoves(moves)
+		moved += rolled
+		archived += gone
+	}
+	recordEscalations(store, requestActor(r))
+
 	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"message":     "Auto rollover completed",
-		"tasks_moved": count,
-		"from_date":   yesterday,
-		"to_date":     today,
+		"message":        "Auto rollover completed",
+		"tasks_moved":    moved,
+		"tasks_archived": archived,
+		"from_date":      fromDate,
+		"to_date":        today,
 	})
 }
 
-// HandleRolloverAll rolls over ALL incomplete tasks from any past date to today
+// HandleRolloverAll rolls over ALL incomplete tasks from any past date to
+// today. An optional body narrows the tasks or asks for a dry run.
 func HandleRolloverAll(w http.ResponseWriter, r *http.Request) {
-	today := GetTodayIST()
+	today := requestToday(r)
+
+	var opts RolloverOptions
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
 
-	count, err := RolloverAllPendingTasks(today)
+	moves, err := store.RolloverAllPendingTasks(today, opts, requestActor(r))
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	if !opts.DryRun {
+		recordEscalations(store, requestActor(r))
+	}
+
+	resp := rolloverResponse("All pending tasks rolled over to today", moves, opts.DryRun)
+	resp["to_date"] = today
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// HandleGetClock reports the server's current time, today's date and timezone
+func HandleGetClock(w http.ResponseWriter, r *http.Request) {
+	loc := requestLocation(r)
+	_, simulated := clock.(*SimulatedClock)
+
 	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"message":     "All pending tasks rolled over to today",
-		"tasks_moved": count,
-		"to_date":     today,
+		"now":       NowIn(loc).Format(time.RFC3339),
+		"today":     TodayIn(loc),
+		"timezone":  loc.String(),
+		"simulated": simulated,
 	})
 }
+
+// HandleSetClock moves a simulated clock to another date
+func HandleSetClock(w http.ResponseWriter, r *http.Request) {
+	simulated, ok := clock.(*SimulatedClock)
+	if !ok {
+		respondError(w, http.StatusConflict, "Clock is not simulated; start the server with -simulate-date")
+		return
+	}
+
+	var req struct {
+		Date string `json:"date"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := simulated.SetDate(req.Date, requestLocation(r)); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid date, expected YYYY-MM-DD")
+		return
+	}
+
+	HandleGetClock(w, r)
+}
+
+// HandleGetRolloverRuns lists recent scheduled rollover runs
+func HandleGetRolloverRuns(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 {
+			respondError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	runs, err := store.GetRolloverRuns(limit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if runs == nil {
+		runs = []RolloverRun{}
+	}
+
+	respondJSON(w, http.StatusOK, runs)
+}
+
+// Holiday handlers
+
+// HandleGetHolidays lists holidays, optionally limited by from/to dates
+func HandleGetHolidays(w http.ResponseWriter, r *http.Request) {
+	holidays, err := store.GetHolidays(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if holidays == nil {
+		holidays = []Holiday{}
+	}
+
+	respondJSON(w, http.StatusOK, holidays)
+}
+
+// HandleCreateHoliday adds a holiday
+func HandleCreateHoliday(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Date string `json:"date"`
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if !isValidDate(req.Date) {
+		respondError(w, http.StatusBadRequest, "Date must be YYYY-MM-DD")
+		return
+	}
+
+	holiday, err := store.CreateHoliday(req.Date, req.Name)
+	if err == ErrDuplicate {
+		respondError(w, http.StatusConflict, "A holiday on that date already exists")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := reloadHolidays(store); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, holiday)
+}
+
+// HandleUpdateHoliday changes a holiday's date or name
+func HandleUpdateHoliday(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/holidays/")
+	id, err := strconv.ParseInt(path, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid holiday ID")
+		return
+	}
+
+	var req struct {
+		Date string `json:"date"`
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if !isValidDate(req.Date) {
+		respondError(w, http.StatusBadRequest, "Date must be YYYY-MM-DD")
+		return
+	}
+
+	holiday, err := store.UpdateHoliday(id, req.Date, req.Name)
+	switch {
+	case err == ErrNotFound:
+		respondError(w, http.StatusNotFound, "Holiday not found")
+		return
+	case err == ErrDuplicate:
+		respondError(w, http.StatusConflict, "A holiday on that date already exists")
+		return
+	case err != nil:
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := reloadHolidays(store); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, holiday)
+}
+
+// HandleDeleteHoliday removes a holiday
+func HandleDeleteHoliday(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/holidays/")
+	id, err := strconv.ParseInt(path, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid holiday ID")
+		return
+	}
+
+	if err := store.DeleteHoliday(id); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := reloadHolidays(store); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Holiday deleted successfully"})
+}
+
+// HandleImportHolidays imports holidays from an iCalendar (.ics) request body
+func HandleImportHolidays(w http.ResponseWriter, r *http.Request) {
+	holidays, err := ParseICS(r.Body)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid iCalendar file: "+err.Error())
+		return
+	}
+
+	created, skipped, err := ImportHolidays(store, holidays)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := reloadHolidays(store); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Holidays imported",
+		"created": created,
+		"skipped": skipped,
+	})
+}
+
+// Recurring task handlers
+
+// normalizeRecurringTask validates a recurring task definition and fills in
+// defaults. It returns an error message or "".
+func normalizeRecurringTask(rt *RecurringTask, today string) string {
+	if strings.TrimSpace(rt.Title) == "" {
+		return "Title is required"
+	}
+
+	if _, err := ParseRRule(rt.Rule); err != nil {
+		return "Invalid rule: " + err.Error()
+	}
+
+	if rt.StartDate == "" {
+		rt.StartDate = today
+	}
+	if !isValidDate(rt.StartDate) {
+		return "Start date must be YYYY-MM-DD"
+	}
+
+	rt.EndDate = emptyToNil(rt.EndDate)
+	if rt.EndDate != nil && (!isValidDate(*rt.EndDate) || *rt.EndDate < rt.StartDate) {
+		return "End date must be YYYY-MM-DD and not before the start date"
+	}
+
+	switch rt.MissedPolicy {
+	case "":
+		rt.MissedPolicy = MissedRoll
+	case MissedRoll, MissedSkip:
+	default:
+		return "Missed policy must be roll or skip"
+	}
+
+	var ok bool
+	if rt.Priority, ok = normalizePriority(rt.Priority); !ok {
+		return "Priority must be one of P0, P1, P2, P3"
+	}
+	rt.Priority = emptyToNil(rt.Priority)
+
+	return ""
+}
+
+// HandleGetRecurringTasks lists every recurring task template
+func HandleGetRecurringTasks(w http.ResponseWriter, r *http.Request) {
+	templates, err := store.GetRecurringTasks()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if templates == nil {
+		templates = []RecurringTask{}
+	}
+
+	respondJSON(w, http.StatusOK, templates)
+}
+
+// HandleGetRecurringTask gets a single recurring task template
+func HandleGetRecurringTask(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/recurring/")
+	id, err := strconv.ParseInt(path, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid recurring task ID")
+		return
+	}
+
+	rt, err := store.GetRecurringTaskByID(id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Recurring task not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, rt)
+}
+
+// HandleCreateRecurringTask creates a recurring task template and materializes
+// today's occurrence. Occurrences before today are never generated for a new
+// template.
+func HandleCreateRecurringTask(w http.ResponseWriter, r *http.Request) {
+	var req RecurringTask
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	today := requestToday(r)
+	if msg := normalizeRecurringTask(&req, today); msg != "" {
+		respondError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	req.GeneratedThrough = nil
+	if req.StartDate < today {
+		yesterday := AddDays(today, -1)
+		req.GeneratedThrough = &yesterday
+	}
+
+	rt, err := store.CreateRecurringTask(req)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if _, err := MaterializeRecurring(store, today, today); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, rt)
+}
+
+// HandleUpdateRecurringTask replaces a recurring task's definition. Tasks it
+// already generated are not changed.
+func HandleUpdateRecurringTask(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/recurring/")
+	id, err := strconv.ParseInt(path, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid recurring task ID")
+		return
+	}
+
+	var req RecurringTask
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if msg := normalizeRecurringTask(&req, requestToday(r)); msg != "" {
+		respondError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	req.ID = id
+	rt, err := store.UpdateRecurringTask(req)
+	if err == ErrNotFound {
+		respondError(w, http.StatusNotFound, "Recurring task not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, rt)
+}
+
+// HandleDeleteRecurringTask deletes a recurring task template; the tasks it
+// generated are kept
+func HandleDeleteRecurringTask(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/recurring/")
+	id, err := strconv.ParseInt(path, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid recurring task ID")
+		return
+	}
+
+	if err := store.DeleteRecurringTask(id); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Recurring task deleted successfully"})
+}
+
+// Tag handlers
+
+// tagRequest creates or edits a tag
+type tagRequest struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// normalize trims the name and fills in the default color. It returns an
+// error message or "".
+func (req *tagRequest) normalize() string {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return "Name is required"
+	}
+	if strings.Contains(req.Name, ",") {
+		return "Tag names cannot contain commas"
+	}
+	if req.Color == "" {
+		req.Color = "#8b949e" // default gray
+	}
+	return ""
+}
+
+// HandleGetTags gets all tags
+func HandleGetTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := store.GetAllTags()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if tags == nil {
+		tags = []Tag{}
+	}
+
+	respondJSON(w, http.StatusOK, tags)
+}
+
+// HandleCreateTag creates a new tag
+func HandleCreateTag(w http.ResponseWriter, r *http.Request) {
+	var req tagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if msg := req.normalize(); msg != "" {
+		respondError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	tag, err := store.CreateTag(req.Name, req.Color)
+	if err == ErrDuplicate {
+		respondError(w, http.StatusConflict, "A tag with that name already exists")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, tag)
+}
+
+// HandleUpdateTag renames or recolors a tag
+func HandleUpdateTag(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/tags/")
+	id, err := strconv.ParseInt(path, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid tag ID")
+		return
+	}
+
+	var req tagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if msg := req.normalize(); msg != "" {
+		respondError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	tag, err := store.UpdateTag(id, req.Name, req.Color)
+	switch {
+	case err == ErrNotFound:
+		respondError(w, http.StatusNotFound, "Tag not found")
+		return
+	case err == ErrDuplicate:
+		respondError(w, http.StatusConflict, "A tag with that name already exists")
+		return
+	case err != nil:
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, tag)
+}
+
+// HandleDeleteTag deletes a tag and removes it from every task
+func HandleDeleteTag(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/tags/")
+	id, err := strconv.ParseInt(path, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid tag ID")
+		return
+	}
+
+	if err := store.DeleteTag(id, requestActor(r)); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Tag deleted successfully"})
+}
+
+// parseTaskTagPath extracts the task ID and optional tag ID from
+// /api/tasks/{id}/tags[/{tagID}]
+func parseTaskTagPath(urlPath string) (taskID, tagID int64, err error) {
+	path := strings.TrimPrefix(urlPath, "/api/tasks/")
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+
+	taskID, err = strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(parts) > 2 {
+		tagID, err = strconv.ParseInt(parts[2], 10, 64)
+	}
+	return taskID, tagID, err
+}
+
+// HandleGetTaskTags gets the tags on a task
+func HandleGetTaskTags(w http.ResponseWriter, r *http.Request) {
+	taskID, _, err := parseTaskTagPath(r.URL.Path)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid task ID")
+		return
+	}
+
+	task, err := store.GetTaskByID(taskID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Task not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, task.Tags)
+}
+
+// HandleAddTaskTag puts a tag on a task, by tag_id or by name. A name that
+// doesn't match an existing tag creates it.
+func HandleAddTaskTag(w http.ResponseWriter, r *http.Request) {
+	taskID, _, err := parseTaskTagPath(r.URL.Path)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid task ID")
+		return
+	}
+
+	var req struct {
+		TagID *int64 `json:"tag_id"`
+		tagRequest
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	tagID := int64(0)
+	if req.TagID != nil {
+		tagID = *req.TagID
+	} else {
+		if msg := req.normalize(); msg != "" {
+			respondError(w, http.StatusBadRequest, msg)
+			return
+		}
+		tag, err := store.GetTagByName(req.Name)
+		if err == ErrNotFound {
+			tag, err = store.CreateTag(req.Name, req.Color)
+		}
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		tagID = tag.ID
+	}
+
+	task, err := store.AddTaskTag(taskID, tagID, requestActor(r))
+	if err == ErrNotFound {
+		respondError(w, http.StatusNotFound, "Task or tag not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	markTaskOverdue(task, requestToday(r))
+	respondJSON(w, http.StatusOK, task)
+}
+
+// HandleRemoveTaskTag takes a tag off a task
+func HandleRemoveTaskTag(w http.ResponseWriter, r *http.Request) {
+	taskID, tagID, err := parseTaskTagPath(r.URL.Path)
+	if err != nil || tagID == 0 {
+		respondError(w, http.StatusBadRequest, "Invalid tag ID")
+		return
+	}
+
+	task, err := store.RemoveTaskTag(taskID, tagID, requestActor(r))
+	if err == ErrNotFound {
+		respondError(w, http.StatusNotFound, "Task or tag not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	markTaskOverdue(task, requestToday(r))
+	respondJSON(w, http.StatusOK, task)
+}
+
+// HandleSearch runs a full-text search over task titles and descriptions.
+// Quoted phrases and prefix* terms are supported; completed, category_id,
+// from, to and limit narrow the results.
+func HandleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := SearchQuery{
+		Text:     strings.TrimSpace(query.Get("q")),
+		FromDate: query.Get("from"),
+		ToDate:   query.Get("to"),
+		Limit:    50,
+	}
+
+	if q.Text == "" {
+		respondError(w, http.StatusBadRequest, "Query is required")
+		return
+	}
+
+	if v := query.Get("completed"); v != "" {
+		completed, err := strconv.ParseBool(v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "completed must be true or false")
+			return
+		}
+		q.Completed = &completed
+	}
+
+	if v := query.Get("category_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid category ID")
+			return
+		}
+		q.CategoryID = &id
+	}
+
+	if (q.FromDate != "" && !isValidDate(q.FromDate)) || (q.ToDate != "" && !isValidDate(q.ToDate)) {
+		respondError(w, http.StatusBadRequest, "Dates must be YYYY-MM-DD")
+		return
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > 200 {
+			respondError(w, http.StatusBadRequest, "limit must be between 1 and 200")
+			return
+		}
+		q.Limit = limit
+	}
+
+	results, err := store.SearchTasks(q)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if results == nil {
+		results = []SearchResult{}
+	}
+
+	for i := range results {
+		markTaskOverdue(&results[i].Task, requestToday(r))
+	}
+	respondJSON(w, http.StatusOK, results)
+}
+
+// HandleQueryTasks returns a page of tasks matching any combination of
+// filters, e.g. ?category_id=1&min_drag_days=5&sort=drag_days&order=desc
+func HandleQueryTasks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := TaskQuery{
+		CreatedFrom:   query.Get("created_from"),
+		CreatedTo:     query.Get("created_to"),
+		AssignedFrom:  query.Get("assigned_from"),
+		AssignedTo:    query.Get("assigned_to"),
+		CompletedFrom: query.Get("completed_from"),
+		CompletedTo:   query.Get("completed_to"),
+		Text:          strings.TrimSpace(query.Get("q")),
+		Sort:          "id",
+		Limit:         50,
+	}
+
+	for _, date := range []string{q.CreatedFrom, q.CreatedTo, q.AssignedFrom, q.AssignedTo, q.CompletedFrom, q.CompletedTo} {
+		if date != "" && !isValidDate(date) {
+			respondError(w, http.StatusBadRequest, "Dates must be YYYY-MM-DD")
+			return
+		}
+	}
+
+	if v := query.Get("completed"); v != "" {
+		completed, err := strconv.ParseBool(v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "completed must be true or false")
+			return
+		}
+		q.Completed = &completed
+	}
+
+	for _, value := range query["category_id"] {
+		for _, v := range strings.Split(value, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "Invalid category ID")
+				return
+			}
+			q.CategoryIDs = append(q.CategoryIDs, id)
+		}
+	}
+
+	for name, bound := range map[string]**int{"min_drag_days": &q.MinDragDays, "max_drag_days": &q.MaxDragDays} {
+		if v := query.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				respondError(w, http.StatusBadRequest, name+" must be a non-negative number")
+				return
+			}
+			*bound = &n
+		}
+	}
+
+	if v := query.Get("sort"); v != "" {
+		if !taskSortFields[v] {
+			respondError(w, http.StatusBadRequest, "Unknown sort field "+v)
+			return
+		}
+		q.Sort = v
+	}
+
+	switch strings.ToLower(query.Get("order")) {
+	case "", "asc":
+	case "desc":
+		q.Descending = true
+	default:
+		respondError(w, http.StatusBadRequest, "order must be asc or desc")
+		return
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > 200 {
+			respondError(w, http.StatusBadRequest, "limit must be between 1 and 200")
+			return
+		}
+		q.Limit = limit
+	}
+
+	if v := query.Get("cursor"); v != "" {
+		cursor, err := DecodeTaskCursor(v, q)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		q.After = cursor
+	}
+
+	page, err := store.QueryTasks(q)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	markOverdue(page.Tasks, requestToday(r))
+	respondJSON(w, http.StatusOK, page)
+}
+
+// maxBulkItems caps how many task operations one bulk request may carry
+const maxBulkItems = 1000
+
+// HandleBulkTasks applies a list of operations to sets of tasks in one
+// transaction and reports the outcome for every task
+func HandleBulkTasks(w http.ResponseWriter, r *http.Request) {
+	var req BulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if len(req.Operations) == 0 {
+		respondError(w, http.StatusBadRequest, "At least one operation is required")
+		return
+	}
+
+	items := 0
+	for i := range req.Operations {
+		op := &req.Operations[i]
+		if msg := normalizeBulkOperation(op, requestToday(r)); msg != "" {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Operation %d: %s", i, msg))
+			return
+		}
+		items += len(op.TaskIDs)
+	}
+	if items > maxBulkItems {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("A bulk request can touch at most %d tasks", maxBulkItems))
+		return
+	}
+
+	results, err := store.BulkUpdateTasks(req.Operations, req.AllOrNothing, requestActor(r))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := BulkResponse{Committed: true, Results: results}
+	for _, result := range results {
+		if result.Task != nil {
+			markTaskOverdue(result.Task, requestToday(r))
+		}
+		if result.OK {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+		}
+	}
+
+	// A rolled-back request kept nothing, so every item reports failure
+	status := http.StatusOK
+	if req.AllOrNothing && resp.Failed > 0 {
+		resp.Committed = false
+		status = http.StatusUnprocessableEntity
+	}
+
+	respondJSON(w, status, resp)
+}
+
+// normalizeBulkOperation validates an operation and fills in defaults,
+// returning a message describing the first problem found
+func normalizeBulkOperation(op *BulkOperation, today string) string {
+	if len(op.TaskIDs) == 0 {
+		return "task_ids is required"
+	}
+
+	switch op.Op {
+	case BulkUncomplete, BulkDelete:
+		return ""
+
+	case BulkComplete:
+		if op.Date == "" {
+			op.Date = today
+		}
+		if !isValidDate(op.Date) {
+			return "date must be YYYY-MM-DD"
+		}
+		return ""
+
+	case BulkMoveToDate:
+		if !isValidDate(op.Date) {
+			return "date is required as YYYY-MM-DD"
+		}
+		return ""
+
+	case BulkSetCategory:
+		if op.CategoryID != nil {
+			if _, err := store.GetCategoryByID(*op.CategoryID); err != nil {
+				return "Category not found"
+			}
+		}
+		return ""
+
+	case BulkUpdate:
+		f := &op.Fields
+		if f.Title == nil && f.Description == nil && f.Priority == nil && f.DueDate == nil {
+			return "fields must set at least one of title, description, priority, due_date"
+		}
+		if f.Title != nil && strings.TrimSpace(*f.Title) == "" {
+			return "Title cannot be empty"
+		}
+		var ok bool
+		if f.Priority, ok = normalizePriority(f.Priority); !ok {
+			return "Priority must be one of P0, P1, P2, P3"
+		}
+		if f.DueDate != nil && *f.DueDate != "" && !isValidDate(*f.DueDate) {
+			return "Due date must be YYYY-MM-DD"
+		}
+		return ""
+	}
+
+	return fmt.Sprintf("unknown op %q", op.Op)
+}
+
+// Trash and journal handlers
+
+// HandleGetTrash lists the deleted tasks and categories
+func HandleGetTrash(w http.ResponseWriter, r *http.Request) {
+	trash, err := store.GetTrash()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	markOverdue(trash.Tasks, requestToday(r))
+	respondJSON(w, http.StatusOK, trash)
+}
+
+// HandleEmptyTrash permanently deletes everything in the trash
+func HandleEmptyTrash(w http.ResponseWriter, r *http.Request) {
+	purged, err := store.EmptyTrash()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]int{"purged": purged})
+}
+
+// HandleRestoreFromTrash restores a task or category:
+// /api/trash/tasks/{id}/restore or /api/trash/categories/{id}/restore
+func HandleRestoreFromTrash(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/trash/"), "/"), "/")
+	if len(parts) != 3 || parts[2] != "restore" {
+		respondError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	switch parts[0] {
+	case "tasks":
+		task, err := store.RestoreTask(id, requestActor(r))
+		if err == ErrNotFound {
+			respondError(w, http.StatusNotFound, "Task not found in the trash")
+			return
+		}
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		markTaskOverdue(task, requestToday(r))
+		respondJSON(w, http.StatusOK, task)
+
+	case "categories":
+		category, err := store.RestoreCategory(id, requestActor(r))
+		if err == ErrNotFound {
+			respondError(w, http.StatusNotFound, "Category not found in the trash")
+			return
+		}
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondJSON(w, http.StatusOK, category)
+
+	default:
+		respondError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// HandleGetJournal lists the most recent operations that can be undone or
+// redone
+func HandleGetJournal(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 {
+			respondError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	operations, err := store.GetOperations(limit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if operations == nil {
+		operations = []Operation{}
+	}
+
+	respondJSON(w, http.StatusOK, operations)
+}
+
+// HandleUndo reverts the most recent operations, one step unless the body
+// asks for more
+func HandleUndo(w http.ResponseWriter, r *http.Request) {
+	replayOperations(w, r, store.Undo, "Nothing to undo")
+}
+
+// HandleRedo reapplies the operations undone last, one step unless the body
+// asks for more
+func HandleRedo(w http.ResponseWriter, r *http.Request) {
+	replayOperations(w, r, store.Redo, "Nothing to redo")
+}
+
+// replayOperations runs step up to {"steps": n} times and responds with the
+// operations it replayed, stopping early when the journal runs out
+func replayOperations(w http.ResponseWriter, r *http.Request, step func(actor string) (*Operation, error), nothing string) {
+	req := struct {
+		Steps int `json:"steps"`
+	}{Steps: 1}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	if req.Steps < 1 || req.Steps > journalSize {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("steps must be between 1 and %d", journalSize))
+		return
+	}
+
+	operations := []Operation{}
+	for i := 0; i < req.Steps; i++ {
+		op, err := step(requestActor(r))
+		if err == ErrNotFound {
+			break
+		}
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		operations = append(operations, *op)
+	}
+
+	if len(operations) == 0 {
+		respondError(w, http.StatusConflict, nothing)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string][]Operation{"operations": operations})
+}
+
+// Stale task handlers
+
+// HandleGetStaleTasks lists pending tasks dragged past their stale threshold,
+// critical ones first and then the longest dragged. ?level=critical leaves
+// out tasks that are only stale and category_id narrows them down. Any
+// escalations still due are recorded first.
+func HandleGetStaleTasks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	level := query.Get("level")
+	switch level {
+	case "":
+		level = LevelStale
+	case LevelStale, LevelCritical:
+	default:
+		respondError(w, http.StatusBadRequest, "level must be stale or critical")
+		return
+	}
+
+	pending := false
+	q := TaskQuery{Completed: &pending, Sort: "drag_days", Descending: true}
+	if v := query.Get("category_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid category ID")
+			return
+		}
+		q.CategoryIDs = []int64{id}
+	}
+
+	if _, err := store.RecordEscalations(requestActor(r)); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Only read the tasks dragged long enough to have reached the level in
+	// some category
+	categories, err := store.GetAllCategories()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	fewest := fewestDragDays(categories, level)
+	q.MinDragDays = &fewest
+
+	critical, stale := []Task{}, []Task{}
+	err = store.EachTask(q, func(task Task) error {
+		switch {
+		case task.IsCritical:
+			critical = append(critical, task)
+		case task.IsStale && level == LevelStale:
+			stale = append(stale, task)
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	tasks := append(critical, stale...)
+
+	markOverdue(tasks, requestToday(r))
+	respondJSON(w, http.StatusOK, tasks)
+}
+
+// HandleGetStaleReport reports how many tasks escalated to each stale level
+// between ?from and ?to (the last 30 days by default) and what became of
+// them: completed, deleted or still dragging
+func HandleGetStaleReport(w http.ResponseWriter, r *http.Request) {
+	today := requestToday(r)
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if to == "" {
+		to = today
+	}
+	if from == "" {
+		from = AddDays(to, -29)
+	}
+	if !isValidDate(from) || !isValidDate(to) || from > to {
+		respondError(w, http.StatusBadRequest, "from and to must be YYYY-MM-DD with from not after to")
+		return
+	}
+
+	if _, err := store.RecordEscalations(requestActor(r)); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	start, end, err := localDayBounds(from, to)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	escalations, err := store.GetEscalations(start, end)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, summarizeEscalations(from, to, escalations))
+}
+
+// Report handlers
+
+// HandleGetReport builds a productivity report for ?period=week|month (week
+// by default) containing ?start, which defaults to today. ?top caps the most
+// dragged tasks listed.
+func HandleGetReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	period := query.Get("period")
+	if period == "" {
+		period = PeriodWeek
+	}
+	start := query.Get("start")
+	if start == "" {
+		start = requestToday(r)
+	}
+
+	top := reportTopDragged
+	if v := query.Get("top"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > 50 {
+			respondError(w, http.StatusBadRequest, "top must be between 0 and 50")
+			return
+		}
+		top = n
+	}
+
+	if _, _, err := reportPeriod(period, start); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := BuildReport(store, period, start, top)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
+
+// HandleGetStandup builds the standup for ?date (today by default) as
+// ?format=markdown (the default), text or json
+func HandleGetStandup(w http.ResponseWriter, r *http.Request) {
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = requestToday(r)
+	}
+	if !isValidDate(date) {
+		respondError(w, http.StatusBadRequest, "date must be YYYY-MM-DD")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "":
+		format = "markdown"
+	case "markdown", "text", "json":
+	default:
+		respondError(w, http.StatusBadRequest, "format must be markdown, text or json")
+		return
+	}
+
+	if _, err := MaterializeRecurring(store, date, requestToday(r)); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	standup, err := BuildStandup(store, date)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	switch format {
+	case "json":
+		respondJSON(w, http.StatusOK, standup)
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(standup.Text()))
+	default:
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Write([]byte(standup.Markdown()))
+	}
+}
+
+// Export handlers
+
+// HandleExport streams tasks and categories as ?format=csv|json|ndjson (JSON
+// by default). ?from and ?to bound the tasks' created date. ?type picks tasks,
+// categories or all; CSV exports one of them, tasks by default, while the
+// JSON formats export all by default.
+func HandleExport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := ExportRequest{Format: query.Get("format"), From: query.Get("from"), To: query.Get("to")}
+	if req.Format == "" {
+		req.Format = ExportJSON
+	}
+
+	var contentType string
+	switch req.Format {
+	case ExportCSV:
+		contentType = "text/csv; charset=utf-8"
+	case ExportJSON:
+		contentType = "application/json"
+	case ExportNDJSON:
+		contentType = "application/x-ndjson"
+	default:
+		respondError(w, http.StatusBadRequest, "format must be csv, json or ndjson")
+		return
+	}
+
+	if (req.From != "" && !isValidDate(req.From)) || (req.To != "" && !isValidDate(req.To)) {
+		respondError(w, http.StatusBadRequest, "from and to must be YYYY-MM-DD")
+		return
+	}
+	if req.From != "" && req.To != "" && req.From > req.To {
+		respondError(w, http.StatusBadRequest, "from cannot be after to")
+		return
+	}
+
+	kind := query.Get("type")
+	if kind == "" {
+		kind = "all"
+		if req.Format == ExportCSV {
+			kind = "tasks"
+		}
+	}
+	switch kind {
+	case "tasks":
+		req.Tasks = true
+	case "categories":
+		req.Categories = true
+	case "all":
+		if req.Format == ExportCSV {
+			respondError(w, http.StatusBadRequest, "CSV exports either tasks or categories")
+			return
+		}
+		req.Tasks, req.Categories = true, true
+	default:
+		respondError(w, http.StatusBadRequest, "type must be tasks, categories or all")
+		return
+	}
+
+	filename := "todo-" + kind
+	if req.From != "" || req.To != "" {
+		filename += "-" + req.From + "_" + req.To
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, req.Format))
+
+	// The status has been sent by the time most errors can happen, so they
+	// can only cut the export short
+	if err := Export(store, w, req); err != nil {
+		log.Printf("Export failed: %v", err)
+	}
+}
+
+// HandleImport imports a JSON export. ?categories=merge (the default) reuses
+// existing categories with the same name and ?categories=create makes new
+// ones. ?dry_run=true only reports what would happen. Nothing is imported
+// when any record conflicts.
+func HandleImport(w http.ResponseWriter, r *http.Request) {
+	var doc ImportDocument
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	query := r.URL.Query()
+	opts := ImportOptions{Categories: query.Get("categories"), DryRun: query.Get("dry_run") == "true"}
+	switch opts.Categories {
+	case "":
+		opts.Categories = ImportMerge
+	case ImportMerge, ImportCreate:
+	default:
+		respondError(w, http.StatusBadRequest, "categories must be merge or create")
+		return
+	}
+
+	report, err := store.Import(doc, opts, requestActor(r))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if report.Committed {
+		recordEscalations(store, requestActor(r))
+	}
+
+	status := http.StatusOK
+	if report.Summary.Conflicts > 0 && !report.DryRun {
+		status = http.StatusConflict
+	}
+	respondJSON(w, status, report)
+}
+
+// todo.txt handlers
+
+// HandleGetTodoTxt writes the tasks as a todo.txt file, only the pending or
+// completed ones with ?completed=false|true
+func HandleGetTodoTxt(w http.ResponseWriter, r *http.Request) {
+	var completed *bool
+	switch r.URL.Query().Get("completed") {
+	case "":
+	case "true", "false":
+		v := r.URL.Query().Get("completed") == "true"
+		completed = &v
+	default:
+		respondError(w, http.StatusBadRequest, "completed must be true or false")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	// The status has been sent by the time most errors can happen, so they
+	// can only cut the file short
+	if err := WriteTodoTxt(store, w, completed); err != nil {
+		log.Printf("todo.txt export failed: %v", err)
+	}
+}
+
+// HandlePutTodoTxt reads a todo.txt file, updating the tasks its lines name
+// by id: and importing the rest
+func HandlePutTodoTxt(w http.ResponseWriter, r *http.Request) {
+	lines, err := ParseTodoTxt(r.Body)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := store.ImportTodoTxt(lines, requestToday(r), requestActor(r))
+	var lineErr *TodoTxtError
+	if errors.As(err, &lineErr) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	recordEscalations(store, requestActor(r))
+	respondJSON(w, http.StatusOK, result)
+}
+
+// HandleGetCalendarICS serves the tasks as an iCalendar feed of VTODOs,
+// narrowed down by ?completed=true|false and ?category_id (repeated or
+// comma-separated)
+func HandleGetCalendarICS(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := TaskQuery{Sort: "id"}
+
+	if v := query.Get("completed"); v != "" {
+		completed, err := strconv.ParseBool(v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "completed must be true or false")
+			return
+		}
+		q.Completed = &completed
+	}
+
+	for _, value := range query["category_id"] {
+		for _, v := range strings.Split(value, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "Invalid category ID")
+				return
+			}
+			q.CategoryIDs = append(q.CategoryIDs, id)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	// The status has been sent by the time most errors can happen, so they
+	// can only cut the feed short
+	if err := WriteTaskCalendar(store, w, q); err != nil {
+		log.Printf("Calendar feed failed: %v", err)
+	}
+}
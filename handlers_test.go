@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// useMemoryStore points the handlers at a fresh memory store, with the clock
+// stopped at testNow, until the test ends
+func useMemoryStore(t *testing.T) *MemoryStore {
+	t.Helper()
+	s := NewMemoryStore()
+	prev := store
+	store = s
+	t.Cleanup(func() { store = prev })
+	setClock(t, testNow)
+	return s
+}
+
+// serve sends a request through the timezone middleware to handler, with
+// body encoded as JSON unless nil
+func serve(t *testing.T, handler http.HandlerFunc, method, target string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := httptest.NewRecorder()
+	timezoneMiddleware(handler).ServeHTTP(w, httptest.NewRequest(method, target, &buf))
+	return w
+}
+
+// decodeResponse checks the response status and decodes its JSON body into v
+func decodeResponse(t *testing.T, w *httptest.ResponseRecorder, status int, v interface{}) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("got status %d, want %d: %s", w.Code, status, w.Body)
+	}
+	if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandleCreateTask(t *testing.T) {
+	useMemoryStore(t)
+
+	var task Task
+	w := serve(t, HandleCreateTask, "POST", "/api/tasks", map[string]string{"title": "Buy milk", "priority": "p2"})
+	decodeResponse(t, w, http.StatusCreated, &task)
+	if task.Title != "Buy milk" || task.CreatedDate != testMonday {
+		t.Errorf("got %q created %s, want Buy milk created %s", task.Title, task.CreatedDate, testMonday)
+	}
+	if task.Priority == nil || *task.Priority != "P2" {
+		t.Errorf("got priority %v, want P2", task.Priority)
+	}
+
+	var tasks []Task
+	decodeResponse(t, serve(t, HandleGetTasks, "GET", "/api/tasks?date="+testMonday, nil), http.StatusOK, &tasks)
+	if len(tasks) != 1 || tasks[0].ID != task.ID {
+		t.Errorf("got %d tasks, want the created one", len(tasks))
+	}
+}
+
+func TestHandleCreateTaskRejectsBadInput(t *testing.T) {
+	useMemoryStore(t)
+
+	tests := []struct {
+		name string
+		body interface{}
+	}{
+		{"missing title", map[string]string{"description": "no title"}},
+		{"bad priority", map[string]string{"title": "x", "priority": "P9"}},
+		{"bad due date", map[string]string{"title": "x", "due_date": "tomorrow"}},
+		{"not an object", []string{"x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp map[string]string
+			decodeResponse(t, serve(t, HandleCreateTask, "POST", "/api/tasks", tt.body), http.StatusBadRequest, &resp)
+			if resp["error"] == "" {
+				t.Error("got no error message")
+			}
+		})
+	}
+}
+
+func TestHandleGetTaskNotFound(t *testing.T) {
+	useMemoryStore(t)
+
+	var resp map[string]string
+	decodeResponse(t, serve(t, HandleGetTask, "GET", "/api/tasks/42", nil), http.StatusNotFound, &resp)
+}
+
+func TestHandleOverdueFollowsRequestTimezone(t *testing.T) {
+	s := useMemoryStore(t)
+	today, yesterday := testMonday, "2026-03-01"
+	if _, err := s.CreateTask(TaskRequest{Title: "Due today", Date: today, DueDate: &today}, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateTask(TaskRequest{Title: "Due yesterday", Date: yesterday, DueDate: &yesterday}, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	var page TaskPage
+	decodeResponse(t, serve(t, HandleQueryTasks, "GET", "/api/tasks/query?tz=UTC", nil), http.StatusOK, &page)
+	if len(page.Tasks) != 2 || page.Tasks[0].IsOverdue || !page.Tasks[1].IsOverdue {
+		t.Errorf("UTC: got %+v, want only the task due yesterday overdue", page.Tasks)
+	}
+
+	decodeResponse(t, serve(t, HandleQueryTasks, "GET", "/api/tasks/query?tz=Pacific/Honolulu", nil), http.StatusOK, &page)
+	if len(page.Tasks) != 2 || page.Tasks[0].IsOverdue || page.Tasks[1].IsOverdue {
+		t.Errorf("Honolulu: got %+v, want nothing overdue", page.Tasks)
+	}
+
+	var overdue []Task
+	decodeResponse(t, serve(t, HandleGetTasks, "GET", "/api/tasks?overdue=true&tz=UTC", nil), http.StatusOK, &overdue)
+	if len(overdue) != 1 || overdue[0].Title != "Due yesterday" || !overdue[0].IsOverdue {
+		t.Errorf("overdue list: got %+v, want the task due yesterday flagged", overdue)
+	}
+}
+
+func TestHandleBulkTasksRollsBack(t *testing.T) {
+	s := useMemoryStore(t)
+	task := mustCreateTask(t, s, "Bulk", testMonday)
+
+	req := BulkRequest{
+		Operations:   []BulkOperation{{Op: BulkComplete, TaskIDs: []int64{task.ID, task.ID + 100}}},
+		AllOrNothing: true,
+	}
+	var resp BulkResponse
+	decodeResponse(t, serve(t, HandleBulkTasks, "POST", "/api/tasks/bulk", req), http.StatusUnprocessableEntity, &resp)
+	if resp.Committed || resp.Succeeded != 0 || resp.Failed != 2 {
+		t.Errorf("got committed %v, %d succeeded, %d failed; want nothing committed and 2 failed", resp.Committed, resp.Succeeded, resp.Failed)
+	}
+
+	req.AllOrNothing = false
+	decodeResponse(t, serve(t, HandleBulkTasks, "POST", "/api/tasks/bulk", req), http.StatusOK, &resp)
+	if !resp.Committed || resp.Succeeded != 1 || resp.Failed != 1 {
+		t.Errorf("got committed %v, %d succeeded, %d failed; want 1 and 1", resp.Committed, resp.Succeeded, resp.Failed)
+	}
+}
+
+func TestHandleQueryTasksRejectsForeignCursor(t *testing.T) {
+	useMemoryStore(t)
+
+	cursor := EncodeTaskCursor(TaskCursor{Sort: "title:asc", Key: "a", ID: 1})
+	var resp map[string]string
+	decodeResponse(t, serve(t, HandleQueryTasks, "GET", "/api/tasks/query?sort=id&cursor="+cursor, nil), http.StatusBadRequest, &resp)
+}
+
+func TestHandleUndo(t *testing.T) {
+	s := useMemoryStore(t)
+	var empty map[string]string
+	decodeResponse(t, serve(t, HandleUndo, "POST", "/api/undo", nil), http.StatusConflict, &empty)
+
+	task := mustCreateTask(t, s, "Undo me", testMonday)
+	var deleted map[string]string
+	decodeResponse(t, serve(t, HandleDeleteTask, "DELETE", "/api/tasks/"+strconv.FormatInt(task.ID, 10), nil), http.StatusOK, &deleted)
+
+	var resp map[string][]Operation
+	decodeResponse(t, serve(t, HandleUndo, "POST", "/api/undo", nil), http.StatusOK, &resp)
+	if ops := resp["operations"]; len(ops) != 1 || ops[0].Kind != OpDeleteTask {
+		t.Errorf("undid %+v, want the delete", ops)
+	}
+	if _, err := s.GetTaskByID(task.ID); err != nil {
+		t.Errorf("task not back after undo: %v", err)
+	}
+}
+
+func TestHandleMissingTaskIsNotFound(t *testing.T) {
+	s := useMemoryStore(t)
+	trashed := mustCreateTask(t, s, "Trashed", testMonday)
+	if err := s.DeleteTask(trashed.ID, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/api/tasks/42", "/api/tasks/" + strconv.FormatInt(trashed.ID, 10)} {
+		var resp map[string]string
+		decodeResponse(t, serve(t, HandleDeleteTask, "DELETE", path, nil), http.StatusNotFound, &resp)
+		decodeResponse(t, serve(t, HandleUpdateTask, "PUT", path, map[string]string{"title": "x"}), http.StatusNotFound, &resp)
+		decodeResponse(t, serve(t, HandleUpdateTaskCompletion, "PUT", path+"/complete", map[string]bool{"is_completed": true}), http.StatusNotFound, &resp)
+	}
+}
+
+func TestHandleDeleteCategoryNotFound(t *testing.T) {
+	s := useMemoryStore(t)
+	category, err := s.CreateCategory(CategoryRequest{Name: "Attic", Color: "#123456", RolloverPolicy: RolloverNextDay})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := "/api/categories/" + strconv.FormatInt(category.ID, 10)
+
+	var resp map[string]string
+	decodeResponse(t, serve(t, HandleDeleteCategory, "DELETE", path, nil), http.StatusOK, &resp)
+	decodeResponse(t, serve(t, HandleDeleteCategory, "DELETE", path, nil), http.StatusNotFound, &resp)
+	decodeResponse(t, serve(t, HandleDeleteCategory, "DELETE", "/api/categories/42", nil), http.StatusNotFound, &resp)
+}
+
+func TestHandleHolidayConflicts(t *testing.T) {
+	useMemoryStore(t)
+	useCalendar(t, testCalendar())
+
+	var first, second Holiday
+	decodeResponse(t, serve(t, HandleCreateHoliday, "POST", "/api/holidays", map[string]string{"date": "2026-12-25", "name": "Christmas"}), http.StatusCreated, &first)
+	decodeResponse(t, serve(t, HandleCreateHoliday, "POST", "/api/holidays", map[string]string{"date": "2026-12-26", "name": "Boxing Day"}), http.StatusCreated, &second)
+
+	var resp map[string]string
+	decodeResponse(t, serve(t, HandleCreateHoliday, "POST", "/api/holidays", map[string]string{"date": "2026-12-25", "name": "Again"}), http.StatusConflict, &resp)
+	path := "/api/holidays/" + strconv.FormatInt(second.ID, 10)
+	decodeResponse(t, serve(t, HandleUpdateHoliday, "PUT", path, map[string]string{"date": "2026-12-25", "name": "Clash"}), http.StatusConflict, &resp)
+	decodeResponse(t, serve(t, HandleUpdateHoliday, "PUT", "/api/holidays/42", map[string]string{"date": "2026-12-31", "name": "Missing"}), http.StatusNotFound, &resp)
+
+	// Saving a holiday unchanged is not a conflict with itself
+	decodeResponse(t, serve(t, HandleUpdateHoliday, "PUT", path, map[string]string{"date": "2026-12-26", "name": "Boxing Day"}), http.StatusOK, &second)
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// Outcomes of a task's time on a historical day
+const (
+	OutcomeCompleted  = "completed"
+	OutcomeRolledOver = "rolled_over"
+	OutcomeDeleted    = "deleted"
+	OutcomePending    = "pending"
+)
+
+// HistoricalTask is a task as it stood on a past day
+type HistoricalTask struct {
+	Task
+	Outcome     string     `json:"outcome"`      // completed, rolled_over, deleted or pending
+	ArrivedFrom *string    `json:"arrived_from"` // Date it was rolled over from (nil if created for the day)
+	ArrivedAt   *time.Time `json:"arrived_at"`   // When it landed on the day (nil if unknown)
+	Planned     bool       `json:"planned"`      // On the board at the start of the day or carried over into it
+	AddedMidDay bool       `json:"added_mid_day"`
+	RolledTo    *string    `json:"rolled_to"` // Date it was rolled over to
+}
+
+// HistoricalLog reconstructs what a past day's board looked like
+type HistoricalLog struct {
+	Date            string           `json:"date"`
+	Tasks           []HistoricalTask `json:"tasks"`
+	CompletedCount  int              `json:"completed_count"`
+	PendingCount    int              `json:"pending_count"`
+	RolledOverCount int              `json:"rolled_over_count"`
+	DeletedCount    int              `json:"deleted_count"`
+	PlannedCount    int              `json:"planned_count"`
+	AddedCount      int              `json:"added_count"`
+}
+
+// assignment is one continuous stretch of a task being assigned to a date
+type assignment struct {
+	date          string
+	arrivedFrom   *string
+	arrivedAt     *time.Time
+	outcome       string
+	rolledTo      *string
+	completedDate *string
+}
+
+// GetHistoricalLog rebuilds the board for date from each task's recorded
+// assignment changes. Day boundaries are taken in loc.
+func GetHistoricalLog(s TaskStore, date string, loc *time.Location) (*HistoricalLog, error) {
+	dayStart, err := time.ParseInLocation("2006-01-02", date, loc)
+	if err != nil {
+		return nil, err
+	}
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	ids, err := s.GetTaskIDsTouchingDate(date)
+	if err != nil {
+		return nil, err
+	}
+
+	histories, err := s.GetTaskHistories(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// Trashed tasks still have their row; only purged ones are rebuilt from
+	// their events
+	current, err := s.GetTasksByIDsIncludingTrash(ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]Task, len(current))
+	for _, t := range current {
+		byID[t.ID] = t
+	}
+
+	log := &HistoricalLog{Date: date, Tasks: []HistoricalTask{}}
+
+	for _, id := range ids {
+		task, exists := byID[id]
+		events := histories[id]
+		if !exists {
+			task = deletedTaskSnapshot(id, events)
+		}
+
+		var onDay *assignment
+		for _, a := range replayAssignments(task, exists, events) {
+			a := a
+			switch {
+			case a.date == date:
+				if onDay == nil {
+					onDay = &a
+				} else {
+					// Rolled away and back again: keep the first arrival, last outcome
+					onDay.outcome, onDay.rolledTo, onDay.completedDate = a.outcome, a.rolledTo, a.completedDate
+				}
+			case onDay == nil && a.completedDate != nil && *a.completedDate == date:
+				// Completed on this day from another day's board
+				onDay = &a
+			}
+		}
+		if onDay == nil {
+			continue
+		}
+
+		ht := HistoricalTask{
+			Task:        task,
+			Outcome:     onDay.outcome,
+			ArrivedFrom: onDay.arrivedFrom,
+			ArrivedAt:   onDay.arrivedAt,
+			RolledTo:    onDay.rolledTo,
+		}
+		ht.DragDays = CalculateBusinessDays(task.CreatedDate, date)
+
+		// Work carried over from an earlier day counts as planned even though
+		// the rollover itself happens after the day has started
+		carriedOver := onDay.arrivedFrom != nil && *onDay.arrivedFrom < date
+		if onDay.arrivedAt == nil || onDay.arrivedAt.Before(dayStart) || carriedOver {
+			ht.Planned = true
+			log.PlannedCount++
+		} else if onDay.arrivedAt.Before(dayEnd) {
+			ht.AddedMidDay = true
+			log.AddedCount++
+		}
+
+		switch ht.Outcome {
+		case OutcomeCompleted:
+			log.CompletedCount++
+		case OutcomeRolledOver:
+			log.RolledOverCount++
+		case OutcomeDeleted:
+			log.DeletedCount++
+		default:
+			log.PendingCount++
+		}
+
+		log.Tasks = append(log.Tasks, ht)
+	}
+
+	sort.SliceStable(log.Tasks, func(i, j int) bool {
+		a, b := log.Tasks[i], log.Tasks[j]
+		if (a.Outcome == OutcomeCompleted) != (b.Outcome == OutcomeCompleted) {
+			return a.Outcome == OutcomeCompleted
+		}
+		return a.ID < b.ID
+	})
+
+	return log, nil
+}
+
+// replayAssignments turns a task's events into the dates it was assigned to.
+// Tasks that predate event recording get their history inferred from the
+// created, assigned and completed date columns.
+func replayAssignments(task Task, exists bool, events []TaskEvent) []assignment {
+	var list []assignment
+	open := func(date string, from *string, at *time.Time) {
+		list = append(list, assignment{date: date, arrivedFrom: from, arrivedAt: at, outcome: OutcomePending})
+	}
+	last := func() *assignment {
+		if len(list) == 0 {
+			return nil
+		}
+		return &list[len(list)-1]
+	}
+
+	// Without a created event the start of the task's life was never recorded
+	if len(events) == 0 || events[0].Type != EventCreated {
+		initial := task.CreatedDate
+		for _, e := range events {
+			if (e.Type == EventRolledOver || e.Type == EventMoved) && e.OldValue != nil {
+				initial = *e.OldValue
+				break
+			}
+		}
+		if initial != "" {
+			open(initial, nil, nil)
+		}
+	}
+
+	for _, e := range events {
+		at := e.CreatedAt
+		switch e.Type {
+		case EventCreated:
+			if e.NewValue != nil {
+				open(*e.NewValue, nil, &at)
+			}
+		case EventRolledOver, EventMoved:
+			// Moving a task by hand leaves its day just like a rollover
+			if a := last(); a != nil {
+				a.outcome = OutcomeRolledOver
+				a.rolledTo = e.NewValue
+			}
+			if e.NewValue != nil {
+				open(*e.NewValue, e.OldValue, &at)
+			}
+		case EventCompleted:
+			if a := last(); a != nil {
+				a.outcome = OutcomeCompleted
+				a.completedDate = e.NewValue
+			}
+		case EventUncompleted:
+			if a := last(); a != nil {
+				a.outcome = OutcomePending
+				a.completedDate = nil
+			}
+		case EventDeleted:
+			if a := last(); a != nil {
+				a.outcome = OutcomeDeleted
+			}
+		case EventRestored:
+			// Back from the trash, the task picks up where it left off
+			if a := last(); a != nil && a.outcome == OutcomeDeleted {
+				a.outcome = OutcomePending
+				if a.completedDate != nil {
+					a.outcome = OutcomeCompleted
+				}
+			}
+		}
+	}
+
+	if len(events) > 0 || !exists {
+		return list
+	}
+
+	// No events at all: infer a single rollover and the completion from the row
+	if task.AssignedDate != task.CreatedDate {
+		if a := last(); a != nil {
+			a.outcome = OutcomeRolledOver
+			assigned := task.AssignedDate
+			a.rolledTo = &assigned
+		}
+		from := task.CreatedDate
+		open(task.AssignedDate, &from, nil)
+	}
+	if task.IsCompleted {
+		if a := last(); a != nil {
+			a.outcome = OutcomeCompleted
+			a.completedDate = task.CompletedDate
+		}
+	}
+
+	return list
+}
+
+// deletedTaskSnapshot rebuilds what is known about a task purged from the
+// trash: its dates and its title as of its last deletion
+func deletedTaskSnapshot(id int64, events []TaskEvent) Task {
+	task := Task{ID: id}
+	for _, e := range events {
+		switch e.Type {
+		case EventCreated:
+			if e.NewValue != nil {
+				task.CreatedDate = *e.NewValue
+				task.AssignedDate = *e.NewValue
+			}
+			task.CreatedAt = e.CreatedAt
+		case EventRolledOver, EventMoved:
+			if e.NewValue != nil {
+				task.AssignedDate = *e.NewValue
+			}
+		case EventTitleChanged, EventDeleted:
+			if e.NewValue != nil {
+				task.Title = *e.NewValue
+			}
+		case EventDescriptionChanged:
+			if e.NewValue != nil {
+				task.Description = *e.NewValue
+			}
+		}
+		task.UpdatedAt = e.CreatedAt
+	}
+	return task
+}
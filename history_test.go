@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// historicalTask finds title in log
+func historicalTask(t *testing.T, log *HistoricalLog, title string) HistoricalTask {
+	t.Helper()
+	for _, task := range log.Tasks {
+		if task.Title == title {
+			return task
+		}
+	}
+	t.Fatalf("%q is not on the %s board", title, log.Date)
+	return HistoricalTask{}
+}
+
+func TestGetHistoricalLogReplaysEvents(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		sunday := "2026-03-01"
+		at := func(day, hour int) {
+			setClock(t, time.Date(2026, 3, day, hour, 0, 0, 0, time.UTC))
+		}
+		rollover := func(from, to string) {
+			t.Helper()
+			if _, err := s.RolloverTasks(from, to, RolloverOptions{}, "test"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		complete := func(task *Task, date string) {
+			t.Helper()
+			if _, err := s.UpdateTaskCompletion(task.ID, true, date, "test"); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		at(1, 20)
+		planned := mustCreateTask(t, s, "Planned", testMonday)
+		mustCreateTask(t, s, "Carried", sunday)
+		at(2, 1)
+		rollover(sunday, testMonday)
+		at(2, 10)
+		added := mustCreateTask(t, s, "Added", testMonday)
+		at(2, 12)
+		complete(planned, testMonday)
+		at(3, 0)
+		rollover(testMonday, testTuesday)
+		at(3, 9)
+		complete(added, testTuesday)
+
+		monday, err := GetHistoricalLog(s, testMonday, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if monday.CompletedCount != 1 || monday.RolledOverCount != 2 || monday.PlannedCount != 2 || monday.AddedCount != 1 {
+			t.Errorf("got counts %+v", monday)
+		}
+		if got := historicalTask(t, monday, "Planned"); got.Outcome != OutcomeCompleted || !got.Planned {
+			t.Errorf("Planned: got %s, planned %v", got.Outcome, got.Planned)
+		}
+		// Completing the task later does not rewrite the day it was rolled off
+		if got := historicalTask(t, monday, "Added"); got.Outcome != OutcomeRolledOver || !got.AddedMidDay || got.RolledTo == nil || *got.RolledTo != testTuesday {
+			t.Errorf("Added: got %s to %v, added mid-day %v", got.Outcome, got.RolledTo, got.AddedMidDay)
+		}
+		// Carried over from the day before counts as planned, though it arrived after midnight
+		if got := historicalTask(t, monday, "Carried"); got.Outcome != OutcomeRolledOver || !got.Planned || got.ArrivedFrom == nil || *got.ArrivedFrom != sunday {
+			t.Errorf("Carried: got %s from %v, planned %v", got.Outcome, got.ArrivedFrom, got.Planned)
+		}
+
+		tuesday, err := GetHistoricalLog(s, testTuesday, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tuesday.Tasks) != 2 || tuesday.Tasks[0].Title != "Added" || tuesday.Tasks[0].Outcome != OutcomeCompleted {
+			t.Errorf("got Tuesday %+v, want Added completed first", tuesday.Tasks)
+		}
+		if got := historicalTask(t, tuesday, "Carried"); got.Outcome != OutcomePending {
+			t.Errorf("Carried on Tuesday: got %s, want pending", got.Outcome)
+		}
+
+		// Day boundaries follow the timezone asked for: Planned was created
+		// at 01:30 on Monday in Kolkata, so it was added during the day
+		kolkata, err := GetHistoricalLog(s, testMonday, mustLoadLocation("Asia/Kolkata"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := historicalTask(t, kolkata, "Planned"); got.Planned || !got.AddedMidDay {
+			t.Errorf("Planned in Kolkata: got planned %v, added mid-day %v", got.Planned, got.AddedMidDay)
+		}
+	})
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// How an import matches its categories with existing ones
+const (
+	ImportMerge  = "merge"  // Reuse an existing category with the same name
+	ImportCreate = "create" // Always create new categories; a taken name is a conflict
+)
+
+// What an import does with each record
+const (
+	ImportCreated  = "created"
+	ImportMerged   = "merged"
+	ImportSkipped  = "skipped"
+	ImportConflict = "conflict"
+)
+
+// ImportDocument is a JSON export read back in. Other export fields are
+// ignored.
+type ImportDocument struct {
+	Categories []ExportCategory `json:"categories"`
+	Tasks      []ExportTask     `json:"tasks"`
+}
+
+// ImportOptions say how to import a document
+type ImportOptions struct {
+	Categories string // merge or create
+	DryRun     bool
+}
+
+// ImportCategoryResult is what an import does with one category
+type ImportCategoryResult struct {
+	SourceID int64  `json:"source_id"`
+	ID       *int64 `json:"id"` // The category created or merged into; null when not created (yet)
+	Name     string `json:"name"`
+	Action   string `json:"action"` // created, merged or conflict
+	Reason   string `json:"reason,omitempty"`
+
+	request   CategoryRequest
+	createdAt time.Time
+	mergeInto int // Index of the imported category this one shares a name with, or -1
+}
+
+// ImportTaskResult is what an import does with one task
+type ImportTaskResult struct {
+	SourceID int64    `json:"source_id"`
+	ID       *int64   `json:"id"` // The task created; null when not created (yet)
+	Title    string   `json:"title"`
+	Action   string   `json:"action"` // created, skipped or conflict
+	Reason   string   `json:"reason,omitempty"`
+	Dropped  []string `json:"dropped,omitempty"` // Fields of the record the import cannot keep
+
+	task     ExportTask
+	category int // Index of its category in the report, or -1 when uncategorized
+}
+
+// ImportSummary counts what an import did
+type ImportSummary struct {
+	CategoriesCreated int `json:"categories_created"`
+	CategoriesMerged  int `json:"categories_merged"`
+	TasksCreated      int `json:"tasks_created"`
+	TasksSkipped      int `json:"tasks_skipped"`
+	Conflicts         int `json:"conflicts"`
+}
+
+// ImportReport says what an import did, or on a dry run or conflict what it
+// would do. Nothing is applied unless Committed.
+type ImportReport struct {
+	DryRun     bool                   `json:"dry_run"`
+	Committed  bool                   `json:"committed"`
+	Mode       string                 `json:"categories_mode"`
+	Summary    ImportSummary          `json:"summary"`
+	Categories []ImportCategoryResult `json:"categories"`
+	Tasks      []ImportTaskResult     `json:"tasks"`
+}
+
+// importTaskKey identifies a task for spotting one that was already imported
+type importTaskKey struct {
+	Title       string
+	CreatedDate string
+	CategoryID  int64 // 0 when uncategorized
+}
+
+// planImport decides what to do with each record of doc, given the live and
+// trashed categories and the keys of the tasks already stored. Categories
+// are matched by name without case. Tasks already stored with the same title,
+// created date and category are skipped, so importing a document twice adds
+// nothing. Invalid records and clashing names are conflicts.
+func planImport(doc ImportDocument, opts ImportOptions, live, trashed []Category, existing map[importTaskKey]bool) *ImportReport {
+	report := &ImportReport{
+		DryRun:     opts.DryRun,
+		Mode:       opts.Categories,
+		Categories: []ImportCategoryResult{},
+		Tasks:      []ImportTaskResult{},
+	}
+
+	findCategory := func(categories []Category, name string) *Category {
+		for i := range categories {
+			if strings.EqualFold(categories[i].Name, name) {
+				return &categories[i]
+			}
+		}
+		return nil
+	}
+
+	bySource := map[int64]int{}
+	addCategory := func(c ExportCategory) {
+		result := ImportCategoryResult{SourceID: c.ID, Name: c.Name, Action: ImportCreated, createdAt: c.CreatedAt, mergeInto: -1}
+		result.request = CategoryRequest{
+			Name:              c.Name,
+			Color:             c.Color,
+			RolloverPolicy:    c.RolloverPolicy,
+			ArchiveAfterDays:  copyIntPtr(c.ArchiveAfterDays),
+			StaleAfterDays:    copyIntPtr(c.StaleAfterDays),
+			CriticalAfterDays: copyIntPtr(c.CriticalAfterDays),
+		}
+		if result.request.Color == "" {
+			result.request.Color = "#58a6ff"
+		}
+
+		// An earlier category of the document with the same name
+		earlier := -1
+		for i, prev := range report.Categories {
+			if prev.Action != ImportConflict && strings.EqualFold(prev.Name, c.Name) {
+				earlier = i
+				break
+			}
+		}
+
+		if _, ok := bySource[c.ID]; ok {
+			result.Action, result.Reason = ImportConflict, fmt.Sprintf("duplicate category id %d", c.ID)
+		} else if strings.TrimSpace(c.Name) == "" {
+			result.Action, result.Reason = ImportConflict, "name is required"
+		} else if msg := normalizeCategoryRequest(&result.request); msg != "" {
+			result.Action, result.Reason = ImportConflict, msg
+		} else if opts.Categories == ImportCreate {
+			if findCategory(live, c.Name) != nil || findCategory(trashed, c.Name) != nil || earlier >= 0 {
+				result.Action, result.Reason = ImportConflict, fmt.Sprintf("a category named %q already exists", c.Name)
+			}
+		} else if match := findCategory(live, c.Name); match != nil {
+			id := match.ID
+			result.Action, result.ID = ImportMerged, &id
+		} else if findCategory(trashed, c.Name) != nil {
+			result.Action, result.Reason = ImportConflict, fmt.Sprintf("a category named %q is in the trash", c.Name)
+		} else if earlier >= 0 {
+			result.Action, result.ID, result.mergeInto = ImportMerged, report.Categories[earlier].ID, earlier
+		}
+
+		if _, ok := bySource[c.ID]; !ok {
+			bySource[c.ID] = len(report.Categories)
+		}
+		report.Categories = append(report.Categories, result)
+	}
+
+	for _, c := range doc.Categories {
+		addCategory(c)
+	}
+	// Tasks exported without their categories bring along their category's
+	// name and color
+	for _, t := range doc.Tasks {
+		if t.CategoryID == nil || t.CategoryName == nil {
+			continue
+		}
+		if _, ok := bySource[*t.CategoryID]; ok {
+			continue
+		}
+		c := ExportCategory{ID: *t.CategoryID, Name: *t.CategoryName}
+		if t.CategoryColor != nil {
+			c.Color = *t.CategoryColor
+		}
+		addCategory(c)
+	}
+
+	seen := map[int64]bool{}
+	for _, t := range doc.Tasks {
+		result := ImportTaskResult{SourceID: t.ID, Title: t.Title, Action: ImportCreated, task: t, category: -1}
+
+		if t.CategoryID != nil {
+			if i, ok := bySource[*t.CategoryID]; ok {
+				result.category = i
+			}
+		}
+
+		if t.ID != 0 && seen[t.ID] {
+			result.Action, result.Reason = ImportConflict, fmt.Sprintf("duplicate task id %d", t.ID)
+		} else if msg := normalizeImportTask(&result.task); msg != "" {
+			result.Action, result.Reason = ImportConflict, msg
+		} else if t.CategoryID != nil && result.category < 0 {
+			result.Action, result.Reason = ImportConflict, fmt.Sprintf("category %d is not in the import", *t.CategoryID)
+		} else if result.category >= 0 && report.Categories[result.category].Action == ImportConflict {
+			result.Action, result.Reason = ImportConflict, fmt.Sprintf("its category %d conflicts", *t.CategoryID)
+		} else {
+			key := importTaskKey{Title: t.Title, CreatedDate: t.CreatedDate}
+			if result.category >= 0 {
+				category := report.Categories[result.category]
+				if category.Action == ImportMerged && category.mergeInto < 0 {
+					key.CategoryID = *category.ID
+				} else {
+					key.CategoryID = -1 // A new category holds no tasks yet
+				}
+			}
+			if existing[key] {
+				result.Action, result.Reason = ImportSkipped, "already exists"
+			} else if t.RecurringID != nil {
+				// Recurring templates are not exported, so the task cannot
+				// stay linked to its template
+				result.Dropped = append(result.Dropped, "recurring_id")
+			}
+		}
+		seen[t.ID] = true
+
+		report.Tasks = append(report.Tasks, result)
+	}
+
+	for _, c := range report.Categories {
+		switch c.Action {
+		case ImportCreated:
+			report.Summary.CategoriesCreated++
+		case ImportMerged:
+			report.Summary.CategoriesMerged++
+		case ImportConflict:
+			report.Summary.Conflicts++
+		}
+	}
+	for _, t := range report.Tasks {
+		switch t.Action {
+		case ImportCreated:
+			report.Summary.TasksCreated++
+		case ImportSkipped:
+			report.Summary.TasksSkipped++
+		case ImportConflict:
+			report.Summary.Conflicts++
+		}
+	}
+
+	return report
+}
+
+// normalizeImportTask validates an imported task's dates, priority, tags and
+// checklist, upper-casing the priority and trimming tag names.
+// It returns an error message or "".
+func normalizeImportTask(t *ExportTask) string {
+	if strings.TrimSpace(t.Title) == "" {
+		return "title is required"
+	}
+	if !isValidDate(t.CreatedDate) || !isValidDate(t.AssignedDate) {
+		return "created_date and assigned_date must be YYYY-MM-DD"
+	}
+	if t.AssignedDate < t.CreatedDate {
+		return "assigned_date cannot be before created_date"
+	}
+
+	if !t.IsCompleted {
+		t.CompletedDate = nil
+	} else if t.CompletedDate == nil || !isValidDate(*t.CompletedDate) {
+		return "completed tasks need a completed_date as YYYY-MM-DD"
+	} else if *t.CompletedDate < t.CreatedDate {
+		return "completed_date cannot be before created_date"
+	}
+
+	var ok bool
+	if t.Priority, ok = normalizePriority(emptyToNil(t.Priority)); !ok {
+		return "priority must be one of P0, P1, P2, P3"
+	}
+	t.DueDate = emptyToNil(t.DueDate)
+	if t.DueDate != nil && !isValidDate(*t.DueDate) {
+		return "due_date must be YYYY-MM-DD"
+	}
+
+	// Tags are named once each, ignoring case
+	var tags []string
+	seen := map[string]bool{}
+	for _, name := range t.Tags {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return "tag names cannot be empty"
+		}
+		if strings.Contains(name, ",") {
+			return "tag names cannot contain commas"
+		}
+		if key := strings.ToLower(name); !seen[key] {
+			seen[key] = true
+			tags = append(tags, name)
+		}
+	}
+	t.Tags = tags
+
+	for _, item := range t.Items {
+		if strings.TrimSpace(item.Title) == "" {
+			return "checklist items need a title"
+		}
+	}
+	return ""
+}
+
+// canApply reports whether the planned import should be applied
+func (r *ImportReport) canApply() bool {
+	return !r.DryRun && r.Summary.Conflicts == 0
+}
+
+// linkCategories gives the categories merged into another imported category
+// that category's ID, once the created categories have theirs
+func (r *ImportReport) linkCategories() {
+	for i := range r.Categories {
+		if c := &r.Categories[i]; c.mergeInto >= 0 {
+			c.ID = r.Categories[c.mergeInto].ID
+		}
+	}
+}
+
+// categoryID is the ID of an imported task's category
+func (r *ImportReport) categoryID(t ImportTaskResult) *int64 {
+	if t.category < 0 {
+		return nil
+	}
+	return r.Categories[t.category].ID
+}
+
+// importedTaskEvents retrace an imported task's life: its creation, its
+// rollover to the date it is assigned to and its completion. History and
+// daily logs then show it on the right days.
+func importedTaskEvents(t ExportTask) []stateEvent {
+	created, assigned := t.CreatedDate, t.AssignedDate
+	events := []stateEvent{{EventCreated, nil, &created}}
+	if assigned != created {
+		events = append(events, stateEvent{EventRolledOver, &created, &assigned})
+	}
+	if t.IsCompleted {
+		events = append(events, stateEvent{EventCompleted, nil, t.CompletedDate})
+	}
+	return events
+}
+
+// importTimestamp keeps an imported record's timestamp, or stamps it now
+// when the document has none
+func importTimestamp(t time.Time) time.Time {
+	if t.IsZero() {
+		return clock.Now().UTC()
+	}
+	return t.UTC()
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// journalSize is how many operations the journal keeps for undo
+const journalSize = 100
+
+// taskState is the part of a task that undo and redo put back. CategoryID is
+// the stored column, which survives its category going to the trash.
+type taskState struct {
+	ID            int64   `json:"id"`
+	Title         string  `json:"title"`
+	Description   string  `json:"description"`
+	AssignedDate  string  `json:"assigned_date"`
+	CompletedDate *string `json:"completed_date"`
+	IsCompleted   bool    `json:"is_completed"`
+	CategoryID    *int64  `json:"category_id"`
+	Priority      *string `json:"priority"`
+	DueDate       *string `json:"due_date"`
+	Deleted       bool    `json:"deleted"`
+}
+
+// categoryState records whether a category is in the trash
+type categoryState struct {
+	ID      int64 `json:"id"`
+	Deleted bool  `json:"deleted"`
+}
+
+// journalState is a snapshot of everything one operation touched
+type journalState struct {
+	Tasks      []taskState     `json:"tasks,omitempty"`
+	Categories []categoryState `json:"categories,omitempty"`
+}
+
+// taskIDs lists the tasks in the snapshot
+func (js journalState) taskIDs() []int64 {
+	ids := make([]int64, len(js.Tasks))
+	for i, t := range js.Tasks {
+		ids[i] = t.ID
+	}
+	return ids
+}
+
+// categoryIDs lists the categories in the snapshot
+func (js journalState) categoryIDs() []int64 {
+	ids := make([]int64, len(js.Categories))
+	for i, c := range js.Categories {
+		ids[i] = c.ID
+	}
+	return ids
+}
+
+// merge combines snapshots of different records, keeping them in ID order
+// like the snapshots the stores read
+func (js journalState) merge(other journalState) journalState {
+	merged := journalState{
+		Tasks:      append(append([]taskState{}, js.Tasks...), other.Tasks...),
+		Categories: append(append([]categoryState{}, js.Categories...), other.Categories...),
+	}
+	sort.Slice(merged.Tasks, func(i, j int) bool { return merged.Tasks[i].ID < merged.Tasks[j].ID })
+	sort.Slice(merged.Categories, func(i, j int) bool { return merged.Categories[i].ID < merged.Categories[j].ID })
+	return merged
+}
+
+// sameJournalState reports whether an operation changed nothing
+func sameJournalState(a, b journalState) bool {
+	if len(a.Tasks) != len(b.Tasks) || len(a.Categories) != len(b.Categories) {
+		return false
+	}
+	for i := range a.Tasks {
+		if !sameTaskState(a.Tasks[i], b.Tasks[i]) {
+			return false
+		}
+	}
+	for i := range a.Categories {
+		if a.Categories[i] != b.Categories[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameTaskState(a, b taskState) bool {
+	return len(taskTransition(a, b)) == 0
+}
+
+// stateEvent is a task event that moving between two states records
+type stateEvent struct {
+	Type     string
+	OldValue *string
+	NewValue *string
+}
+
+// taskTransition lists the events that take a task from one state to
+// another, so undo and redo leave the same history as the original change
+func taskTransition(from, to taskState) []stateEvent {
+	var events []stateEvent
+	add := func(eventType string, oldValue, newValue *string) {
+		events = append(events, stateEvent{eventType, oldValue, newValue})
+	}
+
+	if from.Deleted && !to.Deleted {
+		add(EventRestored, nil, &to.AssignedDate)
+	}
+	if from.Title != to.Title {
+		add(EventTitleChanged, &from.Title, &to.Title)
+	}
+	if from.Description != to.Description {
+		add(EventDescriptionChanged, &from.Description, &to.Description)
+	}
+	if !sameStringPtr(from.Priority, to.Priority) {
+		add(EventPriorityChanged, from.Priority, to.Priority)
+	}
+	if !sameStringPtr(from.DueDate, to.DueDate) {
+		add(EventDueDateChanged, from.DueDate, to.DueDate)
+	}
+	if !sameInt64Ptr(from.CategoryID, to.CategoryID) {
+		add(EventCategoryChanged, formatIDPtr(from.CategoryID), formatIDPtr(to.CategoryID))
+	}
+	if from.AssignedDate != to.AssignedDate {
+		add(EventMoved, &from.AssignedDate, &to.AssignedDate)
+	}
+	switch {
+	case to.IsCompleted && (!from.IsCompleted || !sameStringPtr(from.CompletedDate, to.CompletedDate)):
+		add(EventCompleted, from.CompletedDate, to.CompletedDate)
+	case !to.IsCompleted && from.IsCompleted:
+		add(EventUncompleted, from.CompletedDate, nil)
+	}
+	if !from.Deleted && to.Deleted {
+		add(EventDeleted, &from.AssignedDate, &to.Title)
+	}
+
+	return events
+}
+
+// operationSummary describes an operation for the journal. subject is the
+// task title or category name, or the target date of a rollover.
+func operationSummary(kind, subject string, count int) string {
+	switch kind {
+	case OpCreateTask:
+		return fmt.Sprintf("Create %q", subject)
+	case OpUpdateTask:
+		return fmt.Sprintf("Edit %q", subject)
+	case OpCompleteTask:
+		return fmt.Sprintf("Complete %q", subject)
+	case OpUncompleteTask:
+		return fmt.Sprintf("Reopen %q", subject)
+	case OpSetCategory:
+		return fmt.Sprintf("Change the category of %q", subject)
+	case OpDeleteTask:
+		return fmt.Sprintf("Delete %q", subject)
+	case OpRestoreTask:
+		return fmt.Sprintf("Restore %q", subject)
+	case OpRollover:
+		return fmt.Sprintf("Roll %d task(s) over to %s", count, subject)
+	case OpBulk:
+		return fmt.Sprintf("Bulk change to %d task(s)", count)
+	case OpImport:
+		return fmt.Sprintf("Import %d task(s)", count)
+	case OpTodoTxt:
+		return fmt.Sprintf("Apply todo.txt to %d task(s)", count)
+	case OpDeleteCategory:
+		return fmt.Sprintf("Delete category %q", subject)
+	case OpRestoreCategory:
+		return fmt.Sprintf("Restore category %q", subject)
+	}
+	return kind
+}
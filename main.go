@@ -1,18 +1,86 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 )
 
 func main() {
-	// Initialize database
-	if err := initDB(); err != nil {
-		log.Fatal("Failed to initialize database:", err)
+	dbPath := flag.String("db", "./todo.db", "path to the SQLite database file")
+	storeKind := flag.String("store", "sqlite", "storage backend: sqlite or memory")
+	tz := flag.String("tz", DefaultTimezone, "IANA timezone that decides when a day starts")
+	simulateDate := flag.String("simulate-date", "", "run the clock as if today were this date (YYYY-MM-DD)")
+	weekend := flag.String("weekend", "sat,sun", "comma-separated non-working weekdays, e.g. fri,sat")
+	autoRollover := flag.Bool("auto-rollover", true, "roll pending tasks forward automatically at local midnight")
+	rolloverCheck := flag.Duration("rollover-check", time.Minute, "how often the rollover scheduler checks for a new day")
+	staleAfter := flag.Int("stale-after", staleThresholds.Stale, "drag days after which a pending task is stale")
+	criticalAfter := flag.Int("critical-after", staleThresholds.Critical, "drag days after which a pending task is critical")
+	flag.Parse()
+
+	staleThresholds = StaleThresholds{Stale: *staleAfter, Critical: *criticalAfter}
+	if err := staleThresholds.Validate(); err != nil {
+		log.Fatalf("Invalid -stale-after/-critical-after: %v", err)
+	}
+
+	weekendDays, err := ParseWeekend(*weekend)
+	if err != nil {
+		log.Fatalf("Invalid -weekend %q: %v", *weekend, err)
+	}
+	calendar = NewWorkCalendar(weekendDays)
+
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		log.Fatalf("Invalid timezone %q: %v", *tz, err)
+	}
+	location = loc
+
+	if *simulateDate != "" {
+		simulated, err := NewSimulatedClock(*simulateDate, location)
+		if err != nil {
+			log.Fatalf("Invalid -simulate-date %q: %v", *simulateDate, err)
+		}
+		clock = simulated
+	}
+
+	// Subcommands run against the database and exit without starting the server
+	if flag.NArg() > 0 {
+		if err := runCommand(*dbPath, flag.Args()); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Initialize storage
+	switch *storeKind {
+	case "sqlite":
+		sqliteStore, err := NewSQLiteStore(*dbPath)
+		if err != nil {
+			log.Fatal("Failed to initialize database:", err)
+		}
+		store = sqliteStore
+	case "memory":
+		store = NewMemoryStore()
+	default:
+		log.Fatalf("Unknown store %q", *storeKind)
+	}
+	defer store.Close()
+
+	if err := reloadHolidays(store); err != nil {
+		log.Fatal("Failed to load holidays:", err)
+	}
+
+	// Start the background rollover scheduler
+	if *autoRollover {
+		scheduler := NewRolloverScheduler(store, *rolloverCheck)
+		if err := scheduler.Start(); err != nil {
+			log.Fatal("Failed to start rollover scheduler:", err)
+		}
+		defer scheduler.Stop()
 	}
-	defer db.Close()
 
 	// Create a new mux
 	mux := http.NewServeMux()
@@ -38,6 +106,38 @@ func main() {
 		}
 	})
 
+	mux.HandleFunc("/api/tasks/bulk", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			HandleBulkTasks(w, r)
+			return
+		}
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	})
+
+	mux.HandleFunc("/api/tasks/query", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			HandleQueryTasks(w, r)
+			return
+		}
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	})
+
+	mux.HandleFunc("/api/tasks/stale", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			HandleGetStaleTasks(w, r)
+			return
+		}
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	})
+
+	mux.HandleFunc("/api/tasks/stale/report", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			HandleGetStaleReport(w, r)
+			return
+		}
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	})
+
 	mux.HandleFunc("/api/tasks/", func(w http.ResponseWriter, r *http.Request) {
 		path := strings.TrimPrefix(r.URL.Path, "/api/tasks/")
 
@@ -50,6 +150,45 @@ func main() {
 			return
 		}
 
+		if strings.HasSuffix(path, "/history") {
+			if r.Method == "GET" {
+				HandleGetTaskHistory(w, r)
+				return
+			}
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if parts := strings.Split(path, "/"); len(parts) > 1 && parts[1] == "items" {
+			switch {
+			case len(parts) == 2 && r.Method == "GET":
+				HandleGetTaskItems(w, r)
+			case len(parts) == 2 && r.Method == "POST":
+				HandleCreateTaskItem(w, r)
+			case len(parts) == 3 && r.Method == "PUT":
+				HandleUpdateTaskItem(w, r)
+			case len(parts) == 3 && r.Method == "DELETE":
+				HandleDeleteTaskItem(w, r)
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		if parts := strings.Split(path, "/"); len(parts) > 1 && parts[1] == "tags" {
+			switch {
+			case len(parts) == 2 && r.Method == "GET":
+				HandleGetTaskTags(w, r)
+			case len(parts) == 2 && r.Method == "POST":
+				HandleAddTaskTag(w, r)
+			case len(parts) == 3 && r.Method == "DELETE":
+				HandleRemoveTaskTag(w, r)
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
 		if strings.HasSuffix(path, "/category") {
 			if r.Method == "PUT" {
 				HandleUpdateTaskCategory(w, r)
@@ -71,6 +210,14 @@ func main() {
 		}
 	})
 
+	mux.HandleFunc("/api/search", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			HandleSearch(w, r)
+			return
+		}
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	})
+
 	mux.HandleFunc("/api/daily-log", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "GET" {
 			HandleGetDailyLog(w, r)
@@ -95,6 +242,57 @@ func main() {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	})
 
+	mux.HandleFunc("/api/reports", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			HandleGetReport(w, r)
+			return
+		}
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	})
+
+	mux.HandleFunc("/api/standup", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			HandleGetStandup(w, r)
+			return
+		}
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	})
+
+	mux.HandleFunc("/api/calendar.ics", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			HandleGetCalendarICS(w, r)
+			return
+		}
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	})
+
+	mux.HandleFunc("/api/export", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			HandleExport(w, r)
+			return
+		}
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	})
+
+	mux.HandleFunc("/api/import", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			HandleImport(w, r)
+			return
+		}
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	})
+
+	mux.HandleFunc("/api/todotxt", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			HandleGetTodoTxt(w, r)
+		case "PUT":
+			HandlePutTodoTxt(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
 	mux.HandleFunc("/api/rollover", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "POST" {
 			HandleRollover(w, r)
@@ -119,6 +317,14 @@ func main() {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	})
 
+	mux.HandleFunc("/api/rollover-runs", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			HandleGetRolloverRuns(w, r)
+			return
+		}
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	})
+
 	mux.HandleFunc("/api/historical-log", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "GET" {
 			HandleGetHistoricalLog(w, r)
@@ -127,6 +333,99 @@ func main() {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	})
 
+	mux.HandleFunc("/api/clock", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			HandleGetClock(w, r)
+		case "PUT":
+			HandleSetClock(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// Holiday routes
+	mux.HandleFunc("/api/holidays", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			HandleGetHolidays(w, r)
+		case "POST":
+			HandleCreateHoliday(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/api/holidays/", func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/api/holidays/")
+
+		if path == "import" {
+			if r.Method == "POST" {
+				HandleImportHolidays(w, r)
+				return
+			}
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		switch r.Method {
+		case "PUT":
+			HandleUpdateHoliday(w, r)
+		case "DELETE":
+			HandleDeleteHoliday(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// Recurring task routes
+	mux.HandleFunc("/api/recurring", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			HandleGetRecurringTasks(w, r)
+		case "POST":
+			HandleCreateRecurringTask(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/api/recurring/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			HandleGetRecurringTask(w, r)
+		case "PUT":
+			HandleUpdateRecurringTask(w, r)
+		case "DELETE":
+			HandleDeleteRecurringTask(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// Tag routes
+	mux.HandleFunc("/api/tags", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			HandleGetTags(w, r)
+		case "POST":
+			HandleCreateTag(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "PUT":
+			HandleUpdateTag(w, r)
+		case "DELETE":
+			HandleDeleteTag(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
 	// Category routes
 	mux.HandleFunc("/api/categories", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -161,8 +460,52 @@ func main() {
 		}
 	})
 
-	// Apply CORS middleware
-	handler := corsMiddleware(mux)
+	// Trash and undo routes
+	mux.HandleFunc("/api/trash", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			HandleGetTrash(w, r)
+		case "DELETE":
+			HandleEmptyTrash(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/api/trash/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			HandleRestoreFromTrash(w, r)
+			return
+		}
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	})
+
+	mux.HandleFunc("/api/journal", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			HandleGetJournal(w, r)
+			return
+		}
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	})
+
+	mux.HandleFunc("/api/undo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			HandleUndo(w, r)
+			return
+		}
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	})
+
+	mux.HandleFunc("/api/redo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			HandleRedo(w, r)
+			return
+		}
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	})
+
+	// Apply CORS and timezone middleware
+	handler := corsMiddleware(timezoneMiddleware(mux))
 
 	fmt.Println("🚀 Todo App server starting on http://localhost:8080")
 	fmt.Println("📝 Open your browser to http://localhost:8080 to use the app")
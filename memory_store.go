@@ -0,0 +1,2259 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store that keeps everything in process memory. It is
+// useful for tests, demos and embedding; nothing survives a restart.
+type MemoryStore struct {
+	mu             sync.RWMutex
+	tasks          map[int64]*Task
+	trash          map[int64]*Task // Deleted tasks, kept until the trash is emptied
+	categories     map[int64]*Category
+	categoryTrash  map[int64]*Category
+	holidays       map[int64]*Holiday
+	items          map[int64][]*TaskItem // task ID -> checklist in order
+	recurring      map[int64]*RecurringTask
+	tags           map[int64]*Tag
+	taskTags       map[int64]map[int64]bool    // task ID -> set of tag IDs
+	occurrences    map[int64]map[string]*int64 // template ID -> date -> generated task (nil if skipped)
+	rolloverRuns   []RolloverRun
+	events         []TaskEvent
+	operations     []memoryOperation // Journal, oldest first
+	nextTaskID     int64
+	nextCategoryID int64
+	nextHolidayID  int64
+	nextItemID     int64
+	nextRecurrence int64
+	nextTagID      int64
+	nextOperation  int64
+}
+
+// NewMemoryStore creates an empty in-memory store seeded with the default categories
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
+		tasks:         make(map[int64]*Task),
+		trash:         make(map[int64]*Task),
+		categories:    make(map[int64]*Category),
+		categoryTrash: make(map[int64]*Category),
+		holidays:      make(map[int64]*Holiday),
+		items:         make(map[int64][]*TaskItem),
+		recurring:     make(map[int64]*RecurringTask),
+		occurrences:   make(map[int64]map[string]*int64),
+		tags:          make(map[int64]*Tag),
+		taskTags:      make(map[int64]map[int64]bool),
+	}
+	seedDefaultCategories(s)
+	return s
+}
+
+// Close is a no-op for the in-memory store
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// now mirrors SQLite's CURRENT_TIMESTAMP (UTC, second precision) on the injectable clock
+func (s *MemoryStore) now() time.Time {
+	return clock.Now().UTC().Truncate(time.Second)
+}
+
+// taskCopy returns a detached copy of a stored task with derived fields filled in
+func (s *MemoryStore) taskCopy(t *Task) Task {
+	task := *t
+	if t.CompletedDate != nil {
+		completed := *t.CompletedDate
+		task.CompletedDate = &completed
+	}
+	task.Priority = copyStringPtr(t.Priority)
+	if t.RecurringID != nil {
+		recurringID := *t.RecurringID
+		task.RecurringID = &recurringID
+	}
+	task.DueDate = copyStringPtr(t.DueDate)
+	if t.DeletedAt != nil {
+		deletedAt := *t.DeletedAt
+		task.DeletedAt = &deletedAt
+	}
+	// A category in the trash leaves its tasks uncategorized for now
+	task.Category = nil
+	task.CategoryID = nil
+	if t.CategoryID != nil {
+		if cat, ok := s.categories[*t.CategoryID]; ok {
+			categoryID := *t.CategoryID
+			task.CategoryID = &categoryID
+			c := *cat
+			c.TaskCount = 0
+			task.Category = &c
+		}
+	}
+	task.DragDays = CalculateBusinessDays(task.CreatedDate, task.AssignedDate)
+	markStale(&task)
+	task.Tags = []Tag{}
+	for tagID := range s.taskTags[t.ID] {
+		if tag, ok := s.tags[tagID]; ok {
+			c := *tag
+			c.TaskCount = 0
+			task.Tags = append(task.Tags, c)
+		}
+	}
+	sortTags(task.Tags)
+	task.Progress = Progress{Total: len(s.items[t.ID])}
+	for _, item := range s.items[t.ID] {
+		if item.IsDone {
+			task.Progress.Done++
+		}
+	}
+	return task
+}
+
+// comparePriority orders P0 before P3 and unset priorities last, like the
+// SQLite ORDER BY
+func comparePriority(a, b *string) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return strings.Compare(*a, *b)
+}
+
+// filterTasks returns copies of the tasks matching keep, ordered by less
+func (s *MemoryStore) filterTasks(keep func(t *Task) bool, less func(a, b *Task) bool) []Task {
+	var matched []*Task
+	for _, t := range s.tasks {
+		if keep(t) {
+			matched = append(matched, t)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		if less(matched[i], matched[j]) {
+			return true
+		}
+		if less(matched[j], matched[i]) {
+			return false
+		}
+		return matched[i].ID < matched[j].ID
+	})
+
+	var tasks []Task
+	for _, t := range matched {
+		tasks = append(tasks, s.taskCopy(t))
+	}
+	return tasks
+}
+
+// Category operations
+
+// CreateCategory creates a new category
+func (s *MemoryStore) CreateCategory(req CategoryRequest) (*Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cat, err := s.createCategory(req)
+	if err != nil {
+		return nil, err
+	}
+
+	c := *cat
+	return &c, nil
+}
+
+// createCategory adds a category; callers hold the write lock
+func (s *MemoryStore) createCategory(req CategoryRequest) (*Category, error) {
+	if s.categoryNameTaken(req.Name, 0) {
+		return nil, ErrDuplicate
+	}
+
+	s.nextCategoryID++
+	cat := &Category{
+		ID:                s.nextCategoryID,
+		Name:              req.Name,
+		Color:             req.Color,
+		RolloverPolicy:    req.RolloverPolicy,
+		ArchiveAfterDays:  copyIntPtr(req.ArchiveAfterDays),
+		StaleAfterDays:    copyIntPtr(req.StaleAfterDays),
+		CriticalAfterDays: copyIntPtr(req.CriticalAfterDays),
+		CreatedAt:         s.now(),
+	}
+	s.categories[cat.ID] = cat
+	return cat, nil
+}
+
+// GetCategoryByID retrieves a category by ID
+func (s *MemoryStore) GetCategoryByID(id int64) (*Category, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cat, ok := s.categories[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	c := *cat
+	return &c, nil
+}
+
+// GetAllCategories retrieves all categories with task counts
+func (s *MemoryStore) GetAllCategories() ([]Category, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var categories []Category
+	for _, cat := range s.categories {
+		c := *cat
+		c.TaskCount = 0
+		for _, t := range s.tasks {
+			if t.CategoryID != nil && *t.CategoryID == c.ID && !t.IsCompleted {
+				c.TaskCount++
+			}
+		}
+		categories = append(categories, c)
+	}
+
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Name < categories[j].Name
+	})
+
+	return categories, nil
+}
+
+// UpdateCategory updates a category
+func (s *MemoryStore) UpdateCategory(id int64, req CategoryRequest) (*Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cat, ok := s.categories[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	if s.categoryNameTaken(req.Name, id) {
+		return nil, ErrDuplicate
+	}
+
+	cat.Name = req.Name
+	cat.Color = req.Color
+	cat.RolloverPolicy = req.RolloverPolicy
+	cat.ArchiveAfterDays = copyIntPtr(req.ArchiveAfterDays)
+	cat.StaleAfterDays = copyIntPtr(req.StaleAfterDays)
+	cat.CriticalAfterDays = copyIntPtr(req.CriticalAfterDays)
+
+	c := *cat
+	return &c, nil
+}
+
+// categoryNameTaken reports whether another category, in the trash or not,
+// has name; callers hold the lock
+func (s *MemoryStore) categoryNameTaken(name string, exceptID int64) bool {
+	for _, categories := range []map[int64]*Category{s.categories, s.categoryTrash} {
+		for _, c := range categories {
+			if c.ID != exceptID && c.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DeleteCategory moves a category to the trash. Its tasks keep their
+// category ID but show as uncategorized until the category is restored.
+func (s *MemoryStore) DeleteCategory(id int64, actor string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cat, ok := s.categories[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	before := s.journalState(nil, []int64{id})
+	s.setCategoryState(categoryState{ID: id, Deleted: true}, actor)
+	s.journal(OpDeleteCategory, operationSummary(OpDeleteCategory, cat.Name, 0), actor, before)
+	return nil
+}
+
+// Task operations
+
+// CreateTask creates a new task from req, assigned to req.Date (default today)
+func (s *MemoryStore) CreateTask(req TaskRequest, actor string) (*Task, error) {
+	date := req.Date
+	if date == "" {
+		date = GetToday()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task := s.taskCopy(s.createTask(req, date, actor))
+	return &task, nil
+}
+
+// createTask is CreateTask with the date already defaulted; callers hold the
+// write lock
+func (s *MemoryStore) createTask(req TaskRequest, date, actor string) *Task {
+	s.nextTaskID++
+	now := s.now()
+	t := &Task{
+		ID:           s.nextTaskID,
+		Title:        req.Title,
+		Description:  req.Description,
+		CreatedDate:  date,
+		AssignedDate: date,
+		Priority:     copyStringPtr(emptyToNil(req.Priority)),
+		DueDate:      copyStringPtr(emptyToNil(req.DueDate)),
+		CategoryID:   copyInt64Ptr(req.CategoryID),
+		RecurringID:  copyInt64Ptr(req.RecurringID),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	s.tasks[t.ID] = t
+	s.recordEvent(t.ID, EventCreated, nil, &date, actor)
+
+	// Tasks materialized from recurring templates are not the user's doing
+	if req.RecurringID == nil {
+		before := s.journalState([]int64{t.ID}, nil)
+		before.Tasks[0].Deleted = true
+		s.journal(OpCreateTask, operationSummary(OpCreateTask, req.Title, 1), actor, before)
+	}
+	return t
+}
+
+// GetTaskByID retrieves a task by ID
+func (s *MemoryStore) GetTaskByID(id int64) (*Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.tasks[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	task := s.taskCopy(t)
+	return &task, nil
+}
+
+// GetTasksByDate retrieves all tasks for a specific date
+func (s *MemoryStore) GetTasksByDate(date string) ([]Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.filterTasks(
+		func(t *Task) bool {
+			return t.AssignedDate == date || (t.IsCompleted && t.CompletedDate != nil && *t.CompletedDate == date)
+		},
+		func(a, b *Task) bool {
+			if a.IsCompleted != b.IsCompleted {
+				return !a.IsCompleted
+			}
+			if c := comparePriority(a.Priority, b.Priority); c != 0 {
+				return c < 0
+			}
+			return a.CreatedAt.Before(b.CreatedAt)
+		},
+	), nil
+}
+
+// GetTasksByCategory retrieves all incomplete tasks for a specific category
+func (s *MemoryStore) GetTasksByCategory(categoryID int64) ([]Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.categories[categoryID]; !ok {
+		return nil, nil
+	}
+
+	return s.filterTasks(
+		func(t *Task) bool {
+			return t.CategoryID != nil && *t.CategoryID == categoryID && !t.IsCompleted
+		},
+		func(a, b *Task) bool {
+			if a.AssignedDate != b.AssignedDate {
+				return a.AssignedDate < b.AssignedDate
+			}
+			return a.CreatedAt.Before(b.CreatedAt)
+		},
+	), nil
+}
+
+// GetCompletedTasksForDate retrieves tasks that were completed on a specific date
+func (s *MemoryStore) GetCompletedTasksForDate(date string) ([]Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.filterTasks(
+		func(t *Task) bool {
+			return t.IsCompleted && t.CompletedDate != nil && *t.CompletedDate == date
+		},
+		func(a, b *Task) bool {
+			return a.CreatedAt.Before(b.CreatedAt)
+		},
+	), nil
+}
+
+// GetOverdueTasks retrieves incomplete tasks whose due date is before today,
+// most overdue first
+func (s *MemoryStore) GetOverdueTasks(today string) ([]Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.filterTasks(
+		func(t *Task) bool {
+			return isOverdue(*t, today)
+		},
+		func(a, b *Task) bool {
+			if *a.DueDate != *b.DueDate {
+				return *a.DueDate < *b.DueDate
+			}
+			if c := comparePriority(a.Priority, b.Priority); c != 0 {
+				return c < 0
+			}
+			return a.CreatedAt.Before(b.CreatedAt)
+		},
+	), nil
+}
+
+// GetTasksByIDs retrieves the tasks with the given IDs, skipping missing ones
+func (s *MemoryStore) GetTasksByIDs(ids []int64) ([]Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	wanted := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	return s.filterTasks(
+		func(t *Task) bool { return wanted[t.ID] },
+		func(a, b *Task) bool { return false },
+	), nil
+}
+
+// GetTasksByIDsIncludingTrash is GetTasksByIDs with the tasks in the trash
+func (s *MemoryStore) GetTasksByIDsIncludingTrash(ids []int64) ([]Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var tasks []Task
+	for _, id := range sortedIDs(ids) {
+		if t, ok := s.tasks[id]; ok {
+			tasks = append(tasks, s.taskCopy(t))
+		} else if t, ok := s.trash[id]; ok {
+			tasks = append(tasks, s.taskCopy(t))
+		}
+	}
+	return tasks, nil
+}
+
+// GetTaskIDsTouchingDate finds every task, deleted ones included, that was
+// created, assigned or completed on date according to its row or its events
+func (s *MemoryStore) GetTaskIDsTouchingDate(date string) ([]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[int64]bool)
+	for _, tasks := range []map[int64]*Task{s.tasks, s.trash} {
+		for _, t := range tasks {
+			if t.CreatedDate == date || t.AssignedDate == date || (t.CompletedDate != nil && *t.CompletedDate == date) {
+				seen[t.ID] = true
+			}
+		}
+	}
+	for _, e := range s.events {
+		switch e.Type {
+		case EventCreated, EventRolledOver, EventMoved, EventCompleted, EventDeleted, EventRestored:
+			if (e.OldValue != nil && *e.OldValue == date) || (e.NewValue != nil && *e.NewValue == date) {
+				seen[e.TaskID] = true
+			}
+		}
+	}
+
+	var ids []int64
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+// GetAllDates retrieves all unique dates that have tasks
+func (s *MemoryStore) GetAllDates() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var dates []string
+	add := func(date string) {
+		if !seen[date] {
+			seen[date] = true
+			dates = append(dates, date)
+		}
+	}
+
+	for _, t := range s.tasks {
+		add(t.AssignedDate)
+		if t.CompletedDate != nil {
+			add(*t.CompletedDate)
+		}
+	}
+
+	sort.Sort(sort.Reverse(sort.StringSlice(dates)))
+	return dates, nil
+}
+
+// GetHistorySummaries retrieves completion stats for all dates
+func (s *MemoryStore) GetHistorySummaries() ([]HistorySummary, error) {
+	dates, err := s.GetAllDates()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var summaries []HistorySummary
+	for _, date := range dates {
+		summary := HistorySummary{Date: date}
+		for _, t := range s.tasks {
+			if t.IsCompleted && t.CompletedDate != nil && *t.CompletedDate == date {
+				summary.CompletedCount++
+			}
+			if !t.IsCompleted && t.AssignedDate == date {
+				summary.PendingCount++
+			}
+		}
+
+		if summary.CompletedCount > 0 || summary.PendingCount > 0 {
+			summaries = append(summaries, summary)
+		}
+	}
+
+	return summaries, nil
+}
+
+// updateTask applies fn to a stored task, journals the change as an
+// operation of kind and returns the updated copy
+func (s *MemoryStore) updateTask(id int64, kind, actor string, fn func(t *Task)) (*Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tasks[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	before := s.journalState([]int64{id}, nil)
+	fn(t)
+	t.UpdatedAt = s.now()
+	s.journal(kind, operationSummary(kind, before.Tasks[0].Title, 1), actor, before)
+
+	task := s.taskCopy(t)
+	return &task, nil
+}
+
+// UpdateTask updates a task's title and description, and its priority and
+// due date when they are set in req (an empty string clears them)
+func (s *MemoryStore) UpdateTask(id int64, req TaskRequest, actor string) (*Task, error) {
+	return s.updateTask(id, OpUpdateTask, actor, func(t *Task) {
+		s.setFields(t, req, actor)
+	})
+}
+
+// setFields applies an update request to a stored task; callers hold the
+// write lock
+func (s *MemoryStore) setFields(t *Task, req TaskRequest, actor string) {
+	if req.Title != t.Title {
+		oldTitle := t.Title
+		s.recordEvent(t.ID, EventTitleChanged, &oldTitle, &req.Title, actor)
+	}
+	if req.Description != t.Description {
+		oldDescription := t.Description
+		s.recordEvent(t.ID, EventDescriptionChanged, &oldDescription, &req.Description, actor)
+	}
+	t.Title = req.Title
+	t.Description = req.Description
+
+	if req.Priority != nil {
+		priority := copyStringPtr(emptyToNil(req.Priority))
+		if !sameStringPtr(t.Priority, priority) {
+			s.recordEvent(t.ID, EventPriorityChanged, t.Priority, priority, actor)
+		}
+		t.Priority = priority
+	}
+	if req.DueDate != nil {
+		dueDate := copyStringPtr(emptyToNil(req.DueDate))
+		if !sameStringPtr(t.DueDate, dueDate) {
+			s.recordEvent(t.ID, EventDueDateChanged, t.DueDate, dueDate, actor)
+		}
+		t.DueDate = dueDate
+	}
+}
+
+// UpdateTaskCompletion marks a task as completed on completedDate or not completed
+func (s *MemoryStore) UpdateTaskCompletion(id int64, isCompleted bool, completedDate, actor string) (*Task, error) {
+	if isCompleted && completedDate == "" {
+		completedDate = GetToday()
+	}
+
+	kind := OpCompleteTask
+	if !isCompleted {
+		kind = OpUncompleteTask
+	}
+	return s.updateTask(id, kind, actor, func(t *Task) {
+		s.setCompletion(t, isCompleted, completedDate, actor)
+	})
+}
+
+// setCompletion marks a stored task completed on completedDate or not
+// completed; callers hold the write lock
+func (s *MemoryStore) setCompletion(t *Task, isCompleted bool, completedDate, actor string) {
+	switch {
+	case isCompleted && (!t.IsCompleted || t.CompletedDate == nil || *t.CompletedDate != completedDate):
+		s.recordEvent(t.ID, EventCompleted, t.CompletedDate, &completedDate, actor)
+	case !isCompleted && t.IsCompleted:
+		s.recordEvent(t.ID, EventUncompleted, t.CompletedDate, nil, actor)
+	}
+
+	t.IsCompleted = isCompleted
+	t.CompletedDate = nil
+	if isCompleted {
+		t.CompletedDate = &completedDate
+	}
+}
+
+// UpdateTaskCategory updates a task's category
+func (s *MemoryStore) UpdateTaskCategory(id int64, categoryID *int64, actor string) (*Task, error) {
+	if categoryID != nil {
+		if _, err := s.GetCategoryByID(*categoryID); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.updateTask(id, OpSetCategory, actor, func(t *Task) {
+		s.setCategory(t, categoryID, actor)
+	})
+}
+
+// setCategory moves a stored task into a category; callers hold the write lock
+func (s *MemoryStore) setCategory(t *Task, categoryID *int64, actor string) {
+	if !sameInt64Ptr(t.CategoryID, categoryID) {
+		s.recordEvent(t.ID, EventCategoryChanged, formatIDPtr(t.CategoryID), formatIDPtr(categoryID), actor)
+	}
+
+	t.CategoryID = copyInt64Ptr(categoryID)
+}
+
+// rollover reassigns the incomplete tasks matching keep that opts selects,
+// rolling each over according to its category's policy. Tasks whose policy
+// archives them go to the trash. A dry run only lists the moves.
+func (s *MemoryStore) rollover(toDate string, opts RolloverOptions, actor string, keep func(t *Task) bool) []RolloverMove {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var matched []*Task
+	for _, t := range s.tasks {
+		if t.IsCompleted || !keep(t) || !s.rollable(t) {
+			continue
+		}
+		if opts.selects(t.ID, s.shownCategoryID(t)) {
+			matched = append(matched, t)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].AssignedDate != matched[j].AssignedDate {
+			return matched[i].AssignedDate < matched[j].AssignedDate
+		}
+		return matched[i].ID < matched[j].ID
+	})
+
+	var moves []RolloverMove
+	var ids []int64
+	for _, t := range matched {
+		m := RolloverMove{
+			TaskID:     t.ID,
+			Title:      t.Title,
+			CategoryID: s.shownCategoryID(t),
+			FromDate:   t.AssignedDate,
+			ToDate:     toDate,
+		}
+
+		// Uncategorized tasks roll to the next day
+		policy, archiveAfterDays := "", (*int)(nil)
+		if m.CategoryID != nil {
+			cat := s.categories[*m.CategoryID]
+			policy, archiveAfterDays = cat.RolloverPolicy, cat.ArchiveAfterDays
+		}
+		if planRolloverMove(&m, t.CreatedDate, policy, archiveAfterDays) {
+			moves = append(moves, m)
+			ids = append(ids, t.ID)
+		}
+	}
+	if opts.DryRun || len(moves) == 0 {
+		return moves
+	}
+
+	before := s.journalState(ids, nil)
+	now := s.now()
+	for _, m := range moves {
+		t := s.tasks[m.TaskID]
+		if m.Action == RolloverArchived {
+			s.deleteTask(t, actor)
+			continue
+		}
+		oldDate, newDate := t.AssignedDate, m.ToDate
+		s.recordEvent(t.ID, EventRolledOver, &oldDate, &newDate, actor)
+		t.AssignedDate = newDate
+		t.UpdatedAt = now
+	}
+	s.journal(OpRollover, operationSummary(OpRollover, toDate, len(moves)), actor, before)
+	return moves
+}
+
+// shownCategoryID is a stored task's category ID, or nil while the category
+// is in the trash; callers hold the lock
+func (s *MemoryStore) shownCategoryID(t *Task) *int64 {
+	if t.CategoryID == nil || s.categories[*t.CategoryID] == nil {
+		return nil
+	}
+	return copyInt64Ptr(t.CategoryID)
+}
+
+// rollable leaves out occurrences of recurring tasks whose missed policy is to
+// skip rather than roll; callers hold the lock
+func (s *MemoryStore) rollable(t *Task) bool {
+	if t.RecurringID == nil {
+		return true
+	}
+	rt, ok := s.recurring[*t.RecurringID]
+	return !ok || rt.MissedPolicy != MissedSkip
+}
+
+// RolloverTasks moves incomplete tasks from one date to another
+func (s *MemoryStore) RolloverTasks(fromDate, toDate string, opts RolloverOptions, actor string) ([]RolloverMove, error) {
+	return s.rollover(toDate, opts, actor, func(t *Task) bool {
+		return t.AssignedDate == fromDate
+	}), nil
+}
+
+// RolloverAllPendingTasks moves ALL incomplete tasks from any past date to today
+func (s *MemoryStore) RolloverAllPendingTasks(toDate string, opts RolloverOptions, actor string) ([]RolloverMove, error) {
+	return s.rollover(toDate, opts, actor, func(t *Task) bool {
+		return t.AssignedDate < toDate
+	}), nil
+}
+
+// DeleteTask moves a task to the trash
+func (s *MemoryStore) DeleteTask(id int64, actor string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tasks[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	before := s.journalState([]int64{id}, nil)
+	s.deleteTask(t, actor)
+	t.UpdatedAt = s.now()
+	s.journal(OpDeleteTask, operationSummary(OpDeleteTask, t.Title, 1), actor, before)
+	return nil
+}
+
+// deleteTask moves a stored task to the trash. Its checklist and tags stay
+// with it so a restore brings them back; callers hold the write lock.
+func (s *MemoryStore) deleteTask(t *Task, actor string) {
+	assignedDate, title := t.AssignedDate, t.Title
+	s.recordEvent(t.ID, EventDeleted, &assignedDate, &title, actor)
+	now := s.now()
+	t.DeletedAt = &now
+	delete(s.tasks, t.ID)
+	s.trash[t.ID] = t
+}
+
+// BulkUpdateTasks applies ops to their tasks in order under one lock. Items
+// only fail before they change anything; with allOrNothing any failure
+// restores the state from before the batch and fails every item.
+func (s *MemoryStore) BulkUpdateTasks(ops []BulkOperation, allOrNothing bool, actor string) ([]BulkResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var saved *memorySnapshot
+	if allOrNothing {
+		saved = s.snapshot()
+	}
+
+	var ids []int64
+	for _, op := range ops {
+		ids = append(ids, op.TaskIDs...)
+	}
+	before := s.journalState(ids, nil)
+
+	var results []BulkResult
+	failed := false
+	now := s.now()
+	for i, op := range ops {
+		for _, id := range op.TaskIDs {
+			result := BulkResult{Operation: i, Op: op.Op, TaskID: id}
+
+			if t, ok := s.tasks[id]; !ok {
+				result.Error = bulkErrorMessage(ErrNotFound)
+				failed = true
+			} else {
+				s.applyBulkOperation(t, op, actor)
+				t.UpdatedAt = now
+				result.OK = true
+			}
+
+			results = append(results, result)
+		}
+	}
+
+	if allOrNothing && failed {
+		s.restore(saved)
+		return rollBackBulkResults(results), nil
+	}
+
+	succeeded := 0
+	for _, r := range results {
+		if r.OK {
+			succeeded++
+		}
+	}
+	s.journal(OpBulk, operationSummary(OpBulk, "", succeeded), actor, before)
+
+	var tasks []Task
+	for _, r := range results {
+		if t, ok := s.tasks[r.TaskID]; ok {
+			tasks = append(tasks, s.taskCopy(t))
+		}
+	}
+	return attachBulkTasks(results, tasks), nil
+}
+
+// applyBulkOperation applies one bulk operation to a stored task; callers
+// hold the write lock
+func (s *MemoryStore) applyBulkOperation(t *Task, op BulkOperation, actor string) {
+	switch op.Op {
+	case BulkComplete:
+		s.setCompletion(t, true, op.Date, actor)
+	case BulkUncomplete:
+		s.setCompletion(t, false, "", actor)
+	case BulkDelete:
+		s.deleteTask(t, actor)
+	case BulkSetCategory:
+		s.setCategory(t, op.CategoryID, actor)
+	case BulkMoveToDate:
+		if t.AssignedDate != op.Date {
+			oldDate, newDate := t.AssignedDate, op.Date
+			s.recordEvent(t.ID, EventMoved, &oldDate, &newDate, actor)
+			t.AssignedDate = newDate
+		}
+	case BulkUpdate:
+		s.setFields(t, op.Fields.taskRequest(t.Title, t.Description), actor)
+	}
+}
+
+// memorySnapshot holds what a bulk operation or a todo.txt import can change
+type memorySnapshot struct {
+	tasks      map[int64]Task
+	trash      map[int64]Task
+	categories map[int64]*Category
+	items      map[int64][]*TaskItem
+	tags       map[int64]*Tag
+	taskTags   map[int64]map[int64]bool
+	events     int
+}
+
+// snapshot captures the tasks, the trash, the categories, checklists and
+// tags and the event log length; callers hold the write lock
+func (s *MemoryStore) snapshot() *memorySnapshot {
+	saved := &memorySnapshot{
+		tasks:      make(map[int64]Task, len(s.tasks)),
+		trash:      make(map[int64]Task, len(s.trash)),
+		categories: make(map[int64]*Category, len(s.categories)),
+		items:      make(map[int64][]*TaskItem, len(s.items)),
+		tags:       make(map[int64]*Tag, len(s.tags)),
+		taskTags:   make(map[int64]map[int64]bool, len(s.taskTags)),
+		events:     len(s.events),
+	}
+	for id, t := range s.tasks {
+		saved.tasks[id] = *t
+	}
+	for id, t := range s.trash {
+		saved.trash[id] = *t
+	}
+	for id, c := range s.categories {
+		saved.categories[id] = c
+	}
+	for id, items := range s.items {
+		saved.items[id] = items
+	}
+	for id, tag := range s.tags {
+		saved.tags[id] = tag
+	}
+	for id, tags := range s.taskTags {
+		saved.taskTags[id] = make(map[int64]bool, len(tags))
+		for tagID := range tags {
+			saved.taskTags[id][tagID] = true
+		}
+	}
+	return saved
+}
+
+// restore puts back a snapshot; callers hold the write lock
+func (s *MemoryStore) restore(saved *memorySnapshot) {
+	s.tasks = make(map[int64]*Task, len(saved.tasks))
+	for id, t := range saved.tasks {
+		t := t
+		s.tasks[id] = &t
+	}
+	s.trash = make(map[int64]*Task, len(saved.trash))
+	for id, t := range saved.trash {
+		t := t
+		s.trash[id] = &t
+	}
+	s.categories = saved.categories
+	s.items = saved.items
+	s.tags = saved.tags
+	s.taskTags = saved.taskTags
+	s.events = s.events[:saved.events]
+}
+
+// SearchTasks finds tasks whose title or description match q.Text, most
+// relevant first
+func (s *MemoryStore) SearchTasks(q SearchQuery) ([]SearchResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tasks := s.filterTasks(
+		func(t *Task) bool { return true },
+		func(a, b *Task) bool { return false },
+	)
+	return rankSearchResults(tasks, q), nil
+}
+
+// QueryTasks returns the page of tasks matching q
+func (s *MemoryStore) QueryTasks(q TaskQuery) (*TaskPage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tasks := s.filterTasks(
+		func(t *Task) bool { return true },
+		func(a, b *Task) bool { return false },
+	)
+	return pageTasks(tasks, q), nil
+}
+
+// EachTask calls fn with each task matching q in q's sort order. The page is
+// copied first so fn runs unlocked.
+func (s *MemoryStore) EachTask(q TaskQuery, fn func(Task) error) error {
+	page, err := s.QueryTasks(q)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range page.Tasks {
+		if err := fn(task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Import plans and applies an import under one lock
+func (s *MemoryStore) Import(doc ImportDocument, opts ImportOptions, actor string) (*ImportReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report, before := s.importDocument(doc, opts, actor)
+	if report.Committed {
+		s.journal(OpImport, operationSummary(OpImport, "", len(before.Tasks)), actor, before)
+	}
+	return report, nil
+}
+
+// importDocument plans and applies an import without journaling it. It
+// returns the report and, when applied, the state undoing the import puts
+// back: everything it created in the trash. Callers hold the write lock.
+func (s *MemoryStore) importDocument(doc ImportDocument, opts ImportOptions, actor string) (*ImportReport, journalState) {
+	var live, trashed []Category
+	for _, c := range s.categories {
+		live = append(live, *c)
+	}
+	for _, c := range s.categoryTrash {
+		trashed = append(trashed, *c)
+	}
+	sort.Slice(live, func(i, j int) bool { return live[i].ID < live[j].ID })
+	sort.Slice(trashed, func(i, j int) bool { return trashed[i].ID < trashed[j].ID })
+
+	existing := make(map[importTaskKey]bool)
+	for _, t := range s.tasks {
+		key := importTaskKey{Title: t.Title, CreatedDate: t.CreatedDate}
+		if t.CategoryID != nil {
+			key.CategoryID = *t.CategoryID
+		}
+		existing[key] = true
+	}
+
+	report := planImport(doc, opts, live, trashed, existing)
+	if !report.canApply() {
+		return report, journalState{}
+	}
+
+	var categoryIDs, taskIDs []int64
+	for i := range report.Categories {
+		c := &report.Categories[i]
+		if c.Action != ImportCreated {
+			continue
+		}
+		s.nextCategoryID++
+		cat := &Category{
+			ID:                s.nextCategoryID,
+			Name:              c.request.Name,
+			Color:             c.request.Color,
+			RolloverPolicy:    c.request.RolloverPolicy,
+			ArchiveAfterDays:  copyIntPtr(c.request.ArchiveAfterDays),
+			StaleAfterDays:    copyIntPtr(c.request.StaleAfterDays),
+			CriticalAfterDays: copyIntPtr(c.request.CriticalAfterDays),
+			CreatedAt:         importTimestamp(c.createdAt),
+		}
+		s.categories[cat.ID] = cat
+		id := cat.ID
+		c.ID = &id
+		categoryIDs = append(categoryIDs, id)
+	}
+	report.linkCategories()
+
+	for i := range report.Tasks {
+		t := &report.Tasks[i]
+		if t.Action != ImportCreated {
+			continue
+		}
+		s.nextTaskID++
+		task := &Task{
+			ID:            s.nextTaskID,
+			Title:         t.task.Title,
+			Description:   t.task.Description,
+			CreatedDate:   t.task.CreatedDate,
+			AssignedDate:  t.task.AssignedDate,
+			CompletedDate: copyStringPtr(t.task.CompletedDate),
+			IsCompleted:   t.task.IsCompleted,
+			Priority:      copyStringPtr(t.task.Priority),
+			DueDate:       copyStringPtr(t.task.DueDate),
+			CategoryID:    copyInt64Ptr(report.categoryID(*t)),
+			CreatedAt:     importTimestamp(t.task.CreatedAt),
+			UpdatedAt:     importTimestamp(t.task.UpdatedAt),
+		}
+		s.tasks[task.ID] = task
+		id := task.ID
+		t.ID = &id
+		taskIDs = append(taskIDs, id)
+
+		for _, e := range importedTaskEvents(t.task) {
+			s.recordEvent(id, e.Type, e.OldValue, e.NewValue, actor)
+		}
+		s.importTagsAndItems(id, t.task)
+	}
+
+	// Undoing the import sends everything it created to the trash
+	before := s.journalState(taskIDs, categoryIDs)
+	for i := range before.Tasks {
+		before.Tasks[i].Deleted = true
+	}
+	for i := range before.Categories {
+		before.Categories[i].Deleted = true
+	}
+	report.Committed = true
+	return report, before
+}
+
+// importTagsAndItems gives an imported task its tags, creating the ones not
+// yet stored, and its checklist; callers hold the write lock
+func (s *MemoryStore) importTagsAndItems(taskID int64, t ExportTask) {
+	now := s.now()
+	for _, name := range t.Tags {
+		tag := s.tagNamed(name)
+		if s.taskTags[taskID] == nil {
+			s.taskTags[taskID] = make(map[int64]bool)
+		}
+		s.taskTags[taskID][tag.ID] = true
+	}
+
+	for _, item := range t.Items {
+		s.nextItemID++
+		s.items[taskID] = append(s.items[taskID], &TaskItem{
+			ID:        s.nextItemID,
+			TaskID:    taskID,
+			Title:     item.Title,
+			IsDone:    item.IsDone,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+}
+
+// ImportTodoTxt applies a todo.txt file under one lock, journaled as one
+// operation. A line that fails puts back the state from before the file.
+func (s *MemoryStore) ImportTodoTxt(lines []TodoTxtTask, today, actor string) (*TodoTxtResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var categories []Category
+	for _, c := range s.categories {
+		categories = append(categories, *c)
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].ID < categories[j].ID })
+	var tags []Tag
+	for _, tag := range s.tags {
+		tags = append(tags, *tag)
+	}
+	sortTags(tags)
+	stored := make(map[int64]*Task)
+	for _, line := range lines {
+		if line.ID == nil {
+			continue
+		}
+		if t, ok := s.tasks[*line.ID]; ok {
+			task := s.taskCopy(t)
+			stored[task.ID] = &task
+		}
+	}
+
+	plan, err := planTodoTxt(lines, categories, tags, stored, today)
+	if err != nil {
+		return nil, err
+	}
+	saved := s.snapshot()
+	before := s.journalState(plan.taskIDs(), nil)
+
+	apply := &memoryTodoTxt{s: s, actor: actor}
+	result, err := applyTodoTxt(apply, plan)
+	if err != nil {
+		s.restore(saved)
+		return nil, err
+	}
+	s.journal(OpTodoTxt, operationSummary(OpTodoTxt, "", result.Created+result.Updated), actor, before.merge(apply.created))
+	return result, nil
+}
+
+// memoryTodoTxt applies a todo.txt import under the store's write lock,
+// keeping the state that undoing what it creates puts back
+type memoryTodoTxt struct {
+	s       *MemoryStore
+	actor   string
+	created journalState
+}
+
+func (a *memoryTodoTxt) createCategory(req CategoryRequest) (*Category, error) {
+	cat, err := a.s.createCategory(req)
+	if err != nil {
+		return nil, err
+	}
+	a.created.Categories = append(a.created.Categories, categoryState{ID: cat.ID, Deleted: true})
+	c := *cat
+	return &c, nil
+}
+
+func (a *memoryTodoTxt) applyBulkOperation(op BulkOperation, taskID int64) error {
+	t, ok := a.s.tasks[taskID]
+	if !ok {
+		return ErrNotFound
+	}
+	a.s.applyBulkOperation(t, op, a.actor)
+	t.UpdatedAt = a.s.now()
+	return nil
+}
+
+func (a *memoryTodoTxt) addTaskTag(taskID int64, name string) error {
+	a.s.addTaskTag(taskID, a.s.tagNamed(name), a.actor)
+	return nil
+}
+
+func (a *memoryTodoTxt) removeTaskTag(taskID int64, tag Tag) error {
+	if stored, ok := a.s.tags[tag.ID]; ok {
+		a.s.removeTaskTag(taskID, stored, a.actor)
+	}
+	return nil
+}
+
+func (a *memoryTodoTxt) importDocument(doc ImportDocument) (*ImportReport, error) {
+	report, created := a.s.importDocument(doc, ImportOptions{Categories: ImportMerge}, a.actor)
+	a.created = a.created.merge(created)
+	return report, nil
+}
+
+// Trash and journal operations
+
+// memoryOperation is a journaled operation with the states undo and redo
+// put back
+type memoryOperation struct {
+	Operation
+	before, after journalState
+}
+
+// taskStateOf snapshots a stored task
+func taskStateOf(t *Task) taskState {
+	return taskState{
+		ID:            t.ID,
+		Title:         t.Title,
+		Description:   t.Description,
+		AssignedDate:  t.AssignedDate,
+		CompletedDate: copyStringPtr(t.CompletedDate),
+		IsCompleted:   t.IsCompleted,
+		CategoryID:    copyInt64Ptr(t.CategoryID),
+		Priority:      copyStringPtr(t.Priority),
+		DueDate:       copyStringPtr(t.DueDate),
+		Deleted:       t.DeletedAt != nil,
+	}
+}
+
+// findTask looks a task up whether or not it is in the trash; callers hold
+// the lock
+func (s *MemoryStore) findTask(id int64) (*Task, bool) {
+	if t, ok := s.tasks[id]; ok {
+		return t, true
+	}
+	t, ok := s.trash[id]
+	return t, ok
+}
+
+// journalState snapshots the given tasks and categories, live or in the
+// trash, ordered by ID; callers hold the lock
+func (s *MemoryStore) journalState(taskIDs, categoryIDs []int64) journalState {
+	var state journalState
+
+	seen := make(map[int64]bool)
+	for _, id := range taskIDs {
+		if t, ok := s.findTask(id); ok && !seen[id] {
+			seen[id] = true
+			state.Tasks = append(state.Tasks, taskStateOf(t))
+		}
+	}
+	sort.Slice(state.Tasks, func(i, j int) bool { return state.Tasks[i].ID < state.Tasks[j].ID })
+
+	seen = make(map[int64]bool)
+	for _, id := range categoryIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if _, ok := s.categories[id]; ok {
+			state.Categories = append(state.Categories, categoryState{ID: id})
+		} else if _, ok := s.categoryTrash[id]; ok {
+			state.Categories = append(state.Categories, categoryState{ID: id, Deleted: true})
+		}
+	}
+	sort.Slice(state.Categories, func(i, j int) bool { return state.Categories[i].ID < state.Categories[j].ID })
+
+	return state
+}
+
+// setTaskState puts a task back into a journaled state, recording the events
+// of each change. Tasks purged from the trash since are skipped; callers hold
+// the write lock.
+func (s *MemoryStore) setTaskState(to taskState, actor string) {
+	t, ok := s.findTask(to.ID)
+	if !ok {
+		return
+	}
+	events := taskTransition(taskStateOf(t), to)
+	if len(events) == 0 {
+		return
+	}
+
+	t.Title = to.Title
+	t.Description = to.Description
+	t.AssignedDate = to.AssignedDate
+	t.IsCompleted = to.IsCompleted
+	t.CompletedDate = nil
+	if to.IsCompleted {
+		t.CompletedDate = copyStringPtr(to.CompletedDate)
+	}
+	t.CategoryID = copyInt64Ptr(to.CategoryID)
+	t.Priority = copyStringPtr(to.Priority)
+	t.DueDate = copyStringPtr(to.DueDate)
+
+	now := s.now()
+	switch {
+	case to.Deleted && t.DeletedAt == nil:
+		t.DeletedAt = &now
+		delete(s.tasks, t.ID)
+		s.trash[t.ID] = t
+	case !to.Deleted && t.DeletedAt != nil:
+		t.DeletedAt = nil
+		delete(s.trash, t.ID)
+		s.tasks[t.ID] = t
+	}
+	t.UpdatedAt = now
+
+	for _, e := range events {
+		s.recordEvent(t.ID, e.Type, e.OldValue, e.NewValue, actor)
+	}
+}
+
+// setCategoryState moves a category into or out of the trash. Its tasks get
+// a category_changed event, as they gain or lose the category; callers hold
+// the write lock.
+func (s *MemoryStore) setCategoryState(to categoryState, actor string) {
+	from, into := s.categories, s.categoryTrash
+	if !to.Deleted {
+		from, into = s.categoryTrash, s.categories
+	}
+	cat, ok := from[to.ID]
+	if !ok {
+		return
+	}
+
+	var ids []int64
+	for _, t := range s.tasks {
+		if t.CategoryID != nil && *t.CategoryID == to.ID {
+			ids = append(ids, t.ID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	categoryID := formatIDPtr(&to.ID)
+	for _, id := range ids {
+		if to.Deleted {
+			s.recordEvent(id, EventCategoryChanged, categoryID, nil, actor)
+		} else {
+			s.recordEvent(id, EventCategoryChanged, nil, categoryID, actor)
+		}
+	}
+
+	cat.DeletedAt = nil
+	if to.Deleted {
+		now := s.now()
+		cat.DeletedAt = &now
+	}
+	delete(from, to.ID)
+	into[to.ID] = cat
+}
+
+// journal records an operation that took the things in before to their
+// current state. Operations that changed nothing are left out, and a new
+// operation discards whatever could have been redone; callers hold the write
+// lock.
+func (s *MemoryStore) journal(kind, summary, actor string, before journalState) {
+	after := s.journalState(before.taskIDs(), before.categoryIDs())
+	if sameJournalState(before, after) {
+		return
+	}
+
+	kept := s.operations[:0]
+	for _, op := range s.operations {
+		if !op.Undone {
+			kept = append(kept, op)
+		}
+	}
+	s.nextOperation++
+	s.operations = append(kept, memoryOperation{
+		Operation: Operation{
+			ID:        s.nextOperation,
+			Kind:      kind,
+			Summary:   summary,
+			Actor:     actor,
+			TaskCount: len(after.Tasks),
+			CreatedAt: s.now(),
+		},
+		before: before,
+		after:  after,
+	})
+	if len(s.operations) > journalSize {
+		s.operations = s.operations[len(s.operations)-journalSize:]
+	}
+}
+
+// RestoreTask takes a task out of the trash
+func (s *MemoryStore) RestoreTask(id int64, actor string) (*Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.trash[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	before := s.journalState([]int64{id}, nil)
+	restored := before.Tasks[0]
+	restored.Deleted = false
+	s.setTaskState(restored, actor)
+	s.journal(OpRestoreTask, operationSummary(OpRestoreTask, t.Title, 1), actor, before)
+
+	task := s.taskCopy(t)
+	return &task, nil
+}
+
+// RestoreCategory takes a category out of the trash, giving its tasks their
+// category back
+func (s *MemoryStore) RestoreCategory(id int64, actor string) (*Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cat, ok := s.categoryTrash[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	before := s.journalState(nil, []int64{id})
+	s.setCategoryState(categoryState{ID: id}, actor)
+	s.journal(OpRestoreCategory, operationSummary(OpRestoreCategory, cat.Name, 0), actor, before)
+
+	c := *cat
+	return &c, nil
+}
+
+// GetTrash lists deleted tasks and categories, most recently deleted first
+func (s *MemoryStore) GetTrash() (*Trash, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	trash := &Trash{Tasks: []Task{}, Categories: []Category{}}
+	for _, t := range s.trash {
+		trash.Tasks = append(trash.Tasks, s.taskCopy(t))
+	}
+	sort.Slice(trash.Tasks, func(i, j int) bool {
+		a, b := trash.Tasks[i], trash.Tasks[j]
+		if !a.DeletedAt.Equal(*b.DeletedAt) {
+			return a.DeletedAt.After(*b.DeletedAt)
+		}
+		return a.ID > b.ID
+	})
+
+	for _, cat := range s.categoryTrash {
+		c := *cat
+		deletedAt := *cat.DeletedAt
+		c.DeletedAt = &deletedAt
+		trash.Categories = append(trash.Categories, c)
+	}
+	sort.Slice(trash.Categories, func(i, j int) bool {
+		a, b := trash.Categories[i], trash.Categories[j]
+		if !a.DeletedAt.Equal(*b.DeletedAt) {
+			return a.DeletedAt.After(*b.DeletedAt)
+		}
+		return a.ID > b.ID
+	})
+
+	return trash, nil
+}
+
+// EmptyTrash permanently deletes everything in the trash and returns how
+// many tasks and categories went. Task history is kept.
+func (s *MemoryStore) EmptyTrash() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := len(s.trash) + len(s.categoryTrash)
+	for id := range s.trash {
+		delete(s.items, id)
+		delete(s.taskTags, id)
+	}
+	s.trash = make(map[int64]*Task)
+
+	for _, t := range s.tasks {
+		if t.CategoryID != nil && s.categoryTrash[*t.CategoryID] != nil {
+			t.CategoryID = nil
+		}
+	}
+	for _, rt := range s.recurring {
+		if rt.CategoryID != nil && s.categoryTrash[*rt.CategoryID] != nil {
+			rt.CategoryID = nil
+		}
+	}
+	s.categoryTrash = make(map[int64]*Category)
+
+	return purged, nil
+}
+
+// GetOperations lists the most recent journaled operations, newest first
+func (s *MemoryStore) GetOperations(limit int) ([]Operation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var operations []Operation
+	for i := len(s.operations) - 1; i >= 0 && len(operations) < limit; i-- {
+		operations = append(operations, s.operations[i].Operation)
+	}
+	return operations, nil
+}
+
+// Undo reverts the most recent operation that has not been undone
+func (s *MemoryStore) Undo(actor string) (*Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.operations) - 1; i >= 0; i-- {
+		if op := &s.operations[i]; !op.Undone {
+			s.replay(op.before, actor)
+			op.Undone = true
+			result := op.Operation
+			return &result, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Redo reapplies the earliest undone operation
+func (s *MemoryStore) Redo(actor string) (*Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.operations {
+		if op := &s.operations[i]; op.Undone {
+			s.replay(op.after, actor)
+			op.Undone = false
+			result := op.Operation
+			return &result, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// replay puts everything in state back; callers hold the write lock
+func (s *MemoryStore) replay(state journalState, actor string) {
+	for _, c := range state.Categories {
+		s.setCategoryState(c, actor)
+	}
+	for _, t := range state.Tasks {
+		s.setTaskState(t, actor)
+	}
+}
+
+// Task event operations
+
+// recordEvent appends an entry to a task's history; callers hold the write lock
+func (s *MemoryStore) recordEvent(taskID int64, eventType string, oldValue, newValue *string, actor string) {
+	s.events = append(s.events, TaskEvent{
+		ID:        int64(len(s.events) + 1),
+		TaskID:    taskID,
+		Type:      eventType,
+		OldValue:  copyStringPtr(oldValue),
+		NewValue:  copyStringPtr(newValue),
+		Actor:     actor,
+		CreatedAt: s.now(),
+	})
+}
+
+// RecordEscalations records an escalated event for every pending task whose
+// stale level is above the last one recorded for it
+func (s *MemoryStore) RecordEscalations(actor string) ([]Escalation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last := map[int64]string{}
+	for _, e := range s.events {
+		if e.Type == EventEscalated && e.NewValue != nil {
+			last[e.TaskID] = *e.NewValue
+		}
+	}
+
+	var escalations []Escalation
+	for _, t := range s.tasks {
+		task := s.taskCopy(t)
+		level := taskStaleLevel(task)
+		if levelRank(level) <= levelRank(last[t.ID]) {
+			continue
+		}
+
+		var recorded *string
+		if prev, ok := last[t.ID]; ok {
+			recorded = &prev
+		}
+		s.recordEvent(t.ID, EventEscalated, recorded, &level, actor)
+		escalations = append(escalations, Escalation{
+			TaskID:      t.ID,
+			Title:       t.Title,
+			Level:       level,
+			EscalatedAt: s.now(),
+			Outcome:     EscalationDragging,
+			DragDays:    task.DragDays,
+		})
+	}
+
+	sort.Slice(escalations, func(i, j int) bool {
+		return escalations[i].TaskID < escalations[j].TaskID
+	})
+	return escalations, nil
+}
+
+// GetEscalations lists the escalations recorded in [from, to) with what has
+// become of each task since
+func (s *MemoryStore) GetEscalations(from, to time.Time) ([]Escalation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var escalations []Escalation
+	for _, e := range s.events {
+		if e.Type != EventEscalated || e.NewValue == nil || e.CreatedAt.Before(from) || !e.CreatedAt.Before(to) {
+			continue
+		}
+
+		escalation := Escalation{TaskID: e.TaskID, Level: *e.NewValue, EscalatedAt: e.CreatedAt}
+		t, live := s.tasks[e.TaskID]
+		if !live {
+			t = s.trash[e.TaskID]
+		}
+		if t != nil {
+			escalation.Title = t.Title
+			escalation.DragDays = CalculateBusinessDays(t.CreatedDate, t.AssignedDate)
+		}
+		escalation.Outcome = escalationOutcome(t != nil, !live, t != nil && t.IsCompleted)
+		escalations = append(escalations, escalation)
+	}
+
+	return escalations, nil
+}
+
+// GetTaskHistory retrieves every event recorded for a task, oldest first
+func (s *MemoryStore) GetTaskHistory(taskID int64) ([]TaskEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []TaskEvent
+	for _, e := range s.events {
+		if e.TaskID == taskID {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// GetTaskHistories retrieves the events of several tasks keyed by task ID
+func (s *MemoryStore) GetTaskHistories(taskIDs []int64) (map[int64][]TaskEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	wanted := make(map[int64]bool, len(taskIDs))
+	for _, id := range taskIDs {
+		wanted[id] = true
+	}
+
+	histories := make(map[int64][]TaskEvent)
+	for _, e := range s.events {
+		if wanted[e.TaskID] {
+			histories[e.TaskID] = append(histories[e.TaskID], e)
+		}
+	}
+	return histories, nil
+}
+
+func copyInt64Ptr(v *int64) *int64 {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	return &c
+}
+
+func copyIntPtr(v *int) *int {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	return &c
+}
+
+func copyStringPtr(v *string) *string {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	return &c
+}
+
+// Checklist item operations
+
+// itemCopy returns a detached copy of a checklist item at position
+func itemCopy(item *TaskItem, position int) TaskItem {
+	c := *item
+	c.Position = position
+	return c
+}
+
+// findItem locates a task's checklist item; callers hold the lock
+func (s *MemoryStore) findItem(taskID, itemID int64) (int, bool) {
+	if _, ok := s.tasks[taskID]; !ok {
+		return 0, false
+	}
+	for i, item := range s.items[taskID] {
+		if item.ID == itemID {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// CreateTaskItem appends a checklist item to a task
+func (s *MemoryStore) CreateTaskItem(taskID int64, title, actor string) (*TaskItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tasks[taskID]; !ok {
+		return nil, ErrNotFound
+	}
+
+	s.nextItemID++
+	now := s.now()
+	item := &TaskItem{
+		ID:        s.nextItemID,
+		TaskID:    taskID,
+		Title:     title,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.items[taskID] = append(s.items[taskID], item)
+	s.recordEvent(taskID, EventItemAdded, nil, &title, actor)
+
+	c := itemCopy(item, len(s.items[taskID])-1)
+	return &c, nil
+}
+
+// GetTaskItems retrieves a task's checklist in order
+func (s *MemoryStore) GetTaskItems(taskID int64) ([]TaskItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var items []TaskItem
+	for i, item := range s.items[taskID] {
+		items = append(items, itemCopy(item, i))
+	}
+	return items, nil
+}
+
+// GetTaskChecklists retrieves the checklists of several tasks keyed by task ID
+func (s *MemoryStore) GetTaskChecklists(taskIDs []int64) (map[int64][]TaskItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	checklists := make(map[int64][]TaskItem)
+	for _, id := range taskIDs {
+		for i, item := range s.items[id] {
+			checklists[id] = append(checklists[id], itemCopy(item, i))
+		}
+	}
+	return checklists, nil
+}
+
+// UpdateTaskItem renames, checks off or moves a checklist item
+func (s *MemoryStore) UpdateTaskItem(taskID, itemID int64, req TaskItemRequest, actor string) (*TaskItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	position, ok := s.findItem(taskID, itemID)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	list := s.items[taskID]
+	item := list[position]
+
+	if req.Title != nil && *req.Title != item.Title {
+		oldTitle := item.Title
+		s.recordEvent(taskID, EventItemRenamed, &oldTitle, req.Title, actor)
+		item.Title = *req.Title
+	}
+
+	if req.IsDone != nil && *req.IsDone != item.IsDone {
+		eventType := EventItemChecked
+		if !*req.IsDone {
+			eventType = EventItemUnchecked
+		}
+		title := item.Title
+		s.recordEvent(taskID, eventType, nil, &title, actor)
+		item.IsDone = *req.IsDone
+	}
+
+	if req.Position != nil {
+		target := clampPosition(*req.Position, len(list))
+		list = append(list[:position], list[position+1:]...)
+		list = append(list[:target], append([]*TaskItem{item}, list[target:]...)...)
+		s.items[taskID] = list
+		position = target
+	}
+
+	item.UpdatedAt = s.now()
+
+	c := itemCopy(item, position)
+	return &c, nil
+}
+
+// DeleteTaskItem removes a checklist item
+func (s *MemoryStore) DeleteTaskItem(taskID, itemID int64, actor string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	position, ok := s.findItem(taskID, itemID)
+	if !ok {
+		return ErrNotFound
+	}
+
+	list := s.items[taskID]
+	title := list[position].Title
+	s.items[taskID] = append(list[:position], list[position+1:]...)
+	s.recordEvent(taskID, EventItemRemoved, &title, nil, actor)
+	return nil
+}
+
+// Recurring task operations
+
+// recurringCopy returns a detached copy of a stored template
+func recurringCopy(rt *RecurringTask) RecurringTask {
+	c := *rt
+	c.CategoryID = copyInt64Ptr(rt.CategoryID)
+	c.Priority = copyStringPtr(rt.Priority)
+	c.EndDate = copyStringPtr(rt.EndDate)
+	c.GeneratedThrough = copyStringPtr(rt.GeneratedThrough)
+	return c
+}
+
+// CreateRecurringTask stores a new recurring task template
+func (s *MemoryStore) CreateRecurringTask(rt RecurringTask) (*RecurringTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextRecurrence++
+	now := s.now()
+	stored := recurringCopy(&rt)
+	stored.ID = s.nextRecurrence
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+	s.recurring[stored.ID] = &stored
+
+	c := recurringCopy(&stored)
+	return &c, nil
+}
+
+// GetRecurringTaskByID retrieves a recurring task template by ID
+func (s *MemoryStore) GetRecurringTaskByID(id int64) (*RecurringTask, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rt, ok := s.recurring[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	c := recurringCopy(rt)
+	return &c, nil
+}
+
+// GetRecurringTasks retrieves every recurring task template
+func (s *MemoryStore) GetRecurringTasks() ([]RecurringTask, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var templates []RecurringTask
+	for _, rt := range s.recurring {
+		templates = append(templates, recurringCopy(rt))
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].ID < templates[j].ID
+	})
+	return templates, nil
+}
+
+// UpdateRecurringTask replaces a template's definition
+func (s *MemoryStore) UpdateRecurringTask(rt RecurringTask) (*RecurringTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.recurring[rt.ID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	updated := recurringCopy(&rt)
+	updated.GeneratedThrough = stored.GeneratedThrough
+	updated.CreatedAt = stored.CreatedAt
+	updated.UpdatedAt = s.now()
+	*stored = updated
+
+	c := recurringCopy(stored)
+	return &c, nil
+}
+
+// DeleteRecurringTask deletes a template and unlinks the tasks it generated
+func (s *MemoryStore) DeleteRecurringTask(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, tasks := range []map[int64]*Task{s.tasks, s.trash} {
+		for _, t := range tasks {
+			if t.RecurringID != nil && *t.RecurringID == id {
+				t.RecurringID = nil
+			}
+		}
+	}
+	delete(s.occurrences, id)
+	delete(s.recurring, id)
+	return nil
+}
+
+// MaterializeOccurrence creates the task for a template's occurrence on date
+// and marks the occurrence handled. It returns nil when the occurrence was
+// handled before, or when skip only records it as skipped.
+func (s *MemoryStore) MaterializeOccurrence(rt RecurringTask, date string, skip bool) (*Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.occurrences[rt.ID][date]; ok {
+		return nil, nil
+	}
+	if s.occurrences[rt.ID] == nil {
+		s.occurrences[rt.ID] = make(map[string]*int64)
+	}
+	if skip {
+		s.occurrences[rt.ID][date] = nil
+		return nil, nil
+	}
+
+	t := s.createTask(occurrenceRequest(rt, date), date, "recurring")
+	id := t.ID
+	s.occurrences[rt.ID][date] = &id
+
+	task := s.taskCopy(t)
+	return &task, nil
+}
+
+// SetRecurringGeneratedThrough records that every occurrence up to date has been handled
+func (s *MemoryStore) SetRecurringGeneratedThrough(id int64, date string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rt, ok := s.recurring[id]; ok {
+		rt.GeneratedThrough = &date
+	}
+	return nil
+}
+
+// Tag operations
+
+// sortTags orders tags by name, ignoring case, like the SQLite store
+func sortTags(tags []Tag) {
+	sort.Slice(tags, func(i, j int) bool {
+		return strings.ToLower(tags[i].Name) < strings.ToLower(tags[j].Name)
+	})
+}
+
+// findTagByName looks a tag up by name ignoring case; callers hold the lock
+func (s *MemoryStore) findTagByName(name string) *Tag {
+	for _, tag := range s.tags {
+		if strings.EqualFold(tag.Name, name) {
+			return tag
+		}
+	}
+	return nil
+}
+
+// tagNamed looks a tag up by name ignoring case, creating it when there is
+// none; callers hold the write lock
+func (s *MemoryStore) tagNamed(name string) *Tag {
+	if tag := s.findTagByName(name); tag != nil {
+		return tag
+	}
+	s.nextTagID++
+	tag := &Tag{ID: s.nextTagID, Name: name, Color: "#8b949e", CreatedAt: s.now()}
+	s.tags[tag.ID] = tag
+	return tag
+}
+
+// CreateTag creates a new tag; names are unique regardless of case
+func (s *MemoryStore) CreateTag(name, color string) (*Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.findTagByName(name) != nil {
+		return nil, ErrDuplicate
+	}
+
+	s.nextTagID++
+	tag := &Tag{ID: s.nextTagID, Name: name, Color: color, CreatedAt: s.now()}
+	s.tags[tag.ID] = tag
+
+	c := *tag
+	return &c, nil
+}
+
+// GetTagByID retrieves a tag by ID
+func (s *MemoryStore) GetTagByID(id int64) (*Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tag, ok := s.tags[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	c := *tag
+	return &c, nil
+}
+
+// GetTagByName retrieves a tag by name, ignoring case
+func (s *MemoryStore) GetTagByName(name string) (*Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tag := s.findTagByName(name)
+	if tag == nil {
+		return nil, ErrNotFound
+	}
+
+	c := *tag
+	return &c, nil
+}
+
+// GetAllTags retrieves all tags with their incomplete task counts
+func (s *MemoryStore) GetAllTags() ([]Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var tags []Tag
+	for _, tag := range s.tags {
+		c := *tag
+		c.TaskCount = 0
+		for taskID, tagIDs := range s.taskTags {
+			if t, ok := s.tasks[taskID]; ok && tagIDs[tag.ID] && !t.IsCompleted {
+				c.TaskCount++
+			}
+		}
+		tags = append(tags, c)
+	}
+	sortTags(tags)
+	return tags, nil
+}
+
+// UpdateTag renames or recolors a tag
+func (s *MemoryStore) UpdateTag(id int64, name, color string) (*Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tag, ok := s.tags[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if other := s.findTagByName(name); other != nil && other.ID != id {
+		return nil, ErrDuplicate
+	}
+
+	tag.Name = name
+	tag.Color = color
+
+	c := *tag
+	return &c, nil
+}
+
+// DeleteTag deletes a tag, recording a tag_removed event on each of its tasks
+func (s *MemoryStore) DeleteTag(id int64, actor string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tag, ok := s.tags[id]
+	if !ok {
+		return nil
+	}
+
+	name := tag.Name
+	for taskID, tagIDs := range s.taskTags {
+		if tagIDs[id] {
+			s.recordEvent(taskID, EventTagRemoved, &name, nil, actor)
+			delete(tagIDs, id)
+		}
+	}
+	delete(s.tags, id)
+	return nil
+}
+
+// AddTaskTag puts a tag on a task; adding a tag the task already has is a no-op
+func (s *MemoryStore) AddTaskTag(taskID, tagID int64, actor string) (*Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tasks[taskID]
+	tag, tagOK := s.tags[tagID]
+	if !ok || !tagOK {
+		return nil, ErrNotFound
+	}
+
+	s.addTaskTag(taskID, tag, actor)
+
+	task := s.taskCopy(t)
+	return &task, nil
+}
+
+// addTaskTag puts a tag on a task, recording a tag_added event unless the
+// task already has it; callers hold the write lock
+func (s *MemoryStore) addTaskTag(taskID int64, tag *Tag, actor string) {
+	if s.taskTags[taskID] == nil {
+		s.taskTags[taskID] = make(map[int64]bool)
+	}
+	if !s.taskTags[taskID][tag.ID] {
+		s.taskTags[taskID][tag.ID] = true
+		name := tag.Name
+		s.recordEvent(taskID, EventTagAdded, nil, &name, actor)
+	}
+}
+
+// RemoveTaskTag takes a tag off a task; removing a tag it doesn't have is a no-op
+func (s *MemoryStore) RemoveTaskTag(taskID, tagID int64, actor string) (*Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tag, ok := s.tags[tagID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	s.removeTaskTag(taskID, tag, actor)
+
+	t, ok := s.tasks[taskID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	task := s.taskCopy(t)
+	return &task, nil
+}
+
+// removeTaskTag takes a tag off a task, recording a tag_removed event if the
+// task had it; callers hold the write lock
+func (s *MemoryStore) removeTaskTag(taskID int64, tag *Tag, actor string) {
+	if s.taskTags[taskID][tag.ID] {
+		delete(s.taskTags[taskID], tag.ID)
+		name := tag.Name
+		s.recordEvent(taskID, EventTagRemoved, &name, nil, actor)
+	}
+}
+
+// Holiday operations
+
+// CreateHoliday adds a holiday to the working calendar
+func (s *MemoryStore) CreateHoliday(date, name string) (*Holiday, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, h := range s.holidays {
+		if h.Date == date {
+			return nil, ErrDuplicate
+		}
+	}
+
+	s.nextHolidayID++
+	h := &Holiday{ID: s.nextHolidayID, Date: date, Name: name, CreatedAt: s.now()}
+	s.holidays[h.ID] = h
+
+	holiday := *h
+	return &holiday, nil
+}
+
+// GetHolidayByID retrieves a holiday by ID
+func (s *MemoryStore) GetHolidayByID(id int64) (*Holiday, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	h, ok := s.holidays[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	holiday := *h
+	return &holiday, nil
+}
+
+// GetHolidays retrieves holidays between two dates inclusive; empty bounds are open
+func (s *MemoryStore) GetHolidays(fromDate, toDate string) ([]Holiday, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var holidays []Holiday
+	for _, h := range s.holidays {
+		if (fromDate == "" || h.Date >= fromDate) && (toDate == "" || h.Date <= toDate) {
+			holidays = append(holidays, *h)
+		}
+	}
+
+	sort.Slice(holidays, func(i, j int) bool {
+		return holidays[i].Date < holidays[j].Date
+	})
+
+	return holidays, nil
+}
+
+// UpdateHoliday changes a holiday's date and name
+func (s *MemoryStore) UpdateHoliday(id int64, date, name string) (*Holiday, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, ok := s.holidays[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	for _, other := range s.holidays {
+		if other.ID != id && other.Date == date {
+			return nil, ErrDuplicate
+		}
+	}
+
+	h.Date = date
+	h.Name = name
+
+	holiday := *h
+	return &holiday, nil
+}
+
+// DeleteHoliday removes a holiday
+func (s *MemoryStore) DeleteHoliday(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.holidays, id)
+	return nil
+}
+
+// Rollover run operations
+
+// RecordRolloverRun stores the outcome of a scheduled rollover
+func (s *MemoryStore) RecordRolloverRun(run *RolloverRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run.ID = int64(len(s.rolloverRuns) + 1)
+	s.rolloverRuns = append(s.rolloverRuns, *run)
+	return nil
+}
+
+// GetLastRolloverRun retrieves the most recent successful rollover run
+func (s *MemoryStore) GetLastRolloverRun() (*RolloverRun, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var last *RolloverRun
+	for i := range s.rolloverRuns {
+		run := s.rolloverRuns[i]
+		if run.Error == "" && (last == nil || run.RunDate >= last.RunDate) {
+			last = &run
+		}
+	}
+
+	if last == nil {
+		return nil, ErrNotFound
+	}
+	return last, nil
+}
+
+// GetRolloverRuns retrieves the most recent rollover runs, newest first
+func (s *MemoryStore) GetRolloverRuns(limit int) ([]RolloverRun, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var runs []RolloverRun
+	for i := len(s.rolloverRuns) - 1; i >= 0 && len(runs) < limit; i-- {
+		runs = append(runs, s.rolloverRuns[i])
+	}
+	return runs, nil
+}
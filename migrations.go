@@ -0,0 +1,521 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Migration is a single numbered schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *sql.Tx) error
+	Down    func(tx *sql.Tx) error
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+// migrations lists every schema change in order. Never edit or reorder an
+// entry once it has shipped; append a new one instead.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create categories and tasks",
+		Up: func(tx *sql.Tx) error {
+			if err := execSQL(tx, `
+			CREATE TABLE IF NOT EXISTS categories (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL UNIQUE,
+				color TEXT NOT NULL DEFAULT '#58a6ff',
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE TABLE IF NOT EXISTS tasks (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				title TEXT NOT NULL,
+				description TEXT DEFAULT '',
+				created_date TEXT NOT NULL,
+				assigned_date TEXT NOT NULL,
+				completed_date TEXT,
+				is_completed BOOLEAN DEFAULT FALSE,
+				category_id INTEGER,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE SET NULL
+			);
+			`); err != nil {
+				return err
+			}
+
+			// Databases created before categories existed have a tasks table
+			// without category_id, which CREATE TABLE IF NOT EXISTS won't fix
+			exists, err := columnExists(tx, "tasks", "category_id")
+			if err != nil {
+				return err
+			}
+			if !exists {
+				if _, err := tx.Exec(`ALTER TABLE tasks ADD COLUMN category_id INTEGER REFERENCES categories(id) ON DELETE SET NULL`); err != nil {
+					return err
+				}
+			}
+
+			return execSQL(tx, `
+			CREATE INDEX IF NOT EXISTS idx_assigned_date ON tasks(assigned_date);
+			CREATE INDEX IF NOT EXISTS idx_completed_date ON tasks(completed_date);
+			CREATE INDEX IF NOT EXISTS idx_category_id ON tasks(category_id);
+			`)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execSQL(tx, `
+			DROP TABLE IF EXISTS tasks;
+			DROP TABLE IF EXISTS categories;
+			`)
+		},
+	},
+	{
+		Version: 2,
+		Name:    "create holidays",
+		Up: func(tx *sql.Tx) error {
+			return execSQL(tx, `
+			CREATE TABLE holidays (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				date TEXT NOT NULL UNIQUE,
+				name TEXT NOT NULL DEFAULT '',
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			`)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execSQL(tx, `DROP TABLE holidays;`)
+		},
+	},
+	{
+		Version: 3,
+		Name:    "create rollover_runs",
+		Up: func(tx *sql.Tx) error {
+			return execSQL(tx, `
+			CREATE TABLE rollover_runs (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				run_date TEXT NOT NULL,
+				trigger TEXT NOT NULL,
+				tasks_moved INTEGER NOT NULL DEFAULT 0,
+				error TEXT NOT NULL DEFAULT '',
+				started_at DATETIME NOT NULL,
+				finished_at DATETIME NOT NULL
+			);
+
+			CREATE INDEX idx_rollover_runs_run_date ON rollover_runs(run_date);
+			`)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execSQL(tx, `DROP TABLE rollover_runs;`)
+		},
+	},
+	{
+		Version: 4,
+		Name:    "create task_events",
+		Up: func(tx *sql.Tx) error {
+			// No foreign key: a task's history outlives the task itself
+			return execSQL(tx, `
+			CREATE TABLE task_events (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				task_id INTEGER NOT NULL,
+				event_type TEXT NOT NULL,
+				old_value TEXT,
+				new_value TEXT,
+				actor TEXT NOT NULL DEFAULT '',
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE INDEX idx_task_events_task_id ON task_events(task_id);
+			`)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execSQL(tx, `DROP TABLE task_events;`)
+		},
+	},
+	{
+		Version: 5,
+		Name:    "add task priority and due date",
+		Up: func(tx *sql.Tx) error {
+			// Priorities are stored as "P0".."P3" so they sort as text
+			return execSQL(tx, `
+			ALTER TABLE tasks ADD COLUMN priority TEXT;
+			ALTER TABLE tasks ADD COLUMN due_date TEXT;
+
+			CREATE INDEX idx_tasks_due_date ON tasks(due_date);
+			`)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execSQL(tx, `
+			DROP INDEX idx_tasks_due_date;
+			ALTER TABLE tasks DROP COLUMN due_date;
+			ALTER TABLE tasks DROP COLUMN priority;
+			`)
+		},
+	},
+	{
+		Version: 6,
+		Name:    "create task_items",
+		Up: func(tx *sql.Tx) error {
+			return execSQL(tx, `
+			CREATE TABLE task_items (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+				title TEXT NOT NULL,
+				is_done BOOLEAN DEFAULT FALSE,
+				position INTEGER NOT NULL DEFAULT 0,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE INDEX idx_task_items_task_id ON task_items(task_id, position);
+			`)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execSQL(tx, `DROP TABLE task_items;`)
+		},
+	},
+	{
+		Version: 7,
+		Name:    "create recurring_tasks",
+		Up: func(tx *sql.Tx) error {
+			// recurring_occurrences remembers every date a template has been
+			// materialized (or skipped) for, so deleting a generated task does
+			// not bring it back
+			return execSQL(tx, `
+			CREATE TABLE recurring_tasks (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				title TEXT NOT NULL,
+				description TEXT DEFAULT '',
+				category_id INTEGER REFERENCES categories(id) ON DELETE SET NULL,
+				priority TEXT,
+				rule TEXT NOT NULL,
+				start_date TEXT NOT NULL,
+				end_date TEXT,
+				missed_policy TEXT NOT NULL DEFAULT 'roll',
+				generated_through TEXT,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE TABLE recurring_occurrences (
+				recurring_id INTEGER NOT NULL,
+				occurrence_date TEXT NOT NULL,
+				task_id INTEGER,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (recurring_id, occurrence_date)
+			);
+
+			ALTER TABLE tasks ADD COLUMN recurring_id INTEGER;
+			CREATE INDEX idx_tasks_recurring_id ON tasks(recurring_id);
+			`)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execSQL(tx, `
+			DROP INDEX idx_tasks_recurring_id;
+			ALTER TABLE tasks DROP COLUMN recurring_id;
+			DROP TABLE recurring_occurrences;
+			DROP TABLE recurring_tasks;
+			`)
+		},
+	},
+	{
+		Version: 8,
+		Name:    "create tags",
+		Up: func(tx *sql.Tx) error {
+			return execSQL(tx, `
+			CREATE TABLE tags (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+				color TEXT NOT NULL DEFAULT '#8b949e',
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE TABLE task_tags (
+				task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+				tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+				PRIMARY KEY (task_id, tag_id)
+			);
+
+			CREATE INDEX idx_task_tags_tag_id ON task_tags(tag_id);
+			`)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execSQL(tx, `
+			DROP TABLE task_tags;
+			DROP TABLE tags;
+			`)
+		},
+	},
+	{
+		Version: 9,
+		Name:    "add trash and operation journal",
+		Up: func(tx *sql.Tx) error {
+			return execSQL(tx, `
+			ALTER TABLE tasks ADD COLUMN deleted_at DATETIME;
+			ALTER TABLE categories ADD COLUMN deleted_at DATETIME;
+			CREATE INDEX idx_tasks_deleted_at ON tasks(deleted_at);
+
+			CREATE TABLE operations (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				kind TEXT NOT NULL,
+				summary TEXT NOT NULL,
+				actor TEXT NOT NULL,
+				task_count INTEGER NOT NULL DEFAULT 0,
+				before_state TEXT NOT NULL,
+				after_state TEXT NOT NULL,
+				undone_at DATETIME,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			`)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execSQL(tx, `
+			DROP TABLE operations;
+			DROP INDEX idx_tasks_deleted_at;
+			ALTER TABLE categories DROP COLUMN deleted_at;
+			ALTER TABLE tasks DROP COLUMN deleted_at;
+			`)
+		},
+	},
+	{
+		Version: 10,
+		Name:    "add category rollover policies",
+		Up: func(tx *sql.Tx) error {
+			return execSQL(tx, `
+			ALTER TABLE categories ADD COLUMN rollover_policy TEXT NOT NULL DEFAULT 'next_day';
+			ALTER TABLE categories ADD COLUMN archive_after_days INTEGER;
+			`)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execSQL(tx, `
+			ALTER TABLE categories DROP COLUMN archive_after_days;
+			ALTER TABLE categories DROP COLUMN rollover_policy;
+			`)
+		},
+	},
+	{
+		Version: 11,
+		Name:    "add category stale thresholds",
+		Up: func(tx *sql.Tx) error {
+			return execSQL(tx, `
+			ALTER TABLE categories ADD COLUMN stale_after_days INTEGER;
+			ALTER TABLE categories ADD COLUMN critical_after_days INTEGER;
+			CREATE INDEX idx_task_events_type ON task_events(event_type, task_id);
+			`)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execSQL(tx, `
+			DROP INDEX IF EXISTS idx_task_events_type;
+			ALTER TABLE categories DROP COLUMN critical_after_days;
+			ALTER TABLE categories DROP COLUMN stale_after_days;
+			`)
+		},
+	},
+}
+
+// LatestSchemaVersion returns the highest migration version this binary knows
+func LatestSchemaVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+func execSQL(tx *sql.Tx, statements string) error {
+	_, err := tx.Exec(statements)
+	return err
+}
+
+func columnExists(tx *sql.Tx, table, column string) (bool, error) {
+	rows, err := tx.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+
+	return false, rows.Err()
+}
+
+func ensureMigrationsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`)
+	return err
+}
+
+// getAppliedMigrations returns applied versions mapped to when they were applied
+func getAppliedMigrations(db *sql.DB) (map[int]time.Time, error) {
+	if err := ensureMigrationsTable(db); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// GetSchemaVersion returns the highest applied migration version
+func GetSchemaVersion(db *sql.DB) (int, error) {
+	if err := ensureMigrationsTable(db); err != nil {
+		return 0, err
+	}
+
+	var version int
+	err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+// checkSchemaCompatible refuses to run against a database migrated by a newer binary
+func checkSchemaCompatible(db *sql.DB) error {
+	version, err := GetSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	if latest := LatestSchemaVersion(); version > latest {
+		return fmt.Errorf("database schema is at version %d but this binary only knows up to version %d; upgrade the binary", version, latest)
+	}
+
+	return nil
+}
+
+// GetMigrationStatus lists every known migration and whether it is applied
+func GetMigrationStatus(db *sql.DB) ([]MigrationStatus, error) {
+	applied, err := getAppliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if appliedAt, ok := applied[m.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// MigrateUp applies all pending migrations in order and returns how many ran
+func MigrateUp(db *sql.DB) (int, error) {
+	if err := checkSchemaCompatible(db); err != nil {
+		return 0, err
+	}
+
+	applied, err := getAppliedMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		if err := runMigration(db, m, true); err != nil {
+			return count, fmt.Errorf("migration %d (%s) failed: %v", m.Version, m.Name, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// MigrateDown reverts the most recently applied migrations, newest first
+func MigrateDown(db *sql.DB, steps int) (int, error) {
+	if err := checkSchemaCompatible(db); err != nil {
+		return 0, err
+	}
+
+	applied, err := getAppliedMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+
+		if err := runMigration(db, m, false); err != nil {
+			return count, fmt.Errorf("rollback of migration %d (%s) failed: %v", m.Version, m.Name, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// runMigration applies or reverts a single migration in its own transaction
+func runMigration(db *sql.DB, m Migration, up bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if up {
+		if err := m.Up(tx); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.Version, m.Name); err != nil {
+			return err
+		}
+	} else {
+		if m.Down == nil {
+			return fmt.Errorf("migration is irreversible")
+		}
+		if err := m.Down(tx); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, m.Version); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
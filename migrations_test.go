@@ -0,0 +1,149 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// openTestDB opens an empty database in the test's temporary directory
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := openDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// userTables lists the tables a database holds besides the migration log
+func userTables(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name != 'schema_migrations' ORDER BY name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	db := openTestDB(t)
+
+	count, err := MigrateUp(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != len(migrations) {
+		t.Errorf("applied %d migrations, want %d", count, len(migrations))
+	}
+	if version, _ := GetSchemaVersion(db); version != LatestSchemaVersion() {
+		t.Errorf("got version %d, want %d", version, LatestSchemaVersion())
+	}
+	if count, err = MigrateUp(db); err != nil || count != 0 {
+		t.Errorf("migrating again applied %d (%v), want 0", count, err)
+	}
+
+	// Step down one migration at a time; each must undo exactly its own change
+	for i := len(migrations) - 1; i >= 0; i-- {
+		if count, err := MigrateDown(db, 1); err != nil || count != 1 {
+			t.Fatalf("reverting migration %d: reverted %d, %v", migrations[i].Version, count, err)
+		}
+		want := 0
+		if i > 0 {
+			want = migrations[i-1].Version
+		}
+		if version, _ := GetSchemaVersion(db); version != want {
+			t.Fatalf("got version %d after reverting %d, want %d", version, migrations[i].Version, want)
+		}
+	}
+	if tables := userTables(t, db); len(tables) != 0 {
+		t.Errorf("tables left after reverting everything: %v", tables)
+	}
+
+	statuses, err := GetMigrationStatus(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if status.Applied {
+			t.Errorf("migration %d still applied", status.Version)
+		}
+	}
+
+	// The schema comes back from nothing
+	if count, err := MigrateUp(db); err != nil || count != len(migrations) {
+		t.Errorf("reapplying: applied %d, %v", count, err)
+	}
+}
+
+// Databases from before the migration runner have a tasks table without
+// category_id; the first migration adds it
+func TestMigrateUpAddsLegacyCategoryColumn(t *testing.T) {
+	db := openTestDB(t)
+	_, err := db.Exec(`CREATE TABLE tasks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT NOT NULL,
+		description TEXT DEFAULT '',
+		created_date TEXT NOT NULL,
+		assigned_date TEXT NOT NULL,
+		completed_date TEXT,
+		is_completed BOOLEAN DEFAULT FALSE,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if exists, err := columnExists(tx, "tasks", "category_id"); err != nil || !exists {
+		t.Errorf("category_id exists %v (%v), want true", exists, err)
+	}
+}
+
+func TestCheckSchemaCompatible(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSchemaCompatible(db); err != nil {
+		t.Fatalf("got %v for an up-to-date database", err)
+	}
+
+	// A newer binary has migrated the database further than this one knows
+	newer := LatestSchemaVersion() + 1
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, 'from the future')`, newer); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSchemaCompatible(db); err == nil {
+		t.Error("got no error for a newer schema")
+	}
+	if _, err := MigrateUp(db); err == nil {
+		t.Error("migrated up a newer schema")
+	}
+	if _, err := MigrateDown(db, 1); err == nil {
+		t.Error("migrated down a newer schema")
+	}
+}
@@ -4,27 +4,94 @@ import "time"
 
 // Category represents a task category
 type Category struct {
+	ID                int64      `json:"id"`
+	Name              string     `json:"name"`
+	Color             string     `json:"color"`
+	RolloverPolicy    string     `json:"rollover_policy"`      // What rollover does with the category's pending tasks
+	ArchiveAfterDays  *int       `json:"archive_after_days"`   // Drag days before an archive policy archives a task
+	StaleAfterDays    *int       `json:"stale_after_days"`     // Overrides the global stale threshold
+	CriticalAfterDays *int       `json:"critical_after_days"`  // Overrides the global critical threshold
+	TaskCount         int        `json:"task_count,omitempty"` // Number of tasks in this category
+	CreatedAt         time.Time  `json:"created_at"`
+	DeletedAt         *time.Time `json:"deleted_at,omitempty"` // Set while the category is in the trash
+}
+
+// Category rollover policies
+const (
+	RolloverNextDay         = "next_day"          // Roll onto the rollover date (the default)
+	RolloverNextBusinessDay = "next_business_day" // Roll onto the first working day from the rollover date
+	RolloverNextWeek        = "next_week"         // Roll onto the first Monday from the rollover date
+	RolloverArchive         = "archive"           // Roll like next_day, but send tasks dragging ArchiveAfterDays or more to the trash
+	RolloverNever           = "never"             // Leave tasks on their date
+)
+
+// CategoryRequest creates or edits a category
+type CategoryRequest struct {
+	Name              string `json:"name"`
+	Color             string `json:"color"`
+	RolloverPolicy    string `json:"rollover_policy"`
+	ArchiveAfterDays  *int   `json:"archive_after_days"`
+	StaleAfterDays    *int   `json:"stale_after_days"`
+	CriticalAfterDays *int   `json:"critical_after_days"`
+}
+
+// Tag is a free-form label; a task can carry any number of tags
+type Tag struct {
 	ID        int64     `json:"id"`
 	Name      string    `json:"name"`
 	Color     string    `json:"color"`
-	TaskCount int       `json:"task_count,omitempty"` // Number of tasks in this category
+	TaskCount int       `json:"task_count,omitempty"` // Number of incomplete tasks with this tag
 	CreatedAt time.Time `json:"created_at"`
 }
 
 // Task represents a todo item
 type Task struct {
-	ID            int64     `json:"id"`
-	Title         string    `json:"title"`
-	Description   string    `json:"description"`
-	CreatedDate   string    `json:"created_date"`   // Date when task was first created
-	AssignedDate  string    `json:"assigned_date"`  // Current date the task is assigned to
-	CompletedDate *string   `json:"completed_date"` // Date when task was completed (nil if not completed)
-	IsCompleted   bool      `json:"is_completed"`
-	DragDays      int       `json:"drag_days"`   // Business days the task has been dragged
-	CategoryID    *int64    `json:"category_id"` // Optional category
-	Category      *Category `json:"category"`    // Category details (populated on fetch)
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID            int64      `json:"id"`
+	Title         string     `json:"title"`
+	Description   string     `json:"description"`
+	CreatedDate   string     `json:"created_date"`   // Date when task was first created
+	AssignedDate  string     `json:"assigned_date"`  // Current date the task is assigned to
+	CompletedDate *string    `json:"completed_date"` // Date when task was completed (nil if not completed)
+	IsCompleted   bool       `json:"is_completed"`
+	DragDays      int        `json:"drag_days"`    // Business days the task has been dragged
+	CategoryID    *int64     `json:"category_id"`  // Optional category
+	Category      *Category  `json:"category"`     // Category details (populated on fetch)
+	Priority      *string    `json:"priority"`     // "P0" (highest) to "P3", nil if unset
+	DueDate       *string    `json:"due_date"`     // Optional deadline, independent of the assigned date
+	IsOverdue     bool       `json:"is_overdue"`   // Pending and past its due date in the request's timezone
+	IsStale       bool       `json:"is_stale"`     // Pending and dragged past its stale threshold
+	IsCritical    bool       `json:"is_critical"`  // Pending and dragged past its critical threshold
+	Progress      Progress   `json:"progress"`     // Checklist items done out of total
+	RecurringID   *int64     `json:"recurring_id"` // Recurring template that generated the task
+	Tags          []Tag      `json:"tags"`         // Fine-grained labels, by name
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"` // Set while the task is in the trash
+}
+
+// Progress counts a task's checked-off checklist items
+type Progress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// TaskItem is one ordered checklist entry under a task. Items belong to the
+// task rather than a date, so they move with it on rollover.
+type TaskItem struct {
+	ID        int64     `json:"id"`
+	TaskID    int64     `json:"task_id"`
+	Title     string    `json:"title"`
+	IsDone    bool      `json:"is_done"`
+	Position  int       `json:"position"` // 0-based order within the task
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TaskItemRequest creates or edits a checklist item; nil fields are left unchanged
+type TaskItemRequest struct {
+	Title    *string `json:"title"`
+	IsDone   *bool   `json:"is_done"`
+	Position *int    `json:"position"`
 }
 
 // DailyLog represents tasks for a specific day
@@ -33,13 +100,19 @@ type DailyLog struct {
 	Tasks          []Task `json:"tasks"`
 	CompletedCount int    `json:"completed_count"`
 	PendingCount   int    `json:"pending_count"`
+	OverdueCount   int    `json:"overdue_count"` // Pending tasks due before this date
 }
 
-// TaskRequest is used for creating/updating tasks
+// TaskRequest is used for creating/updating tasks. On update, a nil Priority
+// or DueDate leaves the current value alone and an empty string clears it.
 type TaskRequest struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Date        string `json:"date"` // Optional: defaults to today
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	Date        string  `json:"date"`        // Optional: defaults to today
+	Priority    *string `json:"priority"`    // Optional: P0-P3
+	DueDate     *string `json:"due_date"`    // Optional: YYYY-MM-DD
+	CategoryID  *int64  `json:"category_id"` // Optional, on create only
+	RecurringID *int64  `json:"-"`           // Set when a recurring template materializes the task
 }
 
 // CompleteTaskRequest marks a task as complete
@@ -51,4 +124,204 @@ type CompleteTaskRequest struct {
 type RolloverRequest struct {
 	FromDate string `json:"from_date"`
 	ToDate   string `json:"to_date"`
+	RolloverOptions
+}
+
+// RolloverOptions picks which pending tasks a rollover moves and whether it
+// only previews the moves. Empty include lists take every task.
+type RolloverOptions struct {
+	DryRun             bool    `json:"dry_run"`
+	IncludeTaskIDs     []int64 `json:"include_task_ids"`
+	ExcludeTaskIDs     []int64 `json:"exclude_task_ids"`
+	IncludeCategoryIDs []int64 `json:"include_category_ids"`
+	ExcludeCategoryIDs []int64 `json:"exclude_category_ids"`
+}
+
+// RolloverMove is a task that a rollover moved or archived, or would in a
+// dry run
+type RolloverMove struct {
+	TaskID     int64  `json:"task_id"`
+	Title      string `json:"title"`
+	CategoryID *int64 `json:"category_id"`
+	Action     string `json:"action"` // rolled_over or archived
+	FromDate   string `json:"from_date"`
+	ToDate     string `json:"to_date,omitempty"` // Empty when archived
+	DragDays   int    `json:"drag_days"`         // On ToDate, or on the rollover date when archived
+}
+
+// What a rollover did with a task
+const (
+	RolloverRolledOver = "rolled_over"
+	RolloverArchived   = "archived"
+)
+
+// HistorySummary represents what was accomplished on a specific date
+type HistorySummary struct {
+	Date           string `json:"date"`
+	CompletedCount int    `json:"completed_count"`
+	PendingCount   int    `json:"pending_count"` // Tasks assigned but not yet completed
+}
+
+// Missed occurrence policies for recurring tasks
+const (
+	MissedRoll = "roll" // Pending occurrences roll over like any other task
+	MissedSkip = "skip" // Pending occurrences stay on their day and are not carried forward
+)
+
+// RecurringTask is a template that materializes a task on every date its
+// rule matches
+type RecurringTask struct {
+	ID               int64     `json:"id"`
+	Title            string    `json:"title"`
+	Description      string    `json:"description"`
+	CategoryID       *int64    `json:"category_id"`
+	Priority         *string   `json:"priority"`
+	Rule             string    `json:"rule"` // RRULE, e.g. FREQ=WEEKLY;BYDAY=FR
+	StartDate        string    `json:"start_date"`
+	EndDate          *string   `json:"end_date"` // Last date an occurrence may fall on (nil if open-ended)
+	MissedPolicy     string    `json:"missed_policy"`
+	GeneratedThrough *string   `json:"generated_through"` // Every occurrence up to this date has been handled
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// Holiday is a non-working day in the working calendar
+type Holiday struct {
+	ID        int64     `json:"id"`
+	Date      string    `json:"date"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RolloverRun records one execution of the background rollover scheduler
+type RolloverRun struct {
+	ID         int64     `json:"id"`
+	RunDate    string    `json:"run_date"` // Local date tasks were rolled onto
+	Trigger    string    `json:"trigger"`  // "scheduled" or "catch-up"
+	TasksMoved int       `json:"tasks_moved"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// Task event types recorded in a task's history
+const (
+	EventCreated            = "created"
+	EventTitleChanged       = "title_changed"
+	EventDescriptionChanged = "description_changed"
+	EventCompleted          = "completed"
+	EventUncompleted        = "uncompleted"
+	EventCategoryChanged    = "category_changed"
+	EventPriorityChanged    = "priority_changed"
+	EventDueDateChanged     = "due_date_changed"
+	EventItemAdded          = "item_added"
+	EventItemRenamed        = "item_renamed"
+	EventItemChecked        = "item_checked"
+	EventItemUnchecked      = "item_unchecked"
+	EventItemRemoved        = "item_removed"
+	EventTagAdded           = "tag_added"
+	EventTagRemoved         = "tag_removed"
+	EventRolledOver         = "rolled_over"
+	EventMoved              = "moved"
+	EventDeleted            = "deleted" // Old value is the assigned date, new value the title, kept for once the task is purged
+	EventRestored           = "restored"
+	EventEscalated          = "escalated" // New value is the stale level reached
+)
+
+// TaskEvent is one entry in a task's history
+type TaskEvent struct {
+	ID        int64     `json:"id"`
+	TaskID    int64     `json:"task_id"`
+	Type      string    `json:"type"`
+	OldValue  *string   `json:"old_value"`
+	NewValue  *string   `json:"new_value"`
+	Actor     string    `json:"actor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Bulk operation kinds
+const (
+	BulkComplete    = "complete"
+	BulkUncomplete  = "uncomplete"
+	BulkDelete      = "delete"
+	BulkSetCategory = "set_category"
+	BulkMoveToDate  = "move_to_date"
+	BulkUpdate      = "update"
+)
+
+// BulkRequest is the body of a bulk task request
+type BulkRequest struct {
+	Operations   []BulkOperation `json:"operations"`
+	AllOrNothing bool            `json:"all_or_nothing"` // Roll everything back if any item fails
+}
+
+// BulkOperation applies one action to each of its tasks
+type BulkOperation struct {
+	Op         string     `json:"op"`
+	TaskIDs    []int64    `json:"task_ids"`
+	CategoryID *int64     `json:"category_id"` // set_category; null clears the category
+	Date       string     `json:"date"`        // move_to_date target, or the completion date (default today)
+	Fields     BulkFields `json:"fields"`      // update
+}
+
+// BulkFields are the fields an update operation sets; the others keep their
+// value. An empty priority or due date clears it.
+type BulkFields struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	Priority    *string `json:"priority"`
+	DueDate     *string `json:"due_date"`
+}
+
+// BulkResult is the outcome of one operation on one task
+type BulkResult struct {
+	Operation int    `json:"operation"` // Index into the request's operations
+	Op        string `json:"op"`
+	TaskID    int64  `json:"task_id"`
+	OK        bool   `json:"ok"`
+	Error     string `json:"error,omitempty"`
+	Task      *Task  `json:"task,omitempty"` // State after the request; absent when deleted or rolled back
+}
+
+// BulkResponse reports a bulk request item by item
+type BulkResponse struct {
+	Committed bool         `json:"committed"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Results   []BulkResult `json:"results"`
+}
+
+// Trash lists the tasks and categories that were deleted but can still be
+// restored
+type Trash struct {
+	Tasks      []Task     `json:"tasks"`
+	Categories []Category `json:"categories"`
+}
+
+// Operation kinds recorded in the journal
+const (
+	OpCreateTask      = "create_task"
+	OpUpdateTask      = "update_task"
+	OpCompleteTask    = "complete_task"
+	OpUncompleteTask  = "uncomplete_task"
+	OpSetCategory     = "set_category"
+	OpDeleteTask      = "delete_task"
+	OpRestoreTask     = "restore_task"
+	OpRollover        = "rollover"
+	OpBulk            = "bulk"
+	OpImport          = "import"
+	OpTodoTxt         = "todotxt"
+	OpDeleteCategory  = "delete_category"
+	OpRestoreCategory = "restore_category"
+)
+
+// Operation is one journaled mutation that can be undone and then redone
+type Operation struct {
+	ID        int64     `json:"id"`
+	Kind      string    `json:"kind"`
+	Summary   string    `json:"summary"`
+	Actor     string    `json:"actor"`
+	TaskCount int       `json:"task_count"` // Tasks the operation touched
+	Undone    bool      `json:"undone"`
+	CreatedAt time.Time `json:"created_at"`
 }
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Fields a task query can be sorted by
+var taskSortFields = map[string]bool{
+	"id": true, "title": true, "priority": true, "drag_days": true,
+	"created_date": true, "assigned_date": true, "completed_date": true,
+	"due_date": true, "created_at": true, "updated_at": true,
+}
+
+// TaskQuery selects tasks by any combination of filters. Date bounds are
+// inclusive YYYY-MM-DD strings and unset filters match everything.
+type TaskQuery struct {
+	CreatedFrom   string
+	CreatedTo     string
+	AssignedFrom  string
+	AssignedTo    string
+	CompletedFrom string
+	CompletedTo   string
+	Completed     *bool
+	CategoryIDs   []int64
+	MinDragDays   *int
+	MaxDragDays   *int
+	Text          string // Words every task must contain, as in search
+	Sort          string // One of taskSortFields; tasks without a value sort last
+	Descending    bool
+	After         *TaskCursor // Resume after this task
+	Limit         int
+}
+
+// TaskPage is one page of a task query
+type TaskPage struct {
+	Tasks      []Task  `json:"tasks"`
+	Total      int     `json:"total"`       // Tasks matching the filters across all pages
+	NextCursor *string `json:"next_cursor"` // Null on the last page
+}
+
+// TaskCursor marks the last task of a page by its sort key, so paging stays
+// stable while tasks are added or removed
+type TaskCursor struct {
+	Sort string `json:"s"` // Sort field and direction the cursor was made for
+	Key  string `json:"k"`
+	Null bool   `json:"n,omitempty"`
+	ID   int64  `json:"i"`
+}
+
+// sortSpec names a sort field and direction, e.g. "drag_days:desc"
+func (q TaskQuery) sortSpec() string {
+	if q.Descending {
+		return q.Sort + ":desc"
+	}
+	return q.Sort + ":asc"
+}
+
+// EncodeTaskCursor renders a cursor as an opaque URL-safe token
+func EncodeTaskCursor(c TaskCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeTaskCursor reads a token made by EncodeTaskCursor and checks that it
+// belongs to the same sort as q
+func DecodeTaskCursor(token string, q TaskQuery) (*TaskCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var c TaskCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	if c.Sort != q.sortSpec() {
+		return nil, fmt.Errorf("cursor was made for a different sort")
+	}
+	return &c, nil
+}
+
+// taskSortKey returns a key that orders tasks by field when compared as a
+// string, and whether the task has no value for the field
+func taskSortKey(task Task, field string) (string, bool) {
+	optional := func(v *string) (string, bool) {
+		if v == nil {
+			return "", true
+		}
+		return *v, false
+	}
+
+	switch field {
+	case "title":
+		return strings.ToLower(task.Title), false
+	case "priority":
+		return optional(task.Priority)
+	case "drag_days":
+		return fmt.Sprintf("%010d", task.DragDays), false
+	case "created_date":
+		return task.CreatedDate, false
+	case "assigned_date":
+		return task.AssignedDate, false
+	case "completed_date":
+		return optional(task.CompletedDate)
+	case "due_date":
+		return optional(task.DueDate)
+	case "created_at":
+		return task.CreatedAt.UTC().Format("2006-01-02T15:04:05.000000000"), false
+	case "updated_at":
+		return task.UpdatedAt.UTC().Format("2006-01-02T15:04:05.000000000"), false
+	}
+	return fmt.Sprintf("%020d", task.ID), false
+}
+
+// compareSortKeys orders two sort positions for q, breaking ties by ID
+func compareSortKeys(aKey string, aNull bool, aID int64, bKey string, bNull bool, bID int64, q TaskQuery) int {
+	// Missing values sort last in either direction
+	if aNull != bNull {
+		if aNull {
+			return 1
+		}
+		return -1
+	}
+
+	c := strings.Compare(aKey, bKey)
+	if c == 0 {
+		switch {
+		case aID < bID:
+			c = -1
+		case aID > bID:
+			c = 1
+		}
+	}
+	if q.Descending {
+		c = -c
+	}
+	return c
+}
+
+// matchesTaskQuery applies every filter of q to a task
+func matchesTaskQuery(task Task, q TaskQuery, terms []searchTerm) bool {
+	inRange := func(v, from, to string) bool {
+		return (from == "" || v >= from) && (to == "" || v <= to)
+	}
+
+	if !inRange(task.CreatedDate, q.CreatedFrom, q.CreatedTo) ||
+		!inRange(task.AssignedDate, q.AssignedFrom, q.AssignedTo) {
+		return false
+	}
+	if q.CompletedFrom != "" || q.CompletedTo != "" {
+		if task.CompletedDate == nil || !inRange(*task.CompletedDate, q.CompletedFrom, q.CompletedTo) {
+			return false
+		}
+	}
+	if q.Completed != nil && task.IsCompleted != *q.Completed {
+		return false
+	}
+	if len(q.CategoryIDs) > 0 {
+		found := false
+		for _, id := range q.CategoryIDs {
+			if task.CategoryID != nil && *task.CategoryID == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.MinDragDays != nil && task.DragDays < *q.MinDragDays {
+		return false
+	}
+	if q.MaxDragDays != nil && task.DragDays > *q.MaxDragDays {
+		return false
+	}
+
+	return matchesSearchTerms(task.Title, task.Description, terms)
+}
+
+// matchesSearchTerms reports whether every term matches the words of a
+// title or description
+func matchesSearchTerms(title, description string, terms []searchTerm) bool {
+	if len(terms) == 0 {
+		return true
+	}
+
+	titleTokens := tokenizeWithOffsets(title)
+	descTokens := tokenizeWithOffsets(description)
+	for _, term := range terms {
+		if len(matchTerm(titleTokens, term)) == 0 && len(matchTerm(descTokens, term)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// collectTasks loads every task matching q, for queries its filters keep
+// small, such as a date range. Unlike QueryTasks it counts nothing.
+func collectTasks(s TaskStore, q TaskQuery) ([]Task, error) {
+	var tasks []Task
+	err := s.EachTask(q, func(task Task) error {
+		tasks = append(tasks, task)
+		return nil
+	})
+	return tasks, err
+}
+
+// pageTasks filters, sorts and pages tasks held in memory for q
+func pageTasks(tasks []Task, q TaskQuery) *TaskPage {
+	terms := parseSearchQuery(q.Text)
+
+	type keyed struct {
+		task Task
+		key  string
+		null bool
+	}
+	var matched []keyed
+	for _, task := range tasks {
+		if matchesTaskQuery(task, q, terms) {
+			key, null := taskSortKey(task, q.Sort)
+			matched = append(matched, keyed{task, key, null})
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		return compareSortKeys(a.key, a.null, a.task.ID, b.key, b.null, b.task.ID, q) < 0
+	})
+
+	page := &TaskPage{Tasks: []Task{}, Total: len(matched)}
+
+	start := 0
+	if q.After != nil {
+		start = sort.Search(len(matched), func(i int) bool {
+			m := matched[i]
+			return compareSortKeys(m.key, m.null, m.task.ID, q.After.Key, q.After.Null, q.After.ID, q) > 0
+		})
+	}
+	end := len(matched)
+	if q.Limit > 0 && start+q.Limit < end {
+		end = start + q.Limit
+	}
+
+	for _, m := range matched[start:end] {
+		page.Tasks = append(page.Tasks, m.task)
+	}
+	if end < len(matched) {
+		last := matched[end-1]
+		cursor := EncodeTaskCursor(TaskCursor{Sort: q.sortSpec(), Key: last.key, Null: last.null, ID: last.task.ID})
+		page.NextCursor = &cursor
+	}
+
+	return page
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// createQueryTasks stores six tasks with repeated titles and priorities and
+// some missing values, returning their IDs in creation order
+func createQueryTasks(t *testing.T, s Store) []int64 {
+	t.Helper()
+	fixtures := []struct {
+		title    string
+		priority string // Empty for none
+		due      string
+	}{
+		{"Bravo", "P2", "2026-03-10"},
+		{"alpha", "", ""},
+		{"Charlie", "P0", "2026-03-05"},
+		{"Bravo", "P2", ""},
+		{"delta", "", "2026-03-05"},
+		{"Echo", "P1", "2026-03-20"},
+	}
+
+	ids := make([]int64, len(fixtures))
+	for i, f := range fixtures {
+		req := TaskRequest{Title: f.title, Date: testMonday}
+		if f.priority != "" {
+			req.Priority = &fixtures[i].priority
+		}
+		if f.due != "" {
+			req.DueDate = &fixtures[i].due
+		}
+		task, err := s.CreateTask(req, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = task.ID
+	}
+	return ids
+}
+
+// queryAllPages pages through q, passing each cursor through its token as
+// a client would, and returns the IDs in the order they came back
+func queryAllPages(t *testing.T, s Store, q TaskQuery) []int64 {
+	t.Helper()
+	var ids []int64
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("paging does not end")
+		}
+		page, err := s.QueryTasks(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Tasks) > q.Limit {
+			t.Fatalf("got %d tasks on a page of %d", len(page.Tasks), q.Limit)
+		}
+		for _, task := range page.Tasks {
+			ids = append(ids, task.ID)
+		}
+		if page.NextCursor == nil {
+			return ids
+		}
+		if q.After, err = DecodeTaskCursor(*page.NextCursor, q); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestQueryTasksCursorPaging(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		ids := createQueryTasks(t, s)
+		// order lists tasks by their index in createQueryTasks
+		order := func(indexes ...int) []int64 {
+			want := make([]int64, len(indexes))
+			for i, index := range indexes {
+				want[i] = ids[index]
+			}
+			return want
+		}
+
+		tests := []struct {
+			sort       string
+			descending bool
+			want       []int64
+		}{
+			{"id", false, order(0, 1, 2, 3, 4, 5)},
+			{"id", true, order(5, 4, 3, 2, 1, 0)},
+			// Titles sort without case; ties fall back to the ID
+			{"title", false, order(1, 0, 3, 2, 4, 5)},
+			// Tasks without a value sort last in both directions
+			{"priority", false, order(2, 5, 0, 3, 1, 4)},
+			{"priority", true, order(3, 0, 5, 2, 4, 1)},
+			{"due_date", false, order(2, 4, 0, 5, 1, 3)},
+			{"due_date", true, order(5, 0, 4, 2, 3, 1)},
+		}
+		for _, tt := range tests {
+			for _, limit := range []int{1, 2, 4, 6, 50} {
+				q := TaskQuery{Sort: tt.sort, Descending: tt.descending, Limit: limit}
+				if got := queryAllPages(t, s, q); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("%s (descending %v) by %d: got %v, want %v", tt.sort, tt.descending, limit, got, tt.want)
+				}
+			}
+		}
+	})
+}
+
+func TestQueryTasksCursorIsStable(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		ids := createQueryTasks(t, s)
+		q := TaskQuery{Sort: "priority", Limit: 2}
+
+		page, err := s.QueryTasks(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != 6 || page.NextCursor == nil {
+			t.Fatalf("got total %d, cursor %v; want 6 and a cursor", page.Total, page.NextCursor)
+		}
+
+		// A task added before the cursor and one removed after it must not
+		// shift the pages still to come
+		p0 := "P0"
+		if _, err := s.CreateTask(TaskRequest{Title: "Foxtrot", Date: testMonday, Priority: &p0}, "test"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DeleteTask(ids[0], "test"); err != nil {
+			t.Fatal(err)
+		}
+
+		if q.After, err = DecodeTaskCursor(*page.NextCursor, q); err != nil {
+			t.Fatal(err)
+		}
+		want := []int64{ids[3], ids[1], ids[4]}
+		if got := queryAllPages(t, s, q); !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v after the cursor, want %v", got, want)
+		}
+	})
+}
+
+func TestDecodeTaskCursor(t *testing.T) {
+	q := TaskQuery{Sort: "due_date", Descending: true}
+	cursor := TaskCursor{Sort: q.sortSpec(), Key: "2026-03-05", ID: 3}
+
+	got, err := DecodeTaskCursor(EncodeTaskCursor(cursor), q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != cursor {
+		t.Errorf("got %+v, want %+v", *got, cursor)
+	}
+
+	if _, err := DecodeTaskCursor(EncodeTaskCursor(cursor), TaskQuery{Sort: "due_date"}); err == nil {
+		t.Error("got no error for a cursor made for another direction")
+	}
+	for _, token := range []string{"not base64!", "bm90IGpzb24"} {
+		if _, err := DecodeTaskCursor(token, q); err == nil {
+			t.Errorf("%q: got no error", token)
+		}
+	}
+}
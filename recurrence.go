@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RecurrenceRule is the subset of an RFC 5545 RRULE that recurring tasks
+// support: DAILY, WEEKLY and MONTHLY frequencies with INTERVAL, BYDAY and
+// BYMONTHDAY
+type RecurrenceRule struct {
+	Freq       string // DAILY, WEEKLY or MONTHLY
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int // Negative days count back from the end of the month
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// ParseRRule parses a rule such as "FREQ=WEEKLY;BYDAY=MO,WE,FR". An optional
+// "RRULE:" prefix is accepted.
+func ParseRRule(value string) (*RecurrenceRule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	rule := &RecurrenceRule{Interval: 1}
+
+	for _, part := range strings.Split(value, ";") {
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+		key, val = strings.ToUpper(strings.TrimSpace(key)), strings.ToUpper(strings.TrimSpace(val))
+
+		switch key {
+		case "FREQ":
+			switch val {
+			case "DAILY", "WEEKLY", "MONTHLY":
+				rule.Freq = val
+			default:
+				return nil, fmt.Errorf("unsupported FREQ %q", val)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", val)
+			}
+			rule.Interval = n
+		case "BYDAY":
+			for _, name := range strings.Split(val, ",") {
+				day, ok := rruleWeekdays[name]
+				if !ok {
+					return nil, fmt.Errorf("invalid BYDAY %q", name)
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(v)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %q", v)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rule part %s", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("FREQ is required")
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq != "MONTHLY" {
+		return nil, fmt.Errorf("BYMONTHDAY needs FREQ=MONTHLY")
+	}
+	return rule, nil
+}
+
+// Occurs reports whether the rule, anchored on start, has an occurrence on date
+func (r *RecurrenceRule) Occurs(start, date time.Time) bool {
+	if date.Before(start) {
+		return false
+	}
+
+	switch r.Freq {
+	case "DAILY":
+		days := int(date.Sub(start).Hours()/24 + 0.5)
+		return days%r.Interval == 0 && r.matchesDay(date, nil)
+
+	case "WEEKLY":
+		// Weeks start on Monday, the RRULE default
+		weeks := int(weekStart(date).Sub(weekStart(start)).Hours()/(24*7) + 0.5)
+		return weeks%r.Interval == 0 && r.matchesDay(date, []time.Weekday{start.Weekday()})
+
+	case "MONTHLY":
+		months := (date.Year()-start.Year())*12 + int(date.Month()-start.Month())
+		if months%r.Interval != 0 || !r.matchesDay(date, nil) {
+			return false
+		}
+		monthDays := r.ByMonthDay
+		if len(monthDays) == 0 {
+			if len(r.ByDay) > 0 {
+				return true
+			}
+			monthDays = []int{start.Day()}
+		}
+		lastDay := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		for _, d := range monthDays {
+			if d < 0 {
+				d = lastDay + d + 1
+			}
+			if d == date.Day() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// matchesDay checks BYDAY, falling back to defaults when it is not set
+func (r *RecurrenceRule) matchesDay(date time.Time, defaults []time.Weekday) bool {
+	days := r.ByDay
+	if len(days) == 0 {
+		days = defaults
+	}
+	if len(days) == 0 {
+		return true
+	}
+	for _, day := range days {
+		if date.Weekday() == day {
+			return true
+		}
+	}
+	return false
+}
+
+// weekStart returns the Monday on or before t
+func weekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return t.AddDate(0, 0, -offset)
+}
+
+// recurringMu serializes materialization so concurrent page views and the
+// scheduler never create the same occurrence twice
+var recurringMu sync.Mutex
+
+// MaterializeRecurring creates the tasks recurring templates owe up to today,
+// plus any occurrence on date when a future date is being viewed. Occurrences
+// missed before today are created on their own date (and carried forward by
+// rollover) or skipped, according to each template's missed policy. It returns
+// the number of tasks created.
+func MaterializeRecurring(s Store, date, today string) (int, error) {
+	recurringMu.Lock()
+	defer recurringMu.Unlock()
+
+	templates, err := s.GetRecurringTasks()
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, rt := range templates {
+		rule, err := ParseRRule(rt.Rule)
+		if err != nil {
+			log.Printf("Skipping recurring task %d: %v", rt.ID, err)
+			continue
+		}
+		start, err := time.Parse("2006-01-02", rt.StartDate)
+		if err != nil {
+			log.Printf("Skipping recurring task %d: invalid start date %q", rt.ID, rt.StartDate)
+			continue
+		}
+
+		from := rt.StartDate
+		if rt.GeneratedThrough != nil && AddDays(*rt.GeneratedThrough, 1) > from {
+			from = AddDays(*rt.GeneratedThrough, 1)
+		}
+		through := today
+		if rt.EndDate != nil && *rt.EndDate < through {
+			through = *rt.EndDate
+		}
+
+		// Catch up on every occurrence up to today
+		for d := from; d <= through; d = AddDays(d, 1) {
+			day, _ := time.Parse("2006-01-02", d)
+			if !rule.Occurs(start, day) {
+				continue
+			}
+			skip := d < today && rt.MissedPolicy == MissedSkip
+			n, err := materializeOccurrence(s, rt, d, skip)
+			if err != nil {
+				return created, err
+			}
+			created += n
+		}
+		if from <= through {
+			if err := s.SetRecurringGeneratedThrough(rt.ID, through); err != nil {
+				return created, err
+			}
+		}
+
+		// Looking ahead only creates the day being looked at
+		if date > today && date >= rt.StartDate && (rt.EndDate == nil || date <= *rt.EndDate) {
+			day, err := time.Parse("2006-01-02", date)
+			if err == nil && rule.Occurs(start, day) {
+				n, err := materializeOccurrence(s, rt, date, false)
+				if err != nil {
+					return created, err
+				}
+				created += n
+			}
+		}
+	}
+
+	return created, nil
+}
+
+// materializeOccurrence creates (or, when skip is set, just records) a
+// template's occurrence on date unless it has been handled before
+func materializeOccurrence(s Store, rt RecurringTask, date string, skip bool) (int, error) {
+	task, err := s.MaterializeOccurrence(rt, date, skip)
+	if err != nil || task == nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// occurrenceRequest is the task a template's occurrence on date creates
+func occurrenceRequest(rt RecurringTask, date string) TaskRequest {
+	id := rt.ID
+	return TaskRequest{
+		Title:       rt.Title,
+		Description: rt.Description,
+		Date:        date,
+		Priority:    rt.Priority,
+		CategoryID:  rt.CategoryID,
+		RecurringID: &id,
+	}
+}
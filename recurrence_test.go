@@ -0,0 +1,188 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func mustParseRRule(t *testing.T, value string) *RecurrenceRule {
+	t.Helper()
+	rule, err := ParseRRule(value)
+	if err != nil {
+		t.Fatalf("parsing %q: %v", value, err)
+	}
+	return rule
+}
+
+func TestParseRRule(t *testing.T) {
+	rule := mustParseRRule(t, "RRULE:freq=weekly;interval=2;byday=mo,fr")
+	if rule.Freq != "WEEKLY" || rule.Interval != 2 || len(rule.ByDay) != 2 {
+		t.Errorf("got %+v", rule)
+	}
+
+	for _, value := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;COUNT=3",
+		"FREQ",
+	} {
+		if _, err := ParseRRule(value); err == nil {
+			t.Errorf("%q: got no error", value)
+		}
+	}
+}
+
+func TestRecurrenceRuleOccurs(t *testing.T) {
+	tests := []struct {
+		rule  string
+		start string
+		dates map[string]bool // date -> whether the rule occurs on it
+	}{
+		{"FREQ=DAILY;INTERVAL=2", "2026-03-02", map[string]bool{
+			"2026-03-01": false, // Before the start
+			"2026-03-02": true,
+			"2026-03-03": false,
+			"2026-03-04": true,
+			"2026-03-31": false, // Across the end of the month
+			"2026-04-01": true,
+		}},
+		{"FREQ=DAILY;BYDAY=MO,WE,FR", "2026-03-02", map[string]bool{
+			"2026-03-02": true,
+			"2026-03-03": false,
+			"2026-03-04": true,
+			"2026-03-07": false,
+		}},
+		// Without BYDAY a weekly rule falls on the start's weekday
+		{"FREQ=WEEKLY", "2026-03-04", map[string]bool{
+			"2026-03-04": true,
+			"2026-03-05": false,
+			"2026-03-11": true,
+		}},
+		// Weeks start on Monday, so starting on a Sunday puts the next
+		// Monday in the second week
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SU", "2026-03-01", map[string]bool{
+			"2026-03-01": true,
+			"2026-03-02": false,
+			"2026-03-08": false,
+			"2026-03-09": true,
+			"2026-03-15": true,
+			"2026-03-16": false,
+		}},
+		// Months without the start's day are skipped, not clamped
+		{"FREQ=MONTHLY", "2026-01-31", map[string]bool{
+			"2026-01-31": true,
+			"2026-02-28": false,
+			"2026-03-31": true,
+			"2026-04-30": false,
+		}},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1,15", "2026-01-01", map[string]bool{
+			"2026-01-15": true,
+			"2026-01-31": true,
+			"2026-02-27": false,
+			"2026-02-28": true,
+			"2026-04-30": true,
+		}},
+		{"FREQ=MONTHLY;INTERVAL=3;BYDAY=FR", "2026-01-01", map[string]bool{
+			"2026-01-02": true,
+			"2026-02-06": false,
+			"2026-04-03": true,
+			"2026-04-04": false,
+		}},
+	}
+
+	for _, tt := range tests {
+		rule := mustParseRRule(t, tt.rule)
+		start := mustParseDate(t, tt.start)
+		for date, want := range tt.dates {
+			if got := rule.Occurs(start, mustParseDate(t, date)); got != want {
+				t.Errorf("%s from %s on %s: got %v, want %v", tt.rule, tt.start, date, got, want)
+			}
+		}
+	}
+}
+
+func TestMaterializeRecurring(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		for _, policy := range []string{MissedRoll, MissedSkip} {
+			_, err := s.CreateRecurringTask(RecurringTask{
+				Title:        "Standup notes " + policy,
+				Rule:         "FREQ=DAILY;BYDAY=MO,WE,FR",
+				StartDate:    "2026-03-02",
+				MissedPolicy: policy,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// Catching up to Friday owes Monday, Wednesday and Friday; skipped
+		// templates only create today's
+		created, err := MaterializeRecurring(s, "2026-03-06", "2026-03-06")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if created != 4 {
+			t.Errorf("got %d tasks created, want 4", created)
+		}
+
+		created, err = MaterializeRecurring(s, "2026-03-06", "2026-03-06")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if created != 0 {
+			t.Errorf("got %d tasks created the second time, want 0", created)
+		}
+
+		// Looking ahead creates the day being looked at only
+		if created, err = MaterializeRecurring(s, "2026-03-11", "2026-03-06"); err != nil {
+			t.Fatal(err)
+		}
+		if created != 2 {
+			t.Errorf("got %d tasks created looking ahead, want 2", created)
+		}
+		if tasks, _ := s.GetTasksByDate("2026-03-09"); len(tasks) != 0 {
+			t.Errorf("got %d tasks on the skipped-over Monday, want 0", len(tasks))
+		}
+	})
+}
+
+func TestMaterializeOccurrence(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		rt, err := s.CreateRecurringTask(RecurringTask{Title: "Water plants", Rule: "FREQ=DAILY", StartDate: testMonday, MissedPolicy: MissedRoll})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		task, err := s.MaterializeOccurrence(*rt, testMonday, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if task == nil || task.Title != "Water plants" || task.RecurringID == nil || *task.RecurringID != rt.ID {
+			t.Fatalf("got %+v, want the template's task", task)
+		}
+		if again, err := s.MaterializeOccurrence(*rt, testMonday, false); err != nil || again != nil {
+			t.Errorf("got %+v (%v) materializing again, want nothing", again, err)
+		}
+
+		// A skipped occurrence is handled without a task
+		if skipped, err := s.MaterializeOccurrence(*rt, testTuesday, true); err != nil || skipped != nil {
+			t.Errorf("got %+v (%v) skipping, want nothing", skipped, err)
+		}
+		if again, err := s.MaterializeOccurrence(*rt, testTuesday, false); err != nil || again != nil {
+			t.Errorf("got %+v (%v) after skipping, want nothing", again, err)
+		}
+		if tasks, _ := s.GetTasksByDate(testTuesday); len(tasks) != 0 {
+			t.Errorf("got %d tasks on the skipped day", len(tasks))
+		}
+
+		// Occurrences are not the user's doing, so there is nothing to undo
+		if _, err := s.Undo("test"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v undoing, want ErrNotFound", err)
+		}
+	})
+}
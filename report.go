@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Report periods
+const (
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+// reportTopDragged is how many of the most dragged tasks a report lists by default
+const reportTopDragged = 5
+
+// ReportTotals are the headline numbers of a report period
+type ReportTotals struct {
+	TasksCreated   int      `json:"tasks_created"`
+	TasksCompleted int      `json:"tasks_completed"`
+	CompletionRate *float64 `json:"completion_rate"` // Share of the tasks created in the period that were completed in it; null when none were created
+	AvgDragDays    *float64 `json:"avg_drag_days"`   // Over the tasks completed in the period; null when none were
+	MaxDragDays    int      `json:"max_drag_days"`
+}
+
+// CategoryReport is a report period's totals for one category
+type CategoryReport struct {
+	CategoryID *int64 `json:"category_id"` // Null for uncategorized tasks
+	Name       string `json:"name"`
+	Color      string `json:"color"`
+	ReportTotals
+}
+
+// DraggedTask is one of the most dragged tasks of a report period
+type DraggedTask struct {
+	TaskID        int64   `json:"task_id"`
+	Title         string  `json:"title"`
+	CategoryID    *int64  `json:"category_id"`
+	DragDays      int     `json:"drag_days"`
+	IsCompleted   bool    `json:"is_completed"`
+	CompletedDate *string `json:"completed_date"`
+}
+
+// PeriodTotals are the totals of the period before a report's
+type PeriodTotals struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	ReportTotals
+}
+
+// ReportChange is how a report's totals moved since the previous period.
+// Rates and averages are null when either period has none.
+type ReportChange struct {
+	TasksCreated   int      `json:"tasks_created"`
+	TasksCompleted int      `json:"tasks_completed"`
+	CompletionRate *float64 `json:"completion_rate"`
+	AvgDragDays    *float64 `json:"avg_drag_days"`
+	MaxDragDays    int      `json:"max_drag_days"`
+}
+
+// Report is a productivity report over a week or a month
+type Report struct {
+	Period string `json:"period"`
+	Start  string `json:"start"`
+	End    string `json:"end"`
+	ReportTotals
+	Categories []CategoryReport `json:"categories"`
+	TopDragged []DraggedTask    `json:"top_dragged"`
+	Previous   PeriodTotals     `json:"previous"`
+	Change     ReportChange     `json:"change"`
+}
+
+// reportPeriod returns the first and last day of the week (from Monday) or
+// month containing date
+func reportPeriod(period, date string) (string, string, error) {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return "", "", fmt.Errorf("start must be YYYY-MM-DD")
+	}
+
+	switch period {
+	case PeriodWeek:
+		start := weekStart(day)
+		return start.Format("2006-01-02"), start.AddDate(0, 0, 6).Format("2006-01-02"), nil
+	case PeriodMonth:
+		start := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start.Format("2006-01-02"), start.AddDate(0, 1, -1).Format("2006-01-02"), nil
+	}
+	return "", "", fmt.Errorf("period must be week or month")
+}
+
+// BuildReport reports on the week or month containing date, comparing it with
+// the period before. top caps the most dragged tasks listed.
+func BuildReport(s TaskStore, period, date string, top int) (*Report, error) {
+	start, end, err := reportPeriod(period, date)
+	if err != nil {
+		return nil, err
+	}
+	prevStart, prevEnd, _ := reportPeriod(period, AddDays(start, -1))
+
+	created, completed, pending, err := reportTasks(s, start, end)
+	if err != nil {
+		return nil, err
+	}
+	prevCreated, prevCompleted, _, err := reportTasks(s, prevStart, prevEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Period:       period,
+		Start:        start,
+		End:          end,
+		ReportTotals: reportTotals(created, completed, end),
+		Categories:   categoryReports(created, completed, end),
+		TopDragged:   topDragged(completed, pending, top),
+		Previous: PeriodTotals{
+			Start:        prevStart,
+			End:          prevEnd,
+			ReportTotals: reportTotals(prevCreated, prevCompleted, prevEnd),
+		},
+	}
+
+	now, prev := report.ReportTotals, report.Previous.ReportTotals
+	report.Change = ReportChange{
+		TasksCreated:   now.TasksCreated - prev.TasksCreated,
+		TasksCompleted: now.TasksCompleted - prev.TasksCompleted,
+		CompletionRate: floatChange(now.CompletionRate, prev.CompletionRate, 3),
+		AvgDragDays:    floatChange(now.AvgDragDays, prev.AvgDragDays, 2),
+		MaxDragDays:    now.MaxDragDays - prev.MaxDragDays,
+	}
+
+	return report, nil
+}
+
+// reportTasks loads the tasks created in a period, the tasks completed in it
+// and the tasks still pending on a date in it. Each query is bounded by the
+// period's dates.
+func reportTasks(s TaskStore, start, end string) (created, completed, pending []Task, err error) {
+	done, open := true, false
+	if created, err = collectTasks(s, TaskQuery{CreatedFrom: start, CreatedTo: end, Sort: "id"}); err != nil {
+		return nil, nil, nil, err
+	}
+	if completed, err = collectTasks(s, TaskQuery{CompletedFrom: start, CompletedTo: end, Completed: &done, Sort: "id"}); err != nil {
+		return nil, nil, nil, err
+	}
+	if pending, err = collectTasks(s, TaskQuery{AssignedFrom: start, AssignedTo: end, Completed: &open, Sort: "id"}); err != nil {
+		return nil, nil, nil, err
+	}
+	return created, completed, pending, nil
+}
+
+// reportTotals counts a period's created and completed tasks. Tasks created in
+// the period count towards the completion rate when they were completed by end.
+func reportTotals(created, completed []Task, end string) ReportTotals {
+	totals := ReportTotals{TasksCreated: len(created), TasksCompleted: len(completed)}
+
+	if len(created) > 0 {
+		done := 0
+		for _, task := range created {
+			if task.IsCompleted && task.CompletedDate != nil && *task.CompletedDate <= end {
+				done++
+			}
+		}
+		rate := round(float64(done)/float64(len(created)), 3)
+		totals.CompletionRate = &rate
+	}
+
+	if len(completed) > 0 {
+		sum := 0
+		for _, task := range completed {
+			sum += task.DragDays
+			if task.DragDays > totals.MaxDragDays {
+				totals.MaxDragDays = task.DragDays
+			}
+		}
+		avg := round(float64(sum)/float64(len(completed)), 2)
+		totals.AvgDragDays = &avg
+	}
+
+	return totals
+}
+
+// categoryReports breaks a period's totals down by category, by name with
+// uncategorized tasks last
+func categoryReports(created, completed []Task, end string) []CategoryReport {
+	type group struct {
+		report             CategoryReport
+		created, completed []Task
+	}
+	groups := map[int64]*group{}
+	groupOf := func(task Task) *group {
+		key := int64(0)
+		if task.Category != nil {
+			key = task.Category.ID
+		}
+		g, ok := groups[key]
+		if !ok {
+			g = &group{report: CategoryReport{Name: "Uncategorized"}}
+			if task.Category != nil {
+				id := task.Category.ID
+				g.report = CategoryReport{CategoryID: &id, Name: task.Category.Name, Color: task.Category.Color}
+			}
+			groups[key] = g
+		}
+		return g
+	}
+
+	for _, task := range created {
+		g := groupOf(task)
+		g.created = append(g.created, task)
+	}
+	for _, task := range completed {
+		g := groupOf(task)
+		g.completed = append(g.completed, task)
+	}
+
+	reports := []CategoryReport{}
+	for _, g := range groups {
+		g.report.ReportTotals = reportTotals(g.created, g.completed, end)
+		reports = append(reports, g.report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		a, b := reports[i], reports[j]
+		if (a.CategoryID == nil) != (b.CategoryID == nil) {
+			return b.CategoryID == nil
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.CategoryID != nil && *a.CategoryID < *b.CategoryID
+	})
+	return reports
+}
+
+// topDragged picks the n most dragged of a period's completed and pending
+// tasks, oldest first on ties
+func topDragged(completed, pending []Task, n int) []DraggedTask {
+	tasks := append(append([]Task{}, completed...), pending...)
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].DragDays != tasks[j].DragDays {
+			return tasks[i].DragDays > tasks[j].DragDays
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+
+	dragged := []DraggedTask{}
+	for _, task := range tasks {
+		if len(dragged) == n || task.DragDays == 0 {
+			break
+		}
+		dragged = append(dragged, DraggedTask{
+			TaskID:        task.ID,
+			Title:         task.Title,
+			CategoryID:    task.CategoryID,
+			DragDays:      task.DragDays,
+			IsCompleted:   task.IsCompleted,
+			CompletedDate: task.CompletedDate,
+		})
+	}
+	return dragged
+}
+
+// floatChange is now minus prev, or nil when either is missing
+func floatChange(now, prev *float64, places int) *float64 {
+	if now == nil || prev == nil {
+		return nil
+	}
+	change := round(*now-*prev, places)
+	return &change
+}
+
+func round(v float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(v*scale) / scale
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestReportPeriod(t *testing.T) {
+	tests := []struct {
+		period, date string
+		start, end   string
+	}{
+		{PeriodWeek, testMonday, testMonday, "2026-03-08"},
+		{PeriodWeek, "2026-03-08", testMonday, "2026-03-08"}, // Weeks run Monday to Sunday
+		{PeriodMonth, "2026-02-14", "2026-02-01", "2026-02-28"},
+		{PeriodMonth, "2028-02-29", "2028-02-01", "2028-02-29"},
+	}
+	for _, tt := range tests {
+		start, end, err := reportPeriod(tt.period, tt.date)
+		if err != nil || start != tt.start || end != tt.end {
+			t.Errorf("%s of %s: got %s to %s (%v), want %s to %s", tt.period, tt.date, start, end, err, tt.start, tt.end)
+		}
+	}
+
+	if _, _, err := reportPeriod("year", testMonday); err == nil {
+		t.Error("got no error for an unknown period")
+	}
+	if _, _, err := reportPeriod(PeriodWeek, "March 2"); err == nil {
+		t.Error("got no error for an invalid date")
+	}
+}
+
+func TestBuildReport(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		useCalendar(t, testCalendar())
+		complete := func(task *Task, date string) {
+			t.Helper()
+			if _, err := s.UpdateTaskCompletion(task.ID, true, date, "test"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		rollover := func(task *Task, from, to string) {
+			t.Helper()
+			if _, err := s.RolloverTasks(from, to, RolloverOptions{IncludeTaskIDs: []int64{task.ID}}, "test"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		office, err := s.CreateCategory(CategoryRequest{Name: "Office", Color: "#123456", RolloverPolicy: RolloverNextDay})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The week before
+		complete(mustCreateTask(t, s, "Old", "2026-02-27"), "2026-02-27")
+
+		quick, err := s.CreateTask(TaskRequest{Title: "Quick", Date: testMonday, CategoryID: &office.ID}, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		complete(quick, testMonday)
+		slow := mustCreateTask(t, s, "Slow", testMonday)
+		rollover(slow, testMonday, "2026-03-05")
+		complete(slow, "2026-03-05")
+		open := mustCreateTask(t, s, "Open", testTuesday)
+		rollover(open, testTuesday, "2026-03-04")
+
+		report, err := BuildReport(s, PeriodWeek, "2026-03-04", 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rate, avg := 0.667, 1.5
+		want := ReportTotals{TasksCreated: 3, TasksCompleted: 2, CompletionRate: &rate, AvgDragDays: &avg, MaxDragDays: 3}
+		if report.Start != testMonday || report.End != "2026-03-08" || !reflect.DeepEqual(report.ReportTotals, want) {
+			t.Errorf("got %s to %s with %s, want %s to 2026-03-08 with %s", report.Start, report.End, totalsString(report.ReportTotals), testMonday, totalsString(want))
+		}
+
+		if len(report.Categories) != 2 || report.Categories[0].Name != "Office" || report.Categories[1].CategoryID != nil {
+			t.Fatalf("got categories %+v, want Office then uncategorized", report.Categories)
+		}
+		if got := report.Categories[1]; got.TasksCreated != 2 || got.TasksCompleted != 1 || *got.CompletionRate != 0.5 {
+			t.Errorf("got uncategorized %s", totalsString(got.ReportTotals))
+		}
+
+		if len(report.TopDragged) != 2 || report.TopDragged[0].TaskID != slow.ID || report.TopDragged[1].TaskID != open.ID {
+			t.Errorf("got top dragged %+v, want Slow then Open", report.TopDragged)
+		}
+
+		if got := report.Previous; got.Start != "2026-02-23" || got.TasksCreated != 1 || got.TasksCompleted != 1 || *got.CompletionRate != 1 {
+			t.Errorf("got previous %s from %s", totalsString(got.ReportTotals), got.Start)
+		}
+		rateChange, avgChange := -0.333, 1.5
+		wantChange := ReportChange{TasksCreated: 2, TasksCompleted: 1, CompletionRate: &rateChange, AvgDragDays: &avgChange, MaxDragDays: 3}
+		if !reflect.DeepEqual(report.Change, wantChange) {
+			t.Errorf("got change %+v", report.Change)
+		}
+
+		// An empty period has no rates to report
+		empty, err := BuildReport(s, PeriodMonth, "2026-06-01", 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if empty.CompletionRate != nil || empty.AvgDragDays != nil || empty.Change.CompletionRate != nil || len(empty.Categories) != 0 {
+			t.Errorf("got %+v for an empty month", empty)
+		}
+	})
+}
+
+// totalsString prints totals with their optional values dereferenced
+func totalsString(t ReportTotals) string {
+	value := func(v *float64) interface{} {
+		if v == nil {
+			return nil
+		}
+		return *v
+	}
+	return fmt.Sprintf("{created %d, completed %d, rate %v, avg %v, max %d}", t.TasksCreated, t.TasksCompleted, value(t.CompletionRate), value(t.AvgDragDays), t.MaxDragDays)
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// titlesOn maps the titles of the tasks on date to true
+func titlesOn(t *testing.T, s Store, date string) map[string]bool {
+	t.Helper()
+	tasks, err := s.GetTasksByDate(date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	titles := make(map[string]bool, len(tasks))
+	for _, task := range tasks {
+		titles[task.Title] = true
+	}
+	return titles
+}
+
+func TestRolloverDryRun(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		task := mustCreateTask(t, s, "Pending", testMonday)
+		done := mustCreateTask(t, s, "Done", testMonday)
+		if _, err := s.UpdateTaskCompletion(done.ID, true, testMonday, "test"); err != nil {
+			t.Fatal(err)
+		}
+		ops, _ := s.GetOperations(10)
+
+		moves, err := s.RolloverTasks(testMonday, testTuesday, RolloverOptions{DryRun: true}, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []RolloverMove{{TaskID: task.ID, Title: "Pending", Action: RolloverRolledOver, FromDate: testMonday, ToDate: testTuesday, DragDays: 1}}
+		if !reflect.DeepEqual(moves, want) {
+			t.Errorf("got %+v, want %+v", moves, want)
+		}
+
+		if got, _ := s.GetTaskByID(task.ID); got.AssignedDate != testMonday {
+			t.Errorf("a dry run moved the task to %s", got.AssignedDate)
+		}
+		if history, _ := s.GetTaskHistory(task.ID); len(history) != 1 {
+			t.Errorf("a dry run recorded events: %+v", history)
+		}
+		if after, _ := s.GetOperations(10); len(after) != len(ops) {
+			t.Error("a dry run was journaled")
+		}
+
+		// The real rollover does what the preview said
+		moves, err = s.RolloverTasks(testMonday, testTuesday, RolloverOptions{}, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(moves, want) {
+			t.Errorf("got %+v, want the previewed %+v", moves, want)
+		}
+	})
+}
+
+func TestRolloverSelection(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		work, err := s.CreateCategory(CategoryRequest{Name: "Office", Color: "#123456", RolloverPolicy: RolloverNextDay})
+		if err != nil {
+			t.Fatal(err)
+		}
+		home, err := s.CreateCategory(CategoryRequest{Name: "House", Color: "#654321", RolloverPolicy: RolloverNextDay})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := map[string]int64{}
+		for title, categoryID := range map[string]*int64{"Report": &work.ID, "Slides": &work.ID, "Laundry": &home.ID, "Loose": nil} {
+			task, err := s.CreateTask(TaskRequest{Title: title, Date: testMonday, CategoryID: categoryID}, "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids[title] = task.ID
+		}
+
+		tests := []struct {
+			name string
+			opts RolloverOptions
+			want []string
+		}{
+			{"every task", RolloverOptions{}, []string{"Laundry", "Loose", "Report", "Slides"}},
+			{"include tasks", RolloverOptions{IncludeTaskIDs: []int64{ids["Report"], ids["Loose"]}}, []string{"Loose", "Report"}},
+			{"exclude tasks", RolloverOptions{ExcludeTaskIDs: []int64{ids["Report"]}}, []string{"Laundry", "Loose", "Slides"}},
+			// Uncategorized tasks are left out when categories are included
+			{"include categories", RolloverOptions{IncludeCategoryIDs: []int64{work.ID}}, []string{"Report", "Slides"}},
+			{"exclude categories", RolloverOptions{ExcludeCategoryIDs: []int64{work.ID}}, []string{"Laundry", "Loose"}},
+			{"include and exclude", RolloverOptions{IncludeCategoryIDs: []int64{work.ID}, ExcludeTaskIDs: []int64{ids["Slides"]}}, []string{"Report"}},
+		}
+		for _, tt := range tests {
+			tt.opts.DryRun = true
+			moves, err := s.RolloverTasks(testMonday, testTuesday, tt.opts, "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, m := range moves {
+				got = append(got, m.Title)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+			}
+		}
+
+		opts := RolloverOptions{ExcludeCategoryIDs: []int64{home.ID}, ExcludeTaskIDs: []int64{ids["Loose"]}}
+		if _, err := s.RolloverTasks(testMonday, testTuesday, opts, "test"); err != nil {
+			t.Fatal(err)
+		}
+		if got := titlesOn(t, s, testMonday); !reflect.DeepEqual(got, map[string]bool{"Laundry": true, "Loose": true}) {
+			t.Errorf("left on Monday: %v", got)
+		}
+	})
+}
+
+func TestPlanRolloverMove(t *testing.T) {
+	useCalendar(t, testCalendar())
+	three := 3
+	tests := []struct {
+		name         string
+		policy       string
+		createdDate  string
+		toDate       string
+		archiveAfter *int
+		wantMove     bool
+		want         RolloverMove
+	}{
+		{"next day onto a weekend", RolloverNextDay, "2026-04-01", "2026-04-04", nil, true,
+			RolloverMove{Action: RolloverRolledOver, ToDate: "2026-04-04", DragDays: 1}},
+		{"uncategorized", "", "2026-04-01", "2026-04-02", nil, true,
+			RolloverMove{Action: RolloverRolledOver, ToDate: "2026-04-02", DragDays: 1}},
+		{"next business day over a holiday", RolloverNextBusinessDay, "2026-04-01", "2026-04-03", nil, true,
+			RolloverMove{Action: RolloverRolledOver, ToDate: "2026-04-06", DragDays: 2}},
+		{"next business day on a working day", RolloverNextBusinessDay, "2026-04-01", "2026-04-02", nil, true,
+			RolloverMove{Action: RolloverRolledOver, ToDate: "2026-04-02", DragDays: 1}},
+		{"next week midweek", RolloverNextWeek, "2026-03-31", "2026-04-01", nil, true,
+			RolloverMove{Action: RolloverRolledOver, ToDate: "2026-04-06", DragDays: 3}},
+		{"next week on a Monday", RolloverNextWeek, "2026-04-02", "2026-04-06", nil, true,
+			RolloverMove{Action: RolloverRolledOver, ToDate: "2026-04-06", DragDays: 1}},
+		{"never", RolloverNever, "2026-04-01", "2026-04-02", nil, false, RolloverMove{}},
+		{"archive at the threshold", RolloverArchive, "2026-03-30", "2026-04-02", &three, true,
+			RolloverMove{Action: RolloverArchived, DragDays: 3}},
+		{"archive under the threshold", RolloverArchive, "2026-03-31", "2026-04-02", &three, true,
+			RolloverMove{Action: RolloverRolledOver, ToDate: "2026-04-02", DragDays: 2}},
+		{"archive without a threshold", RolloverArchive, "2026-03-02", "2026-04-02", nil, true,
+			RolloverMove{Action: RolloverRolledOver, ToDate: "2026-04-02", DragDays: 23}},
+	}
+	for _, tt := range tests {
+		m := RolloverMove{ToDate: tt.toDate}
+		if moved := planRolloverMove(&m, tt.createdDate, tt.policy, tt.archiveAfter); moved != tt.wantMove {
+			t.Errorf("%s: got moved %v, want %v", tt.name, moved, tt.wantMove)
+			continue
+		}
+		if tt.wantMove && m != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, m, tt.want)
+		}
+	}
+}
+
+func TestRolloverCategoryPolicies(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		useCalendar(t, testCalendar())
+		two := 2
+		categoryIDs := map[string]*int64{}
+		for _, req := range []CategoryRequest{
+			{Name: "Pinned", Color: "#111111", RolloverPolicy: RolloverNever},
+			{Name: "Weekly", Color: "#222222", RolloverPolicy: RolloverNextWeek},
+			{Name: "Stale", Color: "#333333", RolloverPolicy: RolloverArchive, ArchiveAfterDays: &two},
+		} {
+			category, err := s.CreateCategory(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			categoryIDs[req.Name] = &category.ID
+		}
+		tasks := map[string]int64{}
+		for _, title := range []string{"Pinned", "Weekly", "Stale"} {
+			task, err := s.CreateTask(TaskRequest{Title: title, Date: "2026-03-30", CategoryID: categoryIDs[title]}, "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tasks[title] = task.ID
+		}
+
+		moves, err := s.RolloverTasks("2026-03-30", "2026-04-01", RolloverOptions{}, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rolled, archived := countRolloverMoves(moves); rolled != 1 || archived != 1 {
+			t.Errorf("got %d rolled and %d archived, want 1 and 1: %+v", rolled, archived, moves)
+		}
+
+		if got := titlesOn(t, s, "2026-03-30"); !reflect.DeepEqual(got, map[string]bool{"Pinned": true}) {
+			t.Errorf("left behind: %v, want only Pinned", got)
+		}
+		if got := titlesOn(t, s, "2026-04-06"); !reflect.DeepEqual(got, map[string]bool{"Weekly": true}) {
+			t.Errorf("on the next Monday: %v, want Weekly", got)
+		}
+		if _, err := s.GetTaskByID(tasks["Stale"]); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v for the archived task, want it in the trash", err)
+		}
+		trash, err := s.GetTrash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(trash.Tasks) != 1 || trash.Tasks[0].ID != tasks["Stale"] {
+			t.Errorf("got trash %+v, want the archived task", trash.Tasks)
+		}
+	})
+}
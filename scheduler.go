@@ -0,0 +1,109 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// RolloverScheduler rolls pending tasks forward once per local day. It polls
+// the clock rather than sleeping until midnight, so it copes with DST changes,
+// suspended hosts and simulated clocks, and it catches up on startup if the
+// last recorded run is from an earlier day.
+type RolloverScheduler struct {
+	store      Store
+	interval   time.Duration
+	lastDate   string // date of the last successful run
+	failedDate string // date of the last recorded failure, to avoid one row per poll
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// NewRolloverScheduler creates a scheduler that checks the date every interval
+func NewRolloverScheduler(s Store, interval time.Duration) *RolloverScheduler {
+	return &RolloverScheduler{
+		store:    s,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start loads the last recorded run and starts the background goroutine
+func (sc *RolloverScheduler) Start() error {
+	last, err := sc.store.GetLastRolloverRun()
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	if last != nil {
+		sc.lastDate = last.RunDate
+	}
+
+	go sc.loop()
+	return nil
+}
+
+// Stop halts the scheduler and waits for an in-flight run to finish
+func (sc *RolloverScheduler) Stop() {
+	close(sc.stop)
+	<-sc.done
+}
+
+func (sc *RolloverScheduler) loop() {
+	defer close(sc.done)
+
+	// Anything missed while the server was down is picked up straight away
+	sc.check("catch-up")
+
+	ticker := time.NewTicker(sc.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			sc.check("scheduled")
+		case <-sc.stop:
+			return
+		}
+	}
+}
+
+// check runs the rollover if it has not yet succeeded for today
+func (sc *RolloverScheduler) check(trigger string) {
+	today := GetToday()
+	if today <= sc.lastDate {
+		return
+	}
+
+	// The day's recurring tasks exist before pending work is carried onto it
+	if _, err := MaterializeRecurring(sc.store, today, today); err != nil {
+		log.Printf("Materializing recurring tasks for %s failed: %v", today, err)
+	}
+
+	run := &RolloverRun{
+		RunDate:   today,
+		Trigger:   trigger,
+		StartedAt: clock.Now().UTC(),
+	}
+
+	moves, err := sc.store.RolloverAllPendingTasks(today, RolloverOptions{}, "scheduler")
+	run.FinishedAt = clock.Now().UTC()
+	moved, archived := countRolloverMoves(moves)
+	run.TasksMoved = moved
+
+	if err != nil {
+		log.Printf("Scheduled rollover for %s failed: %v", today, err)
+		if sc.failedDate == today {
+			return
+		}
+		sc.failedDate = today
+		run.Error = err.Error()
+	} else {
+		sc.lastDate = today
+		log.Printf("Scheduled rollover for %s moved %d task(s) and archived %d", today, moved, archived)
+		recordEscalations(sc.store, "scheduler")
+	}
+
+	if err := sc.store.RecordRolloverRun(run); err != nil {
+		log.Printf("Failed to record rollover run: %v", err)
+	}
+}
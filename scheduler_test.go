@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRolloverSchedulerCheck(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		setClock(t, testNow)
+		task := mustCreateTask(t, s, "Left over", "2026-03-01")
+
+		sc := NewRolloverScheduler(s, time.Hour)
+		sc.check("catch-up")
+
+		got, err := s.GetTaskByID(task.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.AssignedDate != testMonday {
+			t.Errorf("got the task on %s, want %s", got.AssignedDate, testMonday)
+		}
+
+		run, err := s.GetLastRolloverRun()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run.RunDate != testMonday || run.Trigger != "catch-up" || run.TasksMoved != 1 || run.Error != "" {
+			t.Errorf("got run %+v", run)
+		}
+		if !run.StartedAt.Equal(testNow) || !run.FinishedAt.Equal(testNow) {
+			t.Errorf("got run from %v to %v, want both at %v", run.StartedAt, run.FinishedAt, testNow)
+		}
+
+		// Once a day has run, later polls that day do nothing
+		sc.check("scheduled")
+		if runs, _ := s.GetRolloverRuns(10); len(runs) != 1 {
+			t.Errorf("got %d runs after polling again, want 1", len(runs))
+		}
+
+		// A fresh scheduler picks up the last run from the store
+		restarted := NewRolloverScheduler(s, time.Hour)
+		if err := restarted.Start(); err != nil {
+			t.Fatal(err)
+		}
+		restarted.Stop()
+		if runs, _ := s.GetRolloverRuns(10); len(runs) != 1 {
+			t.Errorf("got %d runs after restarting, want 1", len(runs))
+		}
+
+		// The next local day runs again
+		setClock(t, testNow.AddDate(0, 0, 1))
+		sc.check("scheduled")
+		if runs, _ := s.GetRolloverRuns(10); len(runs) != 2 || runs[0].RunDate != testTuesday {
+			t.Errorf("got runs %+v, want a second one for %s", runs, testTuesday)
+		}
+	})
+}
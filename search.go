@@ -0,0 +1,301 @@
+package main
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Snippet markers around matched terms in search results. The rest of the
+// highlighted text is HTML-escaped, so the markers are its only markup.
+const (
+	highlightStart = "<mark>"
+	highlightEnd   = "</mark>"
+	snippetWords   = 16 // Words of context in a description snippet
+)
+
+// FTS5 wraps matches in these control characters, which are swapped for the
+// highlight markers once the text around them is escaped
+const (
+	ftsHighlightStart = "\x02"
+	ftsHighlightEnd   = "\x03"
+)
+
+// ftsHighlighter escapes FTS5 highlighted text and marks its matches
+var ftsHighlighter = strings.NewReplacer(ftsHighlightStart, highlightStart, ftsHighlightEnd, highlightEnd)
+
+// escapeFTSHighlight turns text highlighted by FTS5 into escaped HTML with
+// the matches marked
+func escapeFTSHighlight(text string) string {
+	return ftsHighlighter.Replace(html.EscapeString(text))
+}
+
+// SearchQuery is a full-text search over task titles and descriptions.
+// Unset filters match everything.
+type SearchQuery struct {
+	Text       string
+	Completed  *bool
+	CategoryID *int64
+	FromDate   string // Inclusive bounds on the assigned date
+	ToDate     string
+	Limit      int
+}
+
+// SearchResult is a matching task with its relevance and highlighted text
+type SearchResult struct {
+	Task           Task    `json:"task"`
+	Score          float64 `json:"score"`           // Higher is more relevant
+	TitleHighlight string  `json:"title_highlight"` // Title with matches wrapped in <mark>
+	Snippet        string  `json:"snippet"`         // Description excerpt around the matches
+}
+
+// searchTerm is one word, prefix (word*) or quoted phrase of a query
+type searchTerm struct {
+	words  []string // Lower-cased; more than one for a phrase
+	prefix bool     // Last word matches as a prefix
+}
+
+// parseSearchQuery splits a query into terms. Double quotes group a phrase
+// and a trailing * makes a prefix query; every term must match.
+func parseSearchQuery(q string) []searchTerm {
+	var terms []searchTerm
+
+	for i, part := range strings.Split(q, `"`) {
+		// Odd parts sit between a pair of quotes
+		if i%2 == 1 {
+			prefix := strings.HasSuffix(strings.TrimSpace(part), "*")
+			if words := tokenize(part); len(words) > 0 {
+				terms = append(terms, searchTerm{words: words, prefix: prefix})
+			}
+			continue
+		}
+		for _, field := range strings.Fields(part) {
+			prefix := strings.HasSuffix(field, "*")
+			for _, word := range tokenize(field) {
+				terms = append(terms, searchTerm{words: []string{word}, prefix: prefix})
+			}
+		}
+	}
+
+	return terms
+}
+
+// ftsMatchExpression renders terms as an FTS5 MATCH expression. Every word is
+// quoted so user input can never be read as FTS5 query syntax.
+func ftsMatchExpression(terms []searchTerm) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = `"` + strings.Join(term.words, " ") + `"`
+		if term.prefix {
+			parts[i] += " *"
+		}
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// tokenize lower-cases text and splits it into letter and digit runs, much
+// like FTS5's unicode61 tokenizer
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// textToken is a word of a text with its byte offsets
+type textToken struct {
+	word       string
+	start, end int
+}
+
+func tokenizeWithOffsets(text string) []textToken {
+	var tokens []textToken
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, textToken{strings.ToLower(text[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, textToken{strings.ToLower(text[start:]), start, len(text)})
+	}
+	return tokens
+}
+
+// matchTerm returns the token index ranges where term occurs in tokens
+func matchTerm(tokens []textToken, term searchTerm) [][2]int {
+	var spans [][2]int
+	n := len(term.words)
+	for i := 0; i+n <= len(tokens); i++ {
+		matched := true
+		for j, word := range term.words {
+			token := tokens[i+j].word
+			if term.prefix && j == n-1 {
+				matched = strings.HasPrefix(token, word)
+			} else {
+				matched = token == word
+			}
+			if !matched {
+				break
+			}
+		}
+		if matched {
+			spans = append(spans, [2]int{i, i + n})
+		}
+	}
+	return spans
+}
+
+// scoreTask matches terms against a task the way the FTS5 index would and
+// builds its highlights. It reports false if any term is missing.
+func scoreTask(task Task, terms []searchTerm) (SearchResult, bool) {
+	titleTokens := tokenizeWithOffsets(task.Title)
+	descTokens := tokenizeWithOffsets(task.Description)
+
+	var titleSpans, descSpans [][2]int
+	score := 0.0
+	for _, term := range terms {
+		inTitle := matchTerm(titleTokens, term)
+		inDesc := matchTerm(descTokens, term)
+		if len(inTitle) == 0 && len(inDesc) == 0 {
+			return SearchResult{}, false
+		}
+		// Title matches weigh ten times as much, like the bm25 weights
+		score += 10*float64(len(inTitle)) + float64(len(inDesc))
+		titleSpans = append(titleSpans, inTitle...)
+		descSpans = append(descSpans, inDesc...)
+	}
+
+	return SearchResult{
+		Task:           task,
+		Score:          score,
+		TitleHighlight: highlightSpans(task.Title, titleTokens, titleSpans, 0, len(titleTokens)),
+		Snippet:        descriptionSnippet(task.Description, descTokens, descSpans),
+	}, true
+}
+
+// descriptionSnippet picks a window of snippetWords tokens around the first
+// match, marking ellipses where the text is cut
+func descriptionSnippet(text string, tokens []textToken, spans [][2]int) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+
+	from := 0
+	if len(spans) > 0 {
+		sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+		from = spans[0][0] - snippetWords/4
+		if from < 0 {
+			from = 0
+		}
+	}
+	to := from + snippetWords
+	if to > len(tokens) {
+		to = len(tokens)
+	}
+
+	snippet := highlightSpans(text, tokens, spans, from, to)
+	if from > 0 {
+		snippet = "…" + snippet
+	}
+	if to < len(tokens) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// highlightSpans renders text from token from up to token to as escaped
+// HTML, wrapping the matched token spans in highlight markers
+func highlightSpans(text string, tokens []textToken, spans [][2]int, from, to int) string {
+	if len(tokens) == 0 {
+		return html.EscapeString(text)
+	}
+
+	marked := make([]bool, len(tokens))
+	for _, span := range spans {
+		for i := span[0]; i < span[1]; i++ {
+			marked[i] = true
+		}
+	}
+
+	start, end := 0, len(text)
+	if from > 0 {
+		start = tokens[from].start
+	}
+	if to < len(tokens) {
+		end = tokens[to-1].end
+	}
+
+	var b strings.Builder
+	pos := start
+	for i := from; i < to; i++ {
+		if !marked[i] || (i > from && marked[i-1]) {
+			continue
+		}
+		last := i
+		for last+1 < to && marked[last+1] {
+			last++
+		}
+		b.WriteString(html.EscapeString(text[pos:tokens[i].start]))
+		b.WriteString(highlightStart)
+		b.WriteString(html.EscapeString(text[tokens[i].start:tokens[last].end]))
+		b.WriteString(highlightEnd)
+		pos = tokens[last].end
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	return b.String()
+}
+
+// matchesSearchFilters applies a query's non-text filters to a task
+func matchesSearchFilters(task Task, q SearchQuery) bool {
+	if q.Completed != nil && task.IsCompleted != *q.Completed {
+		return false
+	}
+	if q.CategoryID != nil && (task.CategoryID == nil || *task.CategoryID != *q.CategoryID) {
+		return false
+	}
+	if q.FromDate != "" && task.AssignedDate < q.FromDate {
+		return false
+	}
+	if q.ToDate != "" && task.AssignedDate > q.ToDate {
+		return false
+	}
+	return true
+}
+
+// rankSearchResults scores candidate tasks, drops non-matches and returns the
+// best limit results, most relevant first
+func rankSearchResults(tasks []Task, q SearchQuery) []SearchResult {
+	terms := parseSearchQuery(q.Text)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	var results []SearchResult
+	for _, task := range tasks {
+		if !matchesSearchFilters(task, q) {
+			continue
+		}
+		if result, ok := scoreTask(task, terms); ok {
+			results = append(results, result)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Task.ID > results[j].Task.ID
+	})
+	if q.Limit > 0 && len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+	return results
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// eachSearchStore is eachStore plus a SQLite store searching with LIKE, so
+// the fallback is covered when the tests are built with FTS5
+func eachSearchStore(t *testing.T, test func(t *testing.T, s Store)) {
+	t.Helper()
+	eachStore(t, test)
+	t.Run("sqlite like", func(t *testing.T) {
+		s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		s.fts = false
+		test(t, s)
+	})
+}
+
+// searchTitles runs q and returns the titles found, most relevant first
+func searchTitles(t *testing.T, s Store, q SearchQuery) []string {
+	t.Helper()
+	results, err := s.SearchTasks(q)
+	if err != nil {
+		t.Fatalf("searching %q: %v", q.Text, err)
+	}
+	titles := make([]string, len(results))
+	for i, r := range results {
+		titles[i] = r.Task.Title
+	}
+	return titles
+}
+
+func TestSearchTasks(t *testing.T) {
+	eachSearchStore(t, func(t *testing.T, s Store) {
+		for _, req := range []TaskRequest{
+			{Title: "Fix <kitchen> sink", Description: "Call the plumber about the kitchen drain", Date: testMonday},
+			{Title: "Buy groceries", Description: "Bread and soap for the kitchen", Date: testMonday},
+			{Title: "Kitchen paint", Date: testTuesday},
+			{Title: "Sink the boat", Date: testTuesday},
+		} {
+			if _, err := s.CreateTask(req, "test"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		done := mustCreateTask(t, s, "Kitchen shelves", testTuesday)
+		if _, err := s.UpdateTaskCompletion(done.ID, true, testTuesday, "test"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DeleteTask(mustCreateTask(t, s, "Kitchen in the trash", testMonday).ID, "test"); err != nil {
+			t.Fatal(err)
+		}
+
+		pending := false
+		tests := []struct {
+			name string
+			q    SearchQuery
+			want []string
+		}{
+			{"title and description", SearchQuery{Text: "KITCHEN", Completed: &pending}, []string{"Buy groceries", "Fix <kitchen> sink", "Kitchen paint"}},
+			{"every term", SearchQuery{Text: "kitchen sink"}, []string{"Fix <kitchen> sink"}},
+			{"phrase", SearchQuery{Text: `"sink the"`}, []string{"Sink the boat"}},
+			{"prefix", SearchQuery{Text: "plumb*"}, []string{"Fix <kitchen> sink"}},
+			{"whole words only", SearchQuery{Text: "plumb"}, []string{}},
+			{"dates", SearchQuery{Text: "kitchen", FromDate: testTuesday, ToDate: testTuesday}, []string{"Kitchen paint", "Kitchen shelves"}},
+			// Title matches outrank description matches
+			{"limit", SearchQuery{Text: "kitchen", Completed: &pending, Limit: 2}, []string{"Fix <kitchen> sink", "Kitchen paint"}},
+			// Query syntax is searched for as words, never run
+			{"operators", SearchQuery{Text: `kitchen OR "sink`}, []string{}},
+			{"no words", SearchQuery{Text: `"*" -`}, []string{}},
+		}
+		// FTS5's bm25 and the fallback's scoring can order equal kinds of
+		// match differently, so only which tasks come back is compared
+		for _, tt := range tests {
+			got := searchTitles(t, s, tt.q)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+			}
+		}
+	})
+}
+
+func TestSearchTasksHighlights(t *testing.T) {
+	eachSearchStore(t, func(t *testing.T, s Store) {
+		_, err := s.CreateTask(TaskRequest{
+			Title:       "Fix <kitchen> sink",
+			Description: "One two three four five six seven eight nine ten eleven twelve plumber & kitchen fourteen fifteen sixteen seventeen eighteen",
+			Date:        testMonday,
+		}, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		results, err := s.SearchTasks(SearchQuery{Text: "kitchen"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("got %d results, want 1", len(results))
+		}
+		if got, want := results[0].TitleHighlight, "Fix &lt;<mark>kitchen</mark>&gt; sink"; got != want {
+			t.Errorf("got title %q, want %q", got, want)
+		}
+		// Snippet windows differ between FTS5 and the fallback
+		if got := results[0].Snippet; !strings.Contains(got, "plumber &amp; <mark>kitchen</mark> fourteen") || !strings.Contains(got, "…") {
+			t.Errorf("got snippet %q, want an escaped excerpt around the match", got)
+		}
+		if results[0].Score <= 0 {
+			t.Errorf("got score %v, want it positive", results[0].Score)
+		}
+	})
+}
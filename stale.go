@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// Stale levels a pending task escalates through as it keeps being dragged
+const (
+	LevelStale    = "stale"
+	LevelCritical = "critical"
+)
+
+// EscalationDragging is the outcome of an escalated task that is still pending
+const EscalationDragging = "dragging"
+
+// StaleThresholds are the drag days at which a pending task turns stale and
+// then critical
+type StaleThresholds struct {
+	Stale    int
+	Critical int
+}
+
+// staleThresholds apply to every task whose category does not override them
+var staleThresholds = StaleThresholds{Stale: 3, Critical: 7}
+
+// Validate checks that both thresholds are positive and in order
+func (t StaleThresholds) Validate() error {
+	if t.Stale < 1 || t.Critical < 1 {
+		return fmt.Errorf("thresholds must be at least 1 drag day")
+	}
+	if t.Critical < t.Stale {
+		return fmt.Errorf("the critical threshold cannot be below the stale threshold")
+	}
+	return nil
+}
+
+// categoryThresholds returns the global thresholds with a category's
+// overrides applied. A task is never critical before it is stale.
+func categoryThresholds(staleAfter, criticalAfter *int) StaleThresholds {
+	t := staleThresholds
+	if staleAfter != nil {
+		t.Stale = *staleAfter
+	}
+	if criticalAfter != nil {
+		t.Critical = *criticalAfter
+	}
+	if t.Critical < t.Stale {
+		t.Critical = t.Stale
+	}
+	return t
+}
+
+// level returns the stale level a pending task with dragDays has reached, or
+// "" while it is fresh
+func (t StaleThresholds) level(dragDays int) string {
+	switch {
+	case dragDays >= t.Critical:
+		return LevelCritical
+	case dragDays >= t.Stale:
+		return LevelStale
+	}
+	return ""
+}
+
+// fewestDragDays is the fewest drag days at which a task in any of the
+// categories, or in none, reaches level, so queries can skip the tasks that
+// cannot have reached it
+func fewestDragDays(categories []Category, level string) int {
+	days := func(t StaleThresholds) int {
+		if level == LevelCritical {
+			return t.Critical
+		}
+		return t.Stale
+	}
+
+	fewest := days(staleThresholds)
+	for _, c := range categories {
+		if n := days(categoryThresholds(c.StaleAfterDays, c.CriticalAfterDays)); n < fewest {
+			fewest = n
+		}
+	}
+	return fewest
+}
+
+// levelRank orders stale levels so a task only escalates upwards
+func levelRank(level string) int {
+	switch level {
+	case LevelStale:
+		return 1
+	case LevelCritical:
+		return 2
+	}
+	return 0
+}
+
+// taskStaleLevel is the stale level of a loaded task; completed tasks are
+// never stale
+func taskStaleLevel(task Task) string {
+	if task.IsCompleted {
+		return ""
+	}
+	var staleAfter, criticalAfter *int
+	if task.Category != nil {
+		staleAfter, criticalAfter = task.Category.StaleAfterDays, task.Category.CriticalAfterDays
+	}
+	return categoryThresholds(staleAfter, criticalAfter).level(task.DragDays)
+}
+
+// markStale sets a loaded task's stale and critical flags
+func markStale(task *Task) {
+	level := taskStaleLevel(*task)
+	task.IsStale = level != ""
+	task.IsCritical = level == LevelCritical
+}
+
+// Escalation is a task reaching a stale level, with what has become of the
+// task since
+type Escalation struct {
+	TaskID      int64     `json:"task_id"`
+	Title       string    `json:"title"` // Empty once the task has been purged from the trash
+	Level       string    `json:"level"` // stale or critical
+	EscalatedAt time.Time `json:"escalated_at"`
+	Outcome     string    `json:"outcome"`   // completed, deleted or dragging
+	DragDays    int       `json:"drag_days"` // Now, or when the task was completed or deleted
+}
+
+// EscalationSummary counts the escalations to one level by outcome
+type EscalationSummary struct {
+	Escalated int `json:"escalated"`
+	Completed int `json:"completed"`
+	Deleted   int `json:"deleted"`
+	Dragging  int `json:"dragging"`
+}
+
+// EscalationReport summarizes the escalations recorded between two dates
+type EscalationReport struct {
+	From        string            `json:"from"`
+	To          string            `json:"to"`
+	Stale       EscalationSummary `json:"stale"`
+	Critical    EscalationSummary `json:"critical"`
+	Escalations []Escalation      `json:"escalations"`
+}
+
+// escalationOutcome says what became of an escalated task
+func escalationOutcome(exists, deleted, completed bool) string {
+	switch {
+	case !exists || deleted:
+		return OutcomeDeleted
+	case completed:
+		return OutcomeCompleted
+	}
+	return EscalationDragging
+}
+
+// summarizeEscalations counts escalations by level and outcome
+func summarizeEscalations(from, to string, escalations []Escalation) *EscalationReport {
+	report := &EscalationReport{From: from, To: to, Escalations: escalations}
+	if report.Escalations == nil {
+		report.Escalations = []Escalation{}
+	}
+
+	for _, e := range escalations {
+		summary := &report.Stale
+		if e.Level == LevelCritical {
+			summary = &report.Critical
+		}
+		summary.Escalated++
+		switch e.Outcome {
+		case OutcomeCompleted:
+			summary.Completed++
+		case OutcomeDeleted:
+			summary.Deleted++
+		default:
+			summary.Dragging++
+		}
+	}
+
+	return report
+}
+
+// localDayBounds returns the UTC instants from the start of fromDate up to
+// the start of the day after toDate in the app's timezone
+func localDayBounds(fromDate, toDate string) (time.Time, time.Time, error) {
+	from, err := time.ParseInLocation("2006-01-02", fromDate, location)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	to, err := time.ParseInLocation("2006-01-02", toDate, location)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return from.UTC(), to.AddDate(0, 0, 1).UTC(), nil
+}
+
+// recordEscalations records the escalations of tasks that may just have been
+// dragged further. The change that dragged them has already been made, so a
+// failure is only logged.
+func recordEscalations(s EscalationStore, actor string) {
+	escalations, err := s.RecordEscalations(actor)
+	if err != nil {
+		log.Printf("Failed to record escalations: %v", err)
+		return
+	}
+	if len(escalations) > 0 {
+		log.Printf("Escalated %d stale task(s)", len(escalations))
+	}
+}
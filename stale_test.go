@@ -0,0 +1,152 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStaleThresholdLevels(t *testing.T) {
+	two, nine, one := 2, 9, 1
+	tests := []struct {
+		name          string
+		staleAfter    *int
+		criticalAfter *int
+		levels        map[int]string // drag days -> level
+	}{
+		{"global", nil, nil, map[int]string{0: "", 2: "", 3: LevelStale, 6: LevelStale, 7: LevelCritical, 30: LevelCritical}},
+		{"stale override", &two, nil, map[int]string{1: "", 2: LevelStale, 7: LevelCritical}},
+		{"critical override", nil, &nine, map[int]string{3: LevelStale, 8: LevelStale, 9: LevelCritical}},
+		// A task is never critical before it is stale
+		{"critical below stale", &nine, &two, map[int]string{2: "", 8: "", 9: LevelCritical}},
+		{"critical at once", &one, &one, map[int]string{0: "", 1: LevelCritical}},
+	}
+	for _, tt := range tests {
+		thresholds := categoryThresholds(tt.staleAfter, tt.criticalAfter)
+		for days, want := range tt.levels {
+			if got := thresholds.level(days); got != want {
+				t.Errorf("%s at %d drag days: got %q, want %q", tt.name, days, got, want)
+			}
+		}
+	}
+}
+
+func TestStaleThresholdsValidate(t *testing.T) {
+	for _, thresholds := range []StaleThresholds{{0, 3}, {3, 0}, {5, 4}} {
+		if err := thresholds.Validate(); err == nil {
+			t.Errorf("%+v: got no error", thresholds)
+		}
+	}
+	if err := (StaleThresholds{Stale: 4, Critical: 4}).Validate(); err != nil {
+		t.Errorf("equal thresholds: got %v", err)
+	}
+}
+
+func TestFewestDragDays(t *testing.T) {
+	one, five := 1, 5
+	categories := []Category{{StaleAfterDays: &five}, {CriticalAfterDays: &five}, {StaleAfterDays: &one}}
+	if got := fewestDragDays(categories, LevelStale); got != 1 {
+		t.Errorf("stale: got %d, want 1", got)
+	}
+	if got := fewestDragDays(categories, LevelCritical); got != 5 {
+		t.Errorf("critical: got %d, want 5", got)
+	}
+	if got := fewestDragDays(nil, LevelCritical); got != staleThresholds.Critical {
+		t.Errorf("no categories: got %d, want the global %d", got, staleThresholds.Critical)
+	}
+}
+
+func TestRecordEscalations(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		// Every day is a working day, so drag days count calendar days
+		useCalendar(t, NewWorkCalendar(nil))
+		setClock(t, testNow)
+		rollover := func(from, to string) {
+			t.Helper()
+			if _, err := s.RolloverTasks(from, to, RolloverOptions{}, "test"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		record := func() []Escalation {
+			t.Helper()
+			escalations, err := s.RecordEscalations("test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			return escalations
+		}
+
+		dragged := mustCreateTask(t, s, "Dragged", testMonday)
+		dropped := mustCreateTask(t, s, "Dropped", testMonday)
+		rollover(testMonday, "2026-03-05")
+
+		escalations := record()
+		if len(escalations) != 2 || escalations[0].TaskID != dragged.ID || escalations[0].Level != LevelStale || escalations[0].DragDays != 3 {
+			t.Fatalf("got %+v, want both tasks stale at 3 drag days", escalations)
+		}
+		if again := record(); len(again) != 0 {
+			t.Errorf("recorded %+v again without a change", again)
+		}
+
+		if err := s.DeleteTask(dropped.ID, "test"); err != nil {
+			t.Fatal(err)
+		}
+		rollover("2026-03-05", "2026-03-09")
+		escalations = record()
+		if len(escalations) != 1 || escalations[0].TaskID != dragged.ID || escalations[0].Level != LevelCritical {
+			t.Fatalf("got %+v, want Dragged critical", escalations)
+		}
+
+		task, err := s.GetTaskByID(dragged.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !task.IsStale || !task.IsCritical {
+			t.Errorf("got stale %v, critical %v; want both", task.IsStale, task.IsCritical)
+		}
+		if _, err := s.UpdateTaskCompletion(dragged.ID, true, "2026-03-09", "test"); err != nil {
+			t.Fatal(err)
+		}
+
+		recorded, err := s.GetEscalations(testNow.Add(-time.Hour), testNow.Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		report := summarizeEscalations(testMonday, testMonday, recorded)
+		if report.Stale != (EscalationSummary{Escalated: 2, Completed: 1, Deleted: 1}) {
+			t.Errorf("got stale summary %+v", report.Stale)
+		}
+		if report.Critical != (EscalationSummary{Escalated: 1, Completed: 1}) {
+			t.Errorf("got critical summary %+v", report.Critical)
+		}
+		if none, _ := s.GetEscalations(testNow.Add(time.Hour), testNow.Add(2*time.Hour)); len(none) != 0 {
+			t.Errorf("got %d escalations outside the range", len(none))
+		}
+	})
+}
+
+func TestRecordEscalationsUsesCategoryThresholds(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		useCalendar(t, NewWorkCalendar(nil))
+		one := 1
+		category, err := s.CreateCategory(CategoryRequest{Name: "Urgent", Color: "#ff0000", RolloverPolicy: RolloverNextDay, StaleAfterDays: &one})
+		if err != nil {
+			t.Fatal(err)
+		}
+		urgent, err := s.CreateTask(TaskRequest{Title: "Urgent", Date: testMonday, CategoryID: &category.ID}, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustCreateTask(t, s, "Ordinary", testMonday)
+		if _, err := s.RolloverTasks(testMonday, testTuesday, RolloverOptions{}, "test"); err != nil {
+			t.Fatal(err)
+		}
+
+		escalations, err := s.RecordEscalations("test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(escalations) != 1 || escalations[0].TaskID != urgent.ID || escalations[0].Level != LevelStale {
+			t.Errorf("got %+v, want only the urgent task stale", escalations)
+		}
+	})
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Standup is a "Yesterday / Today / Blocked" report for one day. Each section
+// groups its tasks by category.
+type Standup struct {
+	Date         string         `json:"date"`
+	PreviousDate string         `json:"previous_date"` // Previous business day
+	Yesterday    []StandupGroup `json:"yesterday"`     // Completed since the previous business day
+	Today        []StandupGroup `json:"today"`         // Pending tasks assigned to the date
+	Blocked      []StandupGroup `json:"blocked"`       // Today's tasks dragged past the stale threshold
+}
+
+// StandupGroup is a standup section's tasks in one category
+type StandupGroup struct {
+	CategoryID *int64        `json:"category_id"` // Null for uncategorized tasks
+	Name       string        `json:"name"`
+	Tasks      []StandupTask `json:"tasks"`
+}
+
+// StandupTask is a task as a standup lists it
+type StandupTask struct {
+	ID         int64   `json:"id"`
+	Title      string  `json:"title"`
+	Priority   *string `json:"priority"`
+	DragDays   int     `json:"drag_days"`
+	IsCritical bool    `json:"is_critical"`
+}
+
+// BuildStandup lists the tasks completed from the previous business day up to
+// date, and the pending tasks assigned to date. Stale tasks are left out of
+// today's list and reported as possible blockers instead.
+func BuildStandup(s TaskStore, date string) (*Standup, error) {
+	previous := calendar.PreviousWorkingDay(date)
+
+	done, pending := true, false
+	completed, err := collectTasks(s, TaskQuery{CompletedFrom: previous, CompletedTo: AddDays(date, -1), Completed: &done, Sort: "id"})
+	if err != nil {
+		return nil, err
+	}
+	assigned, err := collectTasks(s, TaskQuery{AssignedFrom: date, AssignedTo: date, Completed: &pending, Sort: "priority"})
+	if err != nil {
+		return nil, err
+	}
+
+	var today, blocked []Task
+	for _, task := range assigned {
+		if task.IsStale {
+			blocked = append(blocked, task)
+		} else {
+			today = append(today, task)
+		}
+	}
+	// The longest dragged blockers first
+	sort.SliceStable(blocked, func(i, j int) bool {
+		return blocked[i].DragDays > blocked[j].DragDays
+	})
+
+	return &Standup{
+		Date:         date,
+		PreviousDate: previous,
+		Yesterday:    groupStandupTasks(completed),
+		Today:        groupStandupTasks(today),
+		Blocked:      groupStandupTasks(blocked),
+	}, nil
+}
+
+// groupStandupTasks groups tasks by category name, uncategorized tasks last,
+// keeping their order within each category
+func groupStandupTasks(tasks []Task) []StandupGroup {
+	groups := []StandupGroup{}
+	index := map[int64]int{}
+	for _, task := range tasks {
+		key := int64(0)
+		if task.Category != nil {
+			key = task.Category.ID
+		}
+		i, ok := index[key]
+		if !ok {
+			group := StandupGroup{Name: "Uncategorized", Tasks: []StandupTask{}}
+			if task.Category != nil {
+				id := task.Category.ID
+				group = StandupGroup{CategoryID: &id, Name: task.Category.Name, Tasks: []StandupTask{}}
+			}
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, group)
+		}
+		groups[i].Tasks = append(groups[i].Tasks, StandupTask{
+			ID:         task.ID,
+			Title:      task.Title,
+			Priority:   task.Priority,
+			DragDays:   task.DragDays,
+			IsCritical: task.IsCritical,
+		})
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if (a.CategoryID == nil) != (b.CategoryID == nil) {
+			return b.CategoryID == nil
+		}
+		return a.Name < b.Name
+	})
+	return groups
+}
+
+// standupSection is a standup section as it is rendered
+type standupSection struct {
+	heading string
+	groups  []StandupGroup
+	blocked bool // Its tasks show how long they have been dragged
+}
+
+func (st *Standup) sections() []standupSection {
+	return []standupSection{
+		{heading: fmt.Sprintf("Yesterday (%s)", st.PreviousDate), groups: st.Yesterday},
+		{heading: fmt.Sprintf("Today (%s)", st.Date), groups: st.Today},
+		{heading: "Blocked", groups: st.Blocked, blocked: true},
+	}
+}
+
+// describe renders a task's line without markup: its priority, title and,
+// for blockers, how long it has been dragged
+func (t StandupTask) describe(title string, blocked bool) string {
+	line := title
+	if t.Priority != nil {
+		line = "[" + *t.Priority + "] " + line
+	}
+	if blocked {
+		line += fmt.Sprintf(" (dragged %d day", t.DragDays)
+		if t.DragDays != 1 {
+			line += "s"
+		}
+		if t.IsCritical {
+			line += ", critical"
+		}
+		line += ")"
+	}
+	return line
+}
+
+// Markdown renders the standup with a heading per section and a bold label
+// per category
+func (st *Standup) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Standup for %s\n", st.Date)
+	for _, section := range st.sections() {
+		fmt.Fprintf(&b, "\n### %s\n", section.heading)
+		if len(section.groups) == 0 {
+			b.WriteString("\n- Nothing\n")
+			continue
+		}
+		for _, group := range section.groups {
+			fmt.Fprintf(&b, "\n**%s**\n\n", escapeMarkdown(group.Name))
+			for _, task := range group.Tasks {
+				fmt.Fprintf(&b, "- %s\n", task.describe(escapeMarkdown(task.Title), section.blocked))
+			}
+		}
+	}
+	return b.String()
+}
+
+// Text renders the standup as indented plain text
+func (st *Standup) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Standup for %s\n", st.Date)
+	for _, section := range st.sections() {
+		fmt.Fprintf(&b, "\n%s:\n", section.heading)
+		if len(section.groups) == 0 {
+			b.WriteString("  Nothing\n")
+			continue
+		}
+		for _, group := range section.groups {
+			fmt.Fprintf(&b, "  %s:\n", group.Name)
+			for _, task := range group.Tasks {
+				fmt.Fprintf(&b, "    - %s\n", task.describe(task.Title, section.blocked))
+			}
+		}
+	}
+	return b.String()
+}
+
+// markdownEscaper backslash-escapes the characters that would turn a title
+// into Markdown formatting
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`, `<`, `\<`, `#`, `\#`,
+)
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
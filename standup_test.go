@@ -0,0 +1,141 @@
+package main
+
+import "testing"
+
+func TestBuildStandup(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		useCalendar(t, testCalendar())
+		monday := "2026-03-09"
+		office, err := s.CreateCategory(CategoryRequest{Name: "Office", Color: "#123456", RolloverPolicy: RolloverNextDay})
+		if err != nil {
+			t.Fatal(err)
+		}
+		create := func(title, date string, categoryID *int64, priority string) *Task {
+			t.Helper()
+			req := TaskRequest{Title: title, Date: date, CategoryID: categoryID}
+			if priority != "" {
+				req.Priority = &priority
+			}
+			task, err := s.CreateTask(req, "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			return task
+		}
+		complete := func(task *Task, date string) {
+			t.Helper()
+			if _, err := s.UpdateTaskCompletion(task.ID, true, date, "test"); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// Monday's standup looks back to Friday, the weekend included
+		complete(create("Too early", "2026-03-05", nil, ""), "2026-03-05")
+		complete(create("Ship *release*", "2026-03-06", &office.ID, ""), "2026-03-06")
+		complete(create("Weekend fix", "2026-03-07", nil, ""), "2026-03-07")
+		create("Review", monday, &office.ID, "")
+		create("Write notes", monday, nil, "P1")
+		old := create("Old bug", testMonday, nil, "")
+		if _, err := s.RolloverTasks(testMonday, monday, RolloverOptions{IncludeTaskIDs: []int64{old.ID}}, "test"); err != nil {
+			t.Fatal(err)
+		}
+
+		standup, err := BuildStandup(s, monday)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wantMarkdown := `## Standup for 2026-03-09
+
+### Yesterday (2026-03-06)
+
+**Office**
+
+- Ship \*release\*
+
+**Uncategorized**
+
+- Weekend fix
+
+### Today (2026-03-09)
+
+**Office**
+
+- Review
+
+**Uncategorized**
+
+- [P1] Write notes
+
+### Blocked
+
+**Uncategorized**
+
+- Old bug (dragged 5 days)
+`
+		if got := standup.Markdown(); got != wantMarkdown {
+			t.Errorf("got Markdown\n%s\nwant\n%s", got, wantMarkdown)
+		}
+
+		wantText := `Standup for 2026-03-09
+
+Yesterday (2026-03-06):
+  Office:
+    - Ship *release*
+  Uncategorized:
+    - Weekend fix
+
+Today (2026-03-09):
+  Office:
+    - Review
+  Uncategorized:
+    - [P1] Write notes
+
+Blocked:
+  Uncategorized:
+    - Old bug (dragged 5 days)
+`
+		if got := standup.Text(); got != wantText {
+			t.Errorf("got text\n%s\nwant\n%s", got, wantText)
+		}
+	})
+}
+
+func TestStandupWithNothing(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		useCalendar(t, testCalendar())
+		standup, err := BuildStandup(s, "2026-04-06")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The Thursday before the Easter weekend is the previous working day
+		want := `Standup for 2026-04-06
+
+Yesterday (2026-04-02):
+  Nothing
+
+Today (2026-04-06):
+  Nothing
+
+Blocked:
+  Nothing
+`
+		if got := standup.Text(); got != want {
+			t.Errorf("got\n%s\nwant\n%s", got, want)
+		}
+	})
+}
+
+func TestStandupTaskDescribe(t *testing.T) {
+	p0 := "P0"
+	task := StandupTask{Title: "Deploy", Priority: &p0, DragDays: 1, IsCritical: true}
+	if got, want := task.describe(task.Title, true), "[P0] Deploy (dragged 1 day, critical)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := task.describe(task.Title, false), "[P0] Deploy"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := escapeMarkdown("#1 [draft] <b>_x_</b>"), `\#1 \[draft\] \<b>\_x\_\</b>`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when a requested record does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrDuplicate is returned when a record would clash with an existing one
+var ErrDuplicate = errors.New("already exists")
+
+// errBulkRolledBack aborts a bulk transaction whose items did not all succeed
+var errBulkRolledBack = errors.New("bulk operation rolled back")
+
+// TaskStore persists tasks and answers the date-based queries the board needs.
+// Every mutation records task events attributed to actor.
+type TaskStore interface {
+	CreateTask(req TaskRequest, actor string) (*Task, error)
+	GetTaskByID(id int64) (*Task, error)
+	GetTasksByDate(date string) ([]Task, error)
+	GetTasksByCategory(categoryID int64) ([]Task, error)
+	GetCompletedTasksForDate(date string) ([]Task, error)
+	GetOverdueTasks(today string) ([]Task, error)
+	GetTasksByIDs(ids []int64) ([]Task, error)
+	GetTasksByIDsIncludingTrash(ids []int64) ([]Task, error) // trashed tasks have DeletedAt set
+	GetTaskIDsTouchingDate(date string) ([]int64, error)
+	GetAllDates() ([]string, error)
+	GetHistorySummaries() ([]HistorySummary, error)
+	UpdateTask(id int64, req TaskRequest, actor string) (*Task, error)
+	UpdateTaskCompletion(id int64, isCompleted bool, completedDate, actor string) (*Task, error)
+	UpdateTaskCategory(id int64, categoryID *int64, actor string) (*Task, error)
+	RolloverTasks(fromDate, toDate string, opts RolloverOptions, actor string) ([]RolloverMove, error)
+	RolloverAllPendingTasks(toDate string, opts RolloverOptions, actor string) ([]RolloverMove, error)
+	DeleteTask(id int64, actor string) error
+	GetTaskHistory(taskID int64) ([]TaskEvent, error)
+	GetTaskHistories(taskIDs []int64) (map[int64][]TaskEvent, error)
+	SearchTasks(q SearchQuery) ([]SearchResult, error)
+	QueryTasks(q TaskQuery) (*TaskPage, error)
+	EachTask(q TaskQuery, fn func(Task) error) error // q's tasks in its sort order, streamed
+	BulkUpdateTasks(ops []BulkOperation, allOrNothing bool, actor string) ([]BulkResult, error)
+}
+
+// TaskItemStore persists the checklist items of tasks. Item changes are
+// recorded as events on the parent task.
+type TaskItemStore interface {
+	CreateTaskItem(taskID int64, title, actor string) (*TaskItem, error)
+	GetTaskItems(taskID int64) ([]TaskItem, error)
+	GetTaskChecklists(taskIDs []int64) (map[int64][]TaskItem, error) // keyed by task ID
+	UpdateTaskItem(taskID, itemID int64, req TaskItemRequest, actor string) (*TaskItem, error)
+	DeleteTaskItem(taskID, itemID int64, actor string) error
+}
+
+// RecurringStore persists recurring task templates and the occurrences
+// already materialized from them
+type RecurringStore interface {
+	CreateRecurringTask(rt RecurringTask) (*RecurringTask, error)
+	GetRecurringTaskByID(id int64) (*RecurringTask, error)
+	GetRecurringTasks() ([]RecurringTask, error)
+	UpdateRecurringTask(rt RecurringTask) (*RecurringTask, error) // everything but GeneratedThrough
+	DeleteRecurringTask(id int64) error
+	MaterializeOccurrence(rt RecurringTask, date string, skip bool) (*Task, error) // nil once handled or when skipped
+	SetRecurringGeneratedThrough(id int64, date string) error
+}
+
+// TagStore persists tags and which tasks carry them. Adding or removing a
+// task's tag is recorded as an event on the task.
+type TagStore interface {
+	CreateTag(name, color string) (*Tag, error)
+	GetTagByID(id int64) (*Tag, error)
+	GetTagByName(name string) (*Tag, error) // case-insensitive
+	GetAllTags() ([]Tag, error)
+	UpdateTag(id int64, name, color string) (*Tag, error)
+	DeleteTag(id int64, actor string) error
+	AddTaskTag(taskID, tagID int64, actor string) (*Task, error)
+	RemoveTaskTag(taskID, tagID int64, actor string) (*Task, error)
+}
+
+// CategoryStore persists task categories
+type CategoryStore interface {
+	CreateCategory(req CategoryRequest) (*Category, error)
+	GetCategoryByID(id int64) (*Category, error)
+	GetAllCategories() ([]Category, error)
+	UpdateCategory(id int64, req CategoryRequest) (*Category, error)
+	DeleteCategory(id int64, actor string) error
+}
+
+// TrashStore keeps deleted tasks and categories until the trash is emptied
+type TrashStore interface {
+	GetTrash() (*Trash, error)
+	RestoreTask(id int64, actor string) (*Task, error)
+	RestoreCategory(id int64, actor string) (*Category, error)
+	EmptyTrash() (int, error) // returns how many tasks and categories were purged
+}
+
+// JournalStore records destructive task and category operations so they can
+// be undone and redone. Undo and Redo return ErrNotFound when there is
+// nothing left to replay.
+type JournalStore interface {
+	GetOperations(limit int) ([]Operation, error) // newest first
+	Undo(actor string) (*Operation, error)
+	Redo(actor string) (*Operation, error)
+}
+
+// EscalationStore records pending tasks reaching a stale level as escalated
+// events. A task escalates to each level at most once.
+type EscalationStore interface {
+	RecordEscalations(actor string) ([]Escalation, error)    // the escalations recorded by this call
+	GetEscalations(from, to time.Time) ([]Escalation, error) // recorded in [from, to), oldest first
+}
+
+// ImportStore imports an exported document or a todo.txt file in one
+// transaction, journaled as one operation
+type ImportStore interface {
+	Import(doc ImportDocument, opts ImportOptions, actor string) (*ImportReport, error) // applies nothing on a dry run or any conflict
+	ImportTodoTxt(lines []TodoTxtTask, today, actor string) (*TodoTxtResult, error)     // applies nothing when a line fails
+}
+
+// HolidayStore persists the holidays of the working calendar
+type HolidayStore interface {
+	CreateHoliday(date, name string) (*Holiday, error) // ErrDuplicate when the date already has one
+	GetHolidayByID(id int64) (*Holiday, error)
+	GetHolidays(fromDate, toDate string) ([]Holiday, error) // empty bounds are open
+	UpdateHoliday(id int64, date, name string) (*Holiday, error)
+	DeleteHoliday(id int64) error
+}
+
+// RolloverRunStore records the history of scheduled rollovers
+type RolloverRunStore interface {
+	RecordRolloverRun(run *RolloverRun) error
+	GetLastRolloverRun() (*RolloverRun, error) // most recent successful run
+	GetRolloverRuns(limit int) ([]RolloverRun, error)
+}
+
+// Store is everything the HTTP handlers need from a storage backend
+type Store interface {
+	TaskStore
+	TaskItemStore
+	RecurringStore
+	TagStore
+	CategoryStore
+	TrashStore
+	JournalStore
+	EscalationStore
+	ImportStore
+	HolidayStore
+	RolloverRunStore
+	Close() error
+}
+
+// store is the backend used by the HTTP handlers, set up in main
+var store Store
+
+// GetDailyLog retrieves the daily log for a specific date
+func GetDailyLog(s TaskStore, date string) (*DailyLog, error) {
+	tasks, err := s.GetTasksByDate(date)
+	if err != nil {
+		return nil, err
+	}
+
+	log := &DailyLog{
+		Date:  date,
+		Tasks: tasks,
+	}
+
+	for _, task := range tasks {
+		if task.IsCompleted {
+			log.CompletedCount++
+		} else {
+			log.PendingCount++
+		}
+		if isOverdue(task, date) {
+			log.OverdueCount++
+		}
+	}
+
+	return log, nil
+}
+
+// FilterTasksByTags keeps the tasks carrying all (matchAll) or any of the
+// named tags, compared without case. No names keeps every task.
+func FilterTasksByTags(tasks []Task, names []string, matchAll bool) []Task {
+	if len(names) == 0 {
+		return tasks
+	}
+
+	var filtered []Task
+	for _, task := range tasks {
+		matched := 0
+		for _, name := range names {
+			for _, tag := range task.Tags {
+				if strings.EqualFold(tag.Name, name) {
+					matched++
+					break
+				}
+			}
+		}
+		if (matchAll && matched == len(names)) || (!matchAll && matched > 0) {
+			filtered = append(filtered, task)
+		}
+	}
+	return filtered
+}
+
+// CompleteIfChecklistDone completes a pending task on completedDate once all
+// of its checklist items are done. Tasks without items are left alone.
+func CompleteIfChecklistDone(s TaskStore, taskID int64, completedDate, actor string) (*Task, error) {
+	task, err := s.GetTaskByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task.IsCompleted || task.Progress.Total == 0 || task.Progress.Done < task.Progress.Total {
+		return task, nil
+	}
+	return s.UpdateTaskCompletion(taskID, true, completedDate, actor)
+}
+
+// clampPosition limits a requested list position to 0..count-1
+func clampPosition(position, count int) int {
+	if position >= count {
+		position = count - 1
+	}
+	if position < 0 {
+		position = 0
+	}
+	return position
+}
+
+// selects reports whether the options leave a task in the rollover. Tasks
+// in a category that is in the trash count as uncategorized.
+func (o RolloverOptions) selects(taskID int64, categoryID *int64) bool {
+	if len(o.IncludeTaskIDs) > 0 && !containsID(o.IncludeTaskIDs, taskID) {
+		return false
+	}
+	if containsID(o.ExcludeTaskIDs, taskID) {
+		return false
+	}
+	if len(o.IncludeCategoryIDs) > 0 && (categoryID == nil || !containsID(o.IncludeCategoryIDs, *categoryID)) {
+		return false
+	}
+	return categoryID == nil || !containsID(o.ExcludeCategoryIDs, *categoryID)
+}
+
+// planRolloverMove applies the rollover policy of a task's category to a
+// move onto m.ToDate, picking the date the task lands on or archiving it. It
+// reports false when the policy leaves the task where it is.
+func planRolloverMove(m *RolloverMove, createdDate, policy string, archiveAfterDays *int) bool {
+	m.Action = RolloverRolledOver
+	m.DragDays = CalculateBusinessDays(createdDate, m.ToDate)
+
+	day, err := time.Parse("2006-01-02", m.ToDate)
+	if err != nil {
+		return true
+	}
+
+	switch policy {
+	case RolloverNever:
+		return false
+	case RolloverNextBusinessDay:
+		if !calendar.IsWorkingDay(day) {
+			m.ToDate = calendar.NextWorkingDay(m.ToDate)
+		}
+	case RolloverNextWeek:
+		if day.Weekday() != time.Monday {
+			m.ToDate = weekStart(day).AddDate(0, 0, 7).Format("2006-01-02")
+		}
+	case RolloverArchive:
+		if archiveAfterDays != nil && m.DragDays >= *archiveAfterDays {
+			m.Action = RolloverArchived
+			m.ToDate = ""
+			return true
+		}
+	}
+
+	m.DragDays = CalculateBusinessDays(createdDate, m.ToDate)
+	return true
+}
+
+// countRolloverMoves splits a rollover's moves into tasks rolled over and
+// tasks archived
+func countRolloverMoves(moves []RolloverMove) (rolled, archived int) {
+	for _, m := range moves {
+		if m.Action == RolloverArchived {
+			archived++
+		} else {
+			rolled++
+		}
+	}
+	return rolled, archived
+}
+
+// containsID reports whether ids holds id
+func containsID(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// sameInt64Ptr reports whether two optional IDs are equal
+func sameInt64Ptr(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// sameStringPtr reports whether two optional values are equal
+func sameStringPtr(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// emptyToNil treats an empty optional value as unset
+func emptyToNil(v *string) *string {
+	if v == nil || *v == "" {
+		return nil
+	}
+	return v
+}
+
+// isOverdue reports whether a task is still pending past its due date
+func isOverdue(task Task, today string) bool {
+	return !task.IsCompleted && task.DueDate != nil && *task.DueDate < today
+}
+
+// formatIDPtr renders an optional ID as an optional event value
+func formatIDPtr(id *int64) *string {
+	if id == nil {
+		return nil
+	}
+	v := strconv.FormatInt(*id, 10)
+	return &v
+}
+
+// seedDefaultCategories inserts the default categories into an empty store
+func seedDefaultCategories(s CategoryStore) error {
+	categories, err := s.GetAllCategories()
+	if err != nil {
+		return err
+	}
+	if len(categories) > 0 {
+		return nil
+	}
+
+	defaults := []struct{ name, color string }{
+		{"Work", "#58a6ff"},
+		{"Personal", "#3fb950"},
+		{"Misc", "#f0883e"},
+	}
+	// Categories in the trash still hold their names
+	for _, c := range defaults {
+		_, err := s.CreateCategory(CategoryRequest{Name: c.name, Color: c.color, RolloverPolicy: RolloverNextDay})
+		if err != nil && err != ErrDuplicate {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// taskRequest merges the fields with a task's current title and description
+func (f BulkFields) taskRequest(title, description string) TaskRequest {
+	req := TaskRequest{Title: title, Description: description, Priority: f.Priority, DueDate: f.DueDate}
+	if f.Title != nil {
+		req.Title = *f.Title
+	}
+	if f.Description != nil {
+		req.Description = *f.Description
+	}
+	return req
+}
+
+// bulkErrorMessage describes why a bulk item failed
+func bulkErrorMessage(err error) string {
+	if err == ErrNotFound {
+		return "Task not found"
+	}
+	return err.Error()
+}
+
+// rollBackBulkResults fails the items of a rolled-back all-or-nothing request
+// that had succeeded, as none of their changes were kept
+func rollBackBulkResults(results []BulkResult) []BulkResult {
+	for i := range results {
+		if results[i].OK {
+			results[i].OK = false
+			results[i].Error = "Rolled back"
+		}
+	}
+	return results
+}
+
+// attachBulkTasks fills in the final state of every task a committed bulk
+// request touched; deleted tasks are left out
+func attachBulkTasks(results []BulkResult, tasks []Task) []BulkResult {
+	byID := make(map[int64]Task, len(tasks))
+	for _, t := range tasks {
+		byID[t.ID] = t
+	}
+	for i := range results {
+		if task, ok := byID[results[i].TaskID]; ok {
+			results[i].Task = &task
+		}
+	}
+	return results
+}
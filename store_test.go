@@ -0,0 +1,389 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// The store tests use a fixed week: 2026-03-02 is a Monday
+const (
+	testMonday  = "2026-03-02"
+	testTuesday = "2026-03-03"
+)
+
+// eachStore runs test against a fresh memory store and a fresh SQLite
+// database, so both backends are held to the same behavior
+func eachStore(t *testing.T, test func(t *testing.T, s Store)) {
+	t.Helper()
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryStore())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		test(t, s)
+	})
+}
+
+// mustCreateTask creates a task with just a title on date
+func mustCreateTask(t *testing.T, s Store, title, date string) *Task {
+	t.Helper()
+	task, err := s.CreateTask(TaskRequest{Title: title, Date: date}, "test")
+	if err != nil {
+		t.Fatalf("creating %q: %v", title, err)
+	}
+	return task
+}
+
+func TestStoreCreateAndGetTask(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		categories, err := s.GetAllCategories()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(categories) != 3 {
+			t.Fatalf("got %d seeded categories, want 3", len(categories))
+		}
+
+		priority, due := "P1", "2026-03-06"
+		created, err := s.CreateTask(TaskRequest{
+			Title:       "Write report",
+			Description: "Quarterly numbers",
+			Date:        testMonday,
+			Priority:    &priority,
+			DueDate:     &due,
+		}, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.UpdateTaskCategory(created.ID, &categories[0].ID, "test"); err != nil {
+			t.Fatal(err)
+		}
+
+		task, err := s.GetTaskByID(created.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if task.Title != "Write report" || task.Description != "Quarterly numbers" {
+			t.Errorf("got %q / %q", task.Title, task.Description)
+		}
+		if task.CreatedDate != testMonday || task.AssignedDate != testMonday || task.IsCompleted {
+			t.Errorf("got created %s, assigned %s, completed %v", task.CreatedDate, task.AssignedDate, task.IsCompleted)
+		}
+		if task.Priority == nil || *task.Priority != "P1" || task.DueDate == nil || *task.DueDate != due {
+			t.Errorf("got priority %v, due date %v", task.Priority, task.DueDate)
+		}
+		if task.Category == nil || task.Category.Name != categories[0].Name {
+			t.Errorf("got category %+v, want %s", task.Category, categories[0].Name)
+		}
+
+		tasks, err := s.GetTasksByDate(testMonday)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tasks) != 1 || tasks[0].ID != created.ID {
+			t.Errorf("got %d tasks on %s, want the created one", len(tasks), testMonday)
+		}
+
+		if _, err := s.GetTaskByID(created.ID + 100); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v for a missing task, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestStoreCompletion(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		task := mustCreateTask(t, s, "Call the bank", testMonday)
+
+		done, err := s.UpdateTaskCompletion(task.ID, true, testTuesday, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !done.IsCompleted || done.CompletedDate == nil || *done.CompletedDate != testTuesday {
+			t.Fatalf("got completed %v on %v", done.IsCompleted, done.CompletedDate)
+		}
+
+		completed, err := s.GetCompletedTasksForDate(testTuesday)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(completed) != 1 || completed[0].ID != task.ID {
+			t.Errorf("got %d tasks completed on %s, want 1", len(completed), testTuesday)
+		}
+
+		undone, err := s.UpdateTaskCompletion(task.ID, false, testTuesday, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if undone.IsCompleted || undone.CompletedDate != nil {
+			t.Errorf("got completed %v on %v after uncompleting", undone.IsCompleted, undone.CompletedDate)
+		}
+	})
+}
+
+func TestStoreRollover(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		pending := mustCreateTask(t, s, "Pending", testMonday)
+		done := mustCreateTask(t, s, "Done", testMonday)
+		if _, err := s.UpdateTaskCompletion(done.ID, true, testMonday, "test"); err != nil {
+			t.Fatal(err)
+		}
+
+		moves, err := s.RolloverTasks(testMonday, testTuesday, RolloverOptions{DryRun: true}, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(moves) != 1 || moves[0].TaskID != pending.ID {
+			t.Fatalf("dry run planned %+v, want only the pending task", moves)
+		}
+		if task, _ := s.GetTaskByID(pending.ID); task.AssignedDate != testMonday {
+			t.Fatalf("dry run moved the task to %s", task.AssignedDate)
+		}
+
+		if _, err := s.RolloverTasks(testMonday, testTuesday, RolloverOptions{}, "test"); err != nil {
+			t.Fatal(err)
+		}
+		task, err := s.GetTaskByID(pending.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if task.AssignedDate != testTuesday || task.DragDays != 1 {
+			t.Errorf("got assigned %s with %d drag days, want %s with 1", task.AssignedDate, task.DragDays, testTuesday)
+		}
+		if task, _ := s.GetTaskByID(done.ID); task.AssignedDate != testMonday {
+			t.Errorf("completed task moved to %s", task.AssignedDate)
+		}
+	})
+}
+
+func TestStoreBulkAllOrNothing(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		task := mustCreateTask(t, s, "Bulk", testMonday)
+		missing := task.ID + 100
+		ops := []BulkOperation{{Op: BulkComplete, TaskIDs: []int64{task.ID, missing}, Date: testMonday}}
+
+		results, err := s.BulkUpdateTasks(ops, true, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 2 {
+			t.Fatalf("got %d results, want 2", len(results))
+		}
+		for _, r := range results {
+			if r.OK || r.Error == "" {
+				t.Errorf("task %d: got ok %v, error %q after a rollback", r.TaskID, r.OK, r.Error)
+			}
+		}
+		if got, _ := s.GetTaskByID(task.ID); got.IsCompleted {
+			t.Error("rolled-back request completed the task")
+		}
+
+		results, err = s.BulkUpdateTasks(ops, false, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !results[0].OK || results[1].OK {
+			t.Errorf("got ok %v and %v, want only the existing task to succeed", results[0].OK, results[1].OK)
+		}
+		if got, _ := s.GetTaskByID(task.ID); !got.IsCompleted {
+			t.Error("partial request left the task pending")
+		}
+	})
+}
+
+func TestStoreUndoRedo(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		if _, err := s.Undo("test"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("got %v undoing an empty journal, want ErrNotFound", err)
+		}
+
+		task := mustCreateTask(t, s, "Undo me", testMonday)
+		if err := s.DeleteTask(task.ID, "test"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.GetTaskByID(task.ID); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("got %v for a deleted task, want ErrNotFound", err)
+		}
+
+		op, err := s.Undo("test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if op.Kind != OpDeleteTask {
+			t.Errorf("undid %s, want %s", op.Kind, OpDeleteTask)
+		}
+		if _, err := s.GetTaskByID(task.ID); err != nil {
+			t.Fatalf("task not back after undo: %v", err)
+		}
+
+		if _, err := s.Redo("test"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.GetTaskByID(task.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v after redoing the delete, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestStoreChecklist(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		task := mustCreateTask(t, s, "Pack", testMonday)
+		first, err := s.CreateTaskItem(task.ID, "Passport", "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.CreateTaskItem(task.ID, "Charger", "test"); err != nil {
+			t.Fatal(err)
+		}
+
+		isDone := true
+		if _, err := s.UpdateTaskItem(task.ID, first.ID, TaskItemRequest{IsDone: &isDone}, "test"); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := s.GetTaskByID(task.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Progress != (Progress{Done: 1, Total: 2}) {
+			t.Errorf("got progress %+v, want 1 of 2", got.Progress)
+		}
+
+		checklists, err := s.GetTaskChecklists([]int64{task.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		items := checklists[task.ID]
+		if len(items) != 2 || items[0].Title != "Passport" || !items[0].IsDone || items[1].Title != "Charger" {
+			t.Errorf("got checklist %+v", items)
+		}
+	})
+}
+
+func TestStoreImport(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		categoryID := int64(7)
+		doc := ImportDocument{
+			Categories: []ExportCategory{{ID: categoryID, Name: "Garden", Color: "#00ff00"}},
+			Tasks: []ExportTask{{
+				ID:           1,
+				Title:        "Plant tulips",
+				CreatedDate:  testMonday,
+				AssignedDate: testTuesday,
+				CategoryID:   &categoryID,
+				Tags:         []string{"outdoors"},
+				Items:        []ExportItem{{Title: "Buy bulbs", IsDone: true}, {Title: "Dig"}},
+			}},
+		}
+
+		report, err := s.Import(doc, ImportOptions{Categories: ImportMerge, DryRun: true}, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Committed || report.Summary.TasksCreated != 1 {
+			t.Fatalf("dry run got committed %v, summary %+v", report.Committed, report.Summary)
+		}
+		if tasks, _ := s.GetTasksByDate(testTuesday); len(tasks) != 0 {
+			t.Fatalf("dry run created %d tasks", len(tasks))
+		}
+
+		report, err = s.Import(doc, ImportOptions{Categories: ImportMerge}, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !report.Committed || report.Summary.CategoriesCreated != 1 || report.Summary.TasksCreated != 1 {
+			t.Fatalf("got committed %v, summary %+v", report.Committed, report.Summary)
+		}
+
+		task, err := s.GetTaskByID(*report.Tasks[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if task.AssignedDate != testTuesday || task.DragDays != 1 {
+			t.Errorf("got assigned %s with %d drag days", task.AssignedDate, task.DragDays)
+		}
+		if task.Category == nil || task.Category.Name != "Garden" {
+			t.Errorf("got category %+v, want Garden", task.Category)
+		}
+		if len(task.Tags) != 1 || task.Tags[0].Name != "outdoors" {
+			t.Errorf("got tags %+v, want outdoors", task.Tags)
+		}
+		if task.Progress != (Progress{Done: 1, Total: 2}) {
+			t.Errorf("got progress %+v, want 1 of 2", task.Progress)
+		}
+
+		report, err = s.Import(doc, ImportOptions{Categories: ImportMerge}, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Summary.CategoriesMerged != 1 || report.Summary.TasksSkipped != 1 || report.Summary.TasksCreated != 0 {
+			t.Errorf("reimport got summary %+v, want the category merged and the task skipped", report.Summary)
+		}
+	})
+}
+
+func TestStoreHistoricalLogKeepsDeletedTasks(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		purged := mustCreateTask(t, s, "Purged", testMonday)
+		if err := s.DeleteTask(purged.ID, "test"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.EmptyTrash(); err != nil {
+			t.Fatal(err)
+		}
+		trashed := mustCreateTask(t, s, "Trashed", testMonday)
+		if err := s.DeleteTask(trashed.ID, "test"); err != nil {
+			t.Fatal(err)
+		}
+
+		log, err := GetHistoricalLog(s, testMonday, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(log.Tasks) != 2 || log.DeletedCount != 2 {
+			t.Fatalf("got %d tasks, %d deleted; want 2 deleted", len(log.Tasks), log.DeletedCount)
+		}
+		for i, want := range []string{"Purged", "Trashed"} {
+			if got := log.Tasks[i]; got.Title != want || got.Outcome != OutcomeDeleted {
+				t.Errorf("got %q %s, want %q deleted", got.Title, got.Outcome, want)
+			}
+		}
+	})
+}
+
+func TestStoreDeleteMissing(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		task := mustCreateTask(t, s, "Once", testMonday)
+		category, err := s.CreateCategory(CategoryRequest{Name: "Once", Color: "#123456", RolloverPolicy: RolloverNextDay})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DeleteTask(task.ID, "test"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DeleteCategory(category.ID, "test"); err != nil {
+			t.Fatal(err)
+		}
+		ops, _ := s.GetOperations(10)
+
+		// Trashed and unknown records are equally missing
+		for _, id := range []int64{task.ID, task.ID + 100} {
+			if err := s.DeleteTask(id, "test"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("task %d: got %v, want ErrNotFound", id, err)
+			}
+		}
+		for _, id := range []int64{category.ID, category.ID + 100} {
+			if err := s.DeleteCategory(id, "test"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("category %d: got %v, want ErrNotFound", id, err)
+			}
+		}
+		if after, _ := s.GetOperations(10); len(after) != len(ops) {
+			t.Error("a failed delete was journaled")
+		}
+	})
+}
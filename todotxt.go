@@ -0,0 +1,559 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// todo.txt priorities (A) to (D) stand for P0 to P3
+const todoTxtPriorities = "ABCD"
+
+// todoTxtPriorityPattern matches a priority such as (A)
+var todoTxtPriorityPattern = regexp.MustCompile(`^\([A-Z]\)$`)
+
+// TodoTxtTask is one line of a todo.txt file:
+//
+//	x 2026-03-12 2026-03-10 Title +Category @tag due:2026-03-20 assigned:2026-03-11 pri:A id:7
+//
+// Lines this app writes carry an id: extension so they update their task when
+// read back in.
+type TodoTxtTask struct {
+	Line          int
+	ID            *int64 // id: extension
+	Title         string
+	Completed     bool
+	CompletedDate *string // Empty on completed lines without a date
+	CreatedDate   string  // Empty when the line has none
+	AssignedDate  string  // assigned: extension; empty for the created date
+	Priority      *string // P0 to P3
+	DueDate       *string // due: extension
+	Project       string  // The last +project, naming the category
+	Contexts      []string
+}
+
+// TodoTxtError is a todo.txt line that cannot be read or imported
+type TodoTxtError struct {
+	Line    int
+	Message string
+}
+
+func (e *TodoTxtError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// TodoTxtResult counts what a todo.txt import did
+type TodoTxtResult struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Skipped   int `json:"skipped"` // New lines matching a task already stored
+}
+
+// todoTxtName turns a category or tag name into a +project or @context,
+// which cannot hold spaces
+func todoTxtName(name string) string {
+	return strings.Join(strings.Fields(name), "_")
+}
+
+// ParseTodoTxt reads a todo.txt file, skipping blank lines. Words other than
+// the markers, +projects, @contexts and the due:, assigned:, pri: and id:
+// extensions make up the title.
+func ParseTodoTxt(r io.Reader) ([]TodoTxtTask, error) {
+	var tasks []TodoTxtTask
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		words := strings.Fields(scanner.Text())
+		if len(words) == 0 {
+			continue
+		}
+		task, msg := parseTodoTxtLine(words)
+		if msg != "" {
+			return nil, &TodoTxtError{Line: line, Message: msg}
+		}
+		task.Line = line
+		tasks = append(tasks, task)
+	}
+
+	return tasks, scanner.Err()
+}
+
+// parseTodoTxtLine reads the words of one line. It returns an error message
+// or "".
+func parseTodoTxtLine(words []string) (TodoTxtTask, string) {
+	var task TodoTxtTask
+	next := func() string {
+		if len(words) == 0 {
+			return ""
+		}
+		return words[0]
+	}
+
+	if next() == "x" {
+		task.Completed = true
+		words = words[1:]
+		if isValidDate(next()) {
+			date := words[0]
+			task.CompletedDate = &date
+			words = words[1:]
+		}
+	} else if todoTxtPriorityPattern.MatchString(next()) {
+		priority, msg := todoTxtPriority(next()[1:2])
+		if msg != "" {
+			return task, msg
+		}
+		task.Priority = priority
+		words = words[1:]
+	}
+	if isValidDate(next()) {
+		task.CreatedDate = words[0]
+		words = words[1:]
+	}
+
+	var title []string
+	for _, word := range words {
+		key, value, _ := strings.Cut(word, ":")
+		switch {
+		case len(word) > 1 && word[0] == '+':
+			if task.Project != "" {
+				title = append(title, "+"+task.Project)
+			}
+			task.Project = word[1:]
+		case len(word) > 1 && word[0] == '@':
+			if strings.Contains(word, ",") {
+				return task, "contexts cannot contain commas"
+			}
+			task.Contexts = append(task.Contexts, word[1:])
+		case key == "due" || key == "assigned":
+			if !isValidDate(value) {
+				return task, key + ": must be YYYY-MM-DD"
+			}
+			if key == "due" {
+				task.DueDate = &value
+			} else {
+				task.AssignedDate = value
+			}
+		case key == "pri" && len(value) == 1:
+			priority, msg := todoTxtPriority(value)
+			if msg != "" {
+				return task, msg
+			}
+			task.Priority = priority
+		case key == "id":
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || id < 1 {
+				return task, "id: must be a positive number"
+			}
+			task.ID = &id
+		default:
+			title = append(title, word)
+		}
+	}
+
+	task.Title = strings.Join(title, " ")
+	if task.Title == "" {
+		return task, "the task has no title"
+	}
+	if task.CreatedDate != "" && task.AssignedDate != "" && task.AssignedDate < task.CreatedDate {
+		return task, "assigned: cannot be before the creation date"
+	}
+	return task, ""
+}
+
+// todoTxtPriority maps a priority letter to P0 to P3
+func todoTxtPriority(letter string) (*string, string) {
+	i := strings.Index(todoTxtPriorities, letter)
+	if i < 0 {
+		return nil, fmt.Sprintf("priority (%s) is not supported; use (A) to (D)", letter)
+	}
+	p := fmt.Sprintf("P%d", i)
+	return &p, ""
+}
+
+// FormatTodoTxt writes a task as a todo.txt line. Descriptions and checklists
+// have no place in the format and are left out.
+func FormatTodoTxt(task Task) string {
+	var words []string
+	letter := ""
+	if task.Priority != nil && len(*task.Priority) == 2 {
+		if i, err := strconv.Atoi((*task.Priority)[1:]); err == nil && i < len(todoTxtPriorities) {
+			letter = todoTxtPriorities[i : i+1]
+		}
+	}
+
+	if task.IsCompleted {
+		words = append(words, "x")
+		if task.CompletedDate != nil {
+			words = append(words, *task.CompletedDate)
+		}
+	} else if letter != "" {
+		words = append(words, "("+letter+")")
+	}
+	words = append(words, task.CreatedDate, task.Title)
+
+	if task.Category != nil {
+		words = append(words, "+"+todoTxtName(task.Category.Name))
+	}
+	for _, tag := range task.Tags {
+		words = append(words, "@"+todoTxtName(tag.Name))
+	}
+	if task.DueDate != nil {
+		words = append(words, "due:"+*task.DueDate)
+	}
+	if task.AssignedDate != task.CreatedDate {
+		words = append(words, "assigned:"+task.AssignedDate)
+	}
+	// Completed lines lose their leading priority, so it moves to pri:
+	if task.IsCompleted && letter != "" {
+		words = append(words, "pri:"+letter)
+	}
+	words = append(words, "id:"+strconv.FormatInt(task.ID, 10))
+
+	return strings.Join(words, " ")
+}
+
+// WriteTodoTxt writes the tasks as a todo.txt file in ID order, only the
+// pending or completed ones when completed is set. Tasks are streamed from
+// the store a line at a time.
+func WriteTodoTxt(s TaskStore, w io.Writer, completed *bool) error {
+	buf := bufio.NewWriter(w)
+	err := s.EachTask(TaskQuery{Completed: completed, Sort: "id"}, func(task Task) error {
+		_, err := fmt.Fprintln(buf, FormatTodoTxt(task))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// todoTxtTx applies a todo.txt import inside one store transaction. None of
+// its changes are journaled; the store journals the whole import once.
+type todoTxtTx interface {
+	createCategory(req CategoryRequest) (*Category, error) // ErrDuplicate when the name is taken
+	applyBulkOperation(op BulkOperation, taskID int64) error
+	addTaskTag(taskID int64, name string) error // Creates the tag when there is none by that name
+	removeTaskTag(taskID int64, tag Tag) error
+	importDocument(doc ImportDocument) (*ImportReport, error)
+}
+
+// todoTxtUpdate is a line naming a stored task
+type todoTxtUpdate struct {
+	line TodoTxtTask
+	task *Task
+}
+
+// todoTxtPlan is a todo.txt import checked against the stored categories,
+// tags and tasks
+type todoTxtPlan struct {
+	updates           []todoTxtUpdate
+	added             []TodoTxtTask // Lines for new tasks
+	categoryByProject map[string]*Category
+	tagByContext      map[string]string // Context, lower-cased, to tag name
+	today             string
+}
+
+// planTodoTxt splits lines into updates of stored tasks and new tasks,
+// checking every line before anything is applied. stored holds the live
+// tasks the lines' ids name.
+func planTodoTxt(lines []TodoTxtTask, categories []Category, tags []Tag, stored map[int64]*Task, today string) (*todoTxtPlan, error) {
+	plan := &todoTxtPlan{
+		categoryByProject: map[string]*Category{},
+		tagByContext:      map[string]string{},
+		today:             today,
+	}
+	// Projects name categories without their spaces and case
+	for i := range categories {
+		key := strings.ToLower(todoTxtName(categories[i].Name))
+		if _, ok := plan.categoryByProject[key]; !ok {
+			plan.categoryByProject[key] = &categories[i]
+		}
+	}
+	for _, tag := range tags {
+		plan.tagByContext[strings.ToLower(todoTxtName(tag.Name))] = tag.Name
+	}
+
+	for _, line := range lines {
+		for _, context := range line.Contexts {
+			if strings.Contains(context, ",") {
+				return nil, &TodoTxtError{Line: line.Line, Message: "contexts cannot contain commas"}
+			}
+		}
+		if line.ID != nil {
+			if task, ok := stored[*line.ID]; ok {
+				if line.AssignedDate != "" && line.AssignedDate < task.CreatedDate {
+					return nil, &TodoTxtError{Line: line.Line, Message: "assigned: cannot be before the creation date"}
+				}
+				plan.updates = append(plan.updates, todoTxtUpdate{line, task})
+				continue
+			}
+		}
+		plan.added = append(plan.added, line)
+	}
+	return plan, nil
+}
+
+// taskIDs lists the stored tasks the plan updates
+func (p *todoTxtPlan) taskIDs() []int64 {
+	ids := make([]int64, len(p.updates))
+	for i, u := range p.updates {
+		ids[i] = u.task.ID
+	}
+	return ids
+}
+
+// applyTodoTxt brings the store in line with a planned todo.txt import
+// through tx. Lines whose id: names a stored task update its title,
+// priority, due date, category, assigned date, completion and tags; the
+// other lines are imported as new tasks, skipping any already stored.
+// Projects and contexts without a category or tag get one. Tasks missing
+// from the file are left alone, and dates missing from new lines default to
+// today. A line that cannot be applied fails with a *TodoTxtError, and the
+// store then rolls back everything.
+func applyTodoTxt(tx todoTxtTx, p *todoTxtPlan) (*TodoTxtResult, error) {
+	result := &TodoTxtResult{}
+
+	for _, u := range p.updates {
+		var categoryID *int64
+		if u.line.Project != "" {
+			c, err := p.category(tx, u.line)
+			if err != nil {
+				return nil, err
+			}
+			categoryID = &c.ID
+		}
+
+		ops := todoTxtUpdateOps(u.task, u.line, categoryID, p.today)
+		removed, added := p.tagChanges(u.task.Tags, u.line.Contexts)
+		if len(ops) == 0 && len(removed) == 0 && len(added) == 0 {
+			result.Unchanged++
+			continue
+		}
+		result.Updated++
+
+		for _, op := range ops {
+			if err := tx.applyBulkOperation(op, u.task.ID); err != nil {
+				return nil, &TodoTxtError{Line: u.line.Line, Message: bulkErrorMessage(err)}
+			}
+		}
+		for _, tag := range removed {
+			if err := tx.removeTaskTag(u.task.ID, tag); err != nil {
+				return nil, err
+			}
+		}
+		for _, name := range added {
+			if err := tx.addTaskTag(u.task.ID, name); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(p.added) == 0 {
+		return result, nil
+	}
+	doc := p.document()
+	report, err := tx.importDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+	if err := todoTxtConflict(report, doc, p.added); err != nil {
+		return nil, err
+	}
+	for _, t := range report.Tasks {
+		if t.Action == ImportSkipped {
+			result.Skipped++
+		} else {
+			result.Created++
+		}
+	}
+
+	return result, nil
+}
+
+// category returns the category a line's project names, creating it through
+// tx when there is none
+func (p *todoTxtPlan) category(tx todoTxtTx, line TodoTxtTask) (*Category, error) {
+	key := strings.ToLower(line.Project)
+	if c, ok := p.categoryByProject[key]; ok {
+		return c, nil
+	}
+
+	req := CategoryRequest{Name: line.Project, Color: "#58a6ff"}
+	normalizeCategoryRequest(&req)
+	c, err := tx.createCategory(req)
+	if err == ErrDuplicate {
+		return nil, &TodoTxtError{Line: line.Line, Message: fmt.Sprintf("a category named %q is in the trash", line.Project)}
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.categoryByProject[key] = c
+	return c, nil
+}
+
+// tagName is the name of the tag a context stands for: the existing tag's,
+// or the context itself for a tag still to be created
+func (p *todoTxtPlan) tagName(context string) string {
+	if name, ok := p.tagByContext[strings.ToLower(context)]; ok {
+		return name
+	}
+	return context
+}
+
+// tagChanges lists the tags a task loses and the names of the tags it gains
+// to carry exactly a line's contexts
+func (p *todoTxtPlan) tagChanges(tags []Tag, contexts []string) (removed []Tag, added []string) {
+	want := map[string]bool{}
+	for _, c := range contexts {
+		want[strings.ToLower(c)] = true
+	}
+
+	for _, tag := range tags {
+		key := strings.ToLower(todoTxtName(tag.Name))
+		if want[key] {
+			delete(want, key)
+			continue
+		}
+		removed = append(removed, tag)
+	}
+
+	for _, context := range contexts {
+		key := strings.ToLower(context)
+		if !want[key] {
+			continue
+		}
+		delete(want, key)
+		added = append(added, p.tagName(context))
+	}
+	return removed, added
+}
+
+// todoTxtUpdateOps lists the bulk operations that bring a stored task in line
+// with its todo.txt line
+func todoTxtUpdateOps(task *Task, line TodoTxtTask, categoryID *int64, today string) []BulkOperation {
+	var ops []BulkOperation
+	ids := []int64{task.ID}
+
+	var fields BulkFields
+	changed := false
+	if line.Title != task.Title {
+		fields.Title, changed = &line.Title, true
+	}
+	if !sameStringPtr(line.Priority, task.Priority) {
+		fields.Priority, changed = todoTxtClearable(line.Priority), true
+	}
+	if !sameStringPtr(line.DueDate, task.DueDate) {
+		fields.DueDate, changed = todoTxtClearable(line.DueDate), true
+	}
+	if changed {
+		ops = append(ops, BulkOperation{Op: BulkUpdate, TaskIDs: ids, Fields: fields})
+	}
+
+	if !sameInt64Ptr(categoryID, task.CategoryID) {
+		ops = append(ops, BulkOperation{Op: BulkSetCategory, TaskIDs: ids, CategoryID: categoryID})
+	}
+	if line.AssignedDate != "" && line.AssignedDate != task.AssignedDate {
+		ops = append(ops, BulkOperation{Op: BulkMoveToDate, TaskIDs: ids, Date: line.AssignedDate})
+	}
+
+	completedDate := today
+	if line.CompletedDate != nil {
+		completedDate = *line.CompletedDate
+	}
+	switch {
+	case line.Completed && (!task.IsCompleted || (line.CompletedDate != nil && !sameStringPtr(line.CompletedDate, task.CompletedDate))):
+		ops = append(ops, BulkOperation{Op: BulkComplete, TaskIDs: ids, Date: completedDate})
+	case !line.Completed && task.IsCompleted:
+		ops = append(ops, BulkOperation{Op: BulkUncomplete, TaskIDs: ids})
+	}
+
+	return ops
+}
+
+// todoTxtClearable is an optional value for a bulk update, where empty clears it
+func todoTxtClearable(v *string) *string {
+	if v == nil {
+		empty := ""
+		return &empty
+	}
+	return v
+}
+
+// document turns the new lines into an import document, one task per line.
+// Each project becomes a category and each context a tag, named like the
+// existing category or tag it stands for so the import merges into it.
+func (p *todoTxtPlan) document() ImportDocument {
+	doc := ImportDocument{}
+	projectIDs := map[string]int64{}
+	for i, line := range p.added {
+		task := ExportTask{
+			ID:            int64(i + 1),
+			Title:         line.Title,
+			CreatedDate:   line.CreatedDate,
+			AssignedDate:  line.AssignedDate,
+			CompletedDate: line.CompletedDate,
+			IsCompleted:   line.Completed,
+			Priority:      line.Priority,
+			DueDate:       line.DueDate,
+		}
+		if task.CreatedDate == "" {
+			task.CreatedDate = p.today
+		}
+		if task.AssignedDate == "" {
+			task.AssignedDate = task.CreatedDate
+		}
+		if task.IsCompleted && task.CompletedDate == nil {
+			today := p.today
+			task.CompletedDate = &today
+		}
+		for _, context := range line.Contexts {
+			task.Tags = append(task.Tags, p.tagName(context))
+		}
+
+		if line.Project != "" {
+			key := strings.ToLower(line.Project)
+			id, ok := projectIDs[key]
+			if !ok {
+				id = int64(len(projectIDs) + 1)
+				projectIDs[key] = id
+				name := line.Project
+				if c, ok := p.categoryByProject[key]; ok {
+					name = c.Name
+				}
+				doc.Categories = append(doc.Categories, ExportCategory{ID: id, Name: name})
+			}
+			task.CategoryID = &id
+		}
+		doc.Tasks = append(doc.Tasks, task)
+	}
+	return doc
+}
+
+// todoTxtConflict reports the first conflicting line of an import of new
+// lines, blaming its project when its category conflicts
+func todoTxtConflict(report *ImportReport, doc ImportDocument, lines []TodoTxtTask) error {
+	categoryReasons := map[int64]string{}
+	for _, c := range report.Categories {
+		if c.Action == ImportConflict {
+			categoryReasons[c.SourceID] = c.Reason
+		}
+	}
+	for i, t := range report.Tasks {
+		if t.Action != ImportConflict {
+			continue
+		}
+		msg := t.Reason
+		if id := doc.Tasks[i].CategoryID; id != nil && categoryReasons[*id] != "" {
+			msg = categoryReasons[*id]
+		}
+		return &TodoTxtError{Line: lines[i].Line, Message: msg}
+	}
+	return nil
+}
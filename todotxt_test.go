@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTodoTxt(t *testing.T) {
+	lines, err := ParseTodoTxt(strings.NewReader(
+		"x 2026-03-12 2026-03-10 Ship release +Side_Project +Home @phone @Errands due:2026-03-20 assigned:2026-03-11 pri:A id:7\n" +
+			"\n" +
+			"(C) 2026-03-02 Water plants\n" +
+			"Call mom at 5:30\n",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3", len(lines))
+	}
+
+	id, completed, due, p0 := int64(7), "2026-03-12", "2026-03-20", "P0"
+	want := TodoTxtTask{
+		Line:          1,
+		ID:            &id,
+		Title:         "Ship release +Side_Project", // Only the last project names the category
+		Completed:     true,
+		CompletedDate: &completed,
+		CreatedDate:   "2026-03-10",
+		AssignedDate:  "2026-03-11",
+		Priority:      &p0,
+		DueDate:       &due,
+		Project:       "Home",
+		Contexts:      []string{"phone", "Errands"},
+	}
+	if !reflect.DeepEqual(lines[0], want) {
+		t.Errorf("got %+v, want %+v", lines[0], want)
+	}
+
+	if lines[1].Line != 3 || lines[1].Title != "Water plants" || lines[1].Priority == nil || *lines[1].Priority != "P2" {
+		t.Errorf("got %+v, want (C) Water plants on line 3", lines[1])
+	}
+	if lines[2].Title != "Call mom at 5:30" || lines[2].CreatedDate != "" || lines[2].Completed {
+		t.Errorf("got %+v, want a bare title", lines[2])
+	}
+}
+
+func TestParseTodoTxtErrors(t *testing.T) {
+	tests := []struct {
+		text, message string
+	}{
+		{"(E) Too low", "priority (E) is not supported; use (A) to (D)"},
+		{"Task due:tomorrow", "due: must be YYYY-MM-DD"},
+		{"Task @a,b", "contexts cannot contain commas"},
+		{"Task id:0", "id: must be a positive number"},
+		{"+Project @context", "the task has no title"},
+		{"2026-03-10 Task assigned:2026-03-09", "assigned: cannot be before the creation date"},
+	}
+	for _, tt := range tests {
+		_, err := ParseTodoTxt(strings.NewReader("Fine\n" + tt.text))
+		var lineErr *TodoTxtError
+		if !errors.As(err, &lineErr) || lineErr.Line != 2 || lineErr.Message != tt.message {
+			t.Errorf("%q: got %v, want line 2: %s", tt.text, err, tt.message)
+		}
+	}
+}
+
+// exportTodoTxt writes every task of s as todo.txt
+func exportTodoTxt(t *testing.T, s Store) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := WriteTodoTxt(s, &buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+// importTodoTxt parses text and applies it to s on testTuesday
+func importTodoTxt(s Store, text string) (*TodoTxtResult, error) {
+	lines, err := ParseTodoTxt(strings.NewReader(text))
+	if err != nil {
+		return nil, err
+	}
+	return s.ImportTodoTxt(lines, testTuesday, "test")
+}
+
+func TestTodoTxtRoundTrip(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		category, err := s.CreateCategory(CategoryRequest{Name: "Home Office", Color: "#123456", RolloverPolicy: RolloverNextDay})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tag, err := s.CreateTag("phone", "#654321")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		p1, due := "P1", "2026-03-06"
+		call, err := s.CreateTask(TaskRequest{Title: "Call printer", Date: testMonday, Priority: &p1, DueDate: &due, CategoryID: &category.ID}, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.AddTaskTag(call.ID, tag.ID, "test"); err != nil {
+			t.Fatal(err)
+		}
+		rolled := mustCreateTask(t, s, "Rolled", testMonday)
+		if _, err := s.RolloverTasks(testMonday, testTuesday, RolloverOptions{IncludeTaskIDs: []int64{rolled.ID}}, "test"); err != nil {
+			t.Fatal(err)
+		}
+		p3 := "P3"
+		done, err := s.CreateTask(TaskRequest{Title: "Done", Date: testMonday, Priority: &p3}, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.UpdateTaskCompletion(done.ID, true, testTuesday, "test"); err != nil {
+			t.Fatal(err)
+		}
+
+		exported := exportTodoTxt(t, s)
+		want := fmt.Sprintf("(B) %s Call printer +Home_Office @phone due:%s id:%d\n", testMonday, due, call.ID) +
+			fmt.Sprintf("%s Rolled assigned:%s id:%d\n", testMonday, testTuesday, rolled.ID) +
+			fmt.Sprintf("x %s %s Done pri:D id:%d\n", testTuesday, testMonday, done.ID)
+		if exported != want {
+			t.Fatalf("got\n%s\nwant\n%s", exported, want)
+		}
+
+		// Reading the file back changes nothing
+		result, err := importTodoTxt(s, exported)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *result != (TodoTxtResult{Unchanged: 3}) {
+			t.Errorf("got %+v reading the export back, want 3 unchanged", *result)
+		}
+		if again := exportTodoTxt(t, s); again != exported {
+			t.Errorf("export changed after reading it back:\n%s", again)
+		}
+
+		// Edit a line and add one; the new task joins the existing category
+		edited := strings.Replace(exported, "Call printer", "Call the printer", 1) + "(A) Fix sink +home_office @urgent\n"
+		result, err = importTodoTxt(s, edited)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *result != (TodoTxtResult{Created: 1, Updated: 1, Unchanged: 2}) {
+			t.Errorf("got %+v, want 1 created, 1 updated and 2 unchanged", *result)
+		}
+
+		lines := strings.Split(strings.TrimSuffix(exportTodoTxt(t, s), "\n"), "\n")
+		if len(lines) != 4 || !strings.HasPrefix(lines[0], "(B) "+testMonday+" Call the printer +Home_Office") {
+			t.Fatalf("got %q", lines)
+		}
+		if !strings.HasPrefix(lines[3], "(A) "+testTuesday+" Fix sink +Home_Office @urgent id:") {
+			t.Errorf("got %q for the new task", lines[3])
+		}
+
+		// Lines without an id that match a stored task are skipped
+		result, err = importTodoTxt(s, "(A) "+testTuesday+" Fix sink +Home_Office @urgent\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *result != (TodoTxtResult{Skipped: 1}) {
+			t.Errorf("got %+v reimporting the new line, want 1 skipped", *result)
+		}
+	})
+}
+
+func TestTodoTxtImportIsAtomic(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		task := mustCreateTask(t, s, "Pending", testMonday)
+		attic, err := s.CreateCategory(CategoryRequest{Name: "Attic", Color: "#123456", RolloverPolicy: RolloverNextDay})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DeleteCategory(attic.ID, "test"); err != nil {
+			t.Fatal(err)
+		}
+		before := exportTodoTxt(t, s)
+
+		// The first line completes the task, the second names the trashed
+		// category and fails
+		text := fmt.Sprintf("x %s %s Pending id:%d\nNew task +Attic\n", testTuesday, testMonday, task.ID)
+		_, err = importTodoTxt(s, text)
+		var lineErr *TodoTxtError
+		if !errors.As(err, &lineErr) || lineErr.Line != 2 {
+			t.Fatalf("got %v, want an error on line 2", err)
+		}
+
+		if after := exportTodoTxt(t, s); after != before {
+			t.Errorf("a failed import changed the tasks:\n%s", after)
+		}
+		if ops, _ := s.GetOperations(10); len(ops) > 0 && ops[0].Kind == OpTodoTxt {
+			t.Error("a failed import was journaled")
+		}
+	})
+}
+
+func TestTodoTxtImportUndoesInOneStep(t *testing.T) {
+	eachStore(t, func(t *testing.T, s Store) {
+		task := mustCreateTask(t, s, "Pending", testMonday)
+		before := exportTodoTxt(t, s)
+
+		text := fmt.Sprintf("x %s %s Pending id:%d\nFirst new\nSecond new +Garage\n", testTuesday, testMonday, task.ID)
+		if _, err := importTodoTxt(s, text); err != nil {
+			t.Fatal(err)
+		}
+
+		op, err := s.Undo("test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if op.Kind != OpTodoTxt {
+			t.Errorf("undid %s, want %s", op.Kind, OpTodoTxt)
+		}
+		if after := exportTodoTxt(t, s); after != before {
+			t.Errorf("one undo left:\n%s\nwant\n%s", after, before)
+		}
+	})
+}